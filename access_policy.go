@@ -0,0 +1,240 @@
+package govaluate
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AccessPolicy restricts which fields and methods accessors (such as "foo.Bar") may reach, and which methods they may call.
+// The zero value imposes no restrictions.
+//
+// If the types of parameters are known ahead of time (see [ExpressionOptions].ParameterTypes), accessors are checked against the policy
+// when the expression is parsed, as far as their types can be followed. Otherwise, or where they can't be, they're checked when evaluated.
+// Either way, a violation fails with an *AccessorError.
+type AccessPolicy struct {
+
+	// Types gives the rules for accessing members of particular struct types, such as reflect.TypeOf(User{}).
+	// Rules for a pointer type apply to the type it points to.
+	Types map[reflect.Type]TypeAccess
+
+	// DenyUnlistedTypes means that no member of a struct type may be accessed, unless that type is present in Types.
+	DenyUnlistedTypes bool
+
+	// DenyMethods disables method calls through accessors entirely; only fields may be accessed.
+	DenyMethods bool
+
+	// ApproveCall, if set, is asked whether each method call which the rest of the policy allows may go ahead, just before it's made.
+	// It's given the value the method is called on, the method's name, and the arguments to it.
+	// If it returns an error, the call is not made, and evaluation fails.
+	ApproveCall func(receiver interface{}, method string, arguments []interface{}) error
+}
+
+// TypeAccess gives the rules for accessing members of a single struct type, by their Go names.
+// Members named in a deny list are never accessible. If an allow list is non-nil, only the members it names are accessible.
+// Members promoted from embedded structs are subject to the rules of the struct which declares them, as well as those of each struct they're promoted through.
+type TypeAccess struct {
+	AllowFields  []string
+	DenyFields   []string
+	AllowMethods []string
+	DenyMethods  []string
+}
+
+// Returns true if the policy allows anything to be accessed, so needn't be checked.
+func (policy AccessPolicy) isUnrestricted() bool {
+	return len(policy.Types) == 0 && !policy.DenyUnlistedTypes && !policy.DenyMethods && policy.ApproveCall == nil
+}
+
+// Returns why the field named [name] of [structType] can't be accessed, or "" if it can.
+func (policy AccessPolicy) fieldDenial(structType reflect.Type, name string) string {
+
+	access, listed := policy.typeAccess(structType)
+	if !listed {
+		if policy.DenyUnlistedTypes {
+			return fmt.Sprintf("Access to field '%s' of '%v' is not allowed, no members of that type are accessible", name, structType)
+		}
+		return ""
+	}
+
+	if !isAccessAllowed(name, access.AllowFields, access.DenyFields) {
+		return fmt.Sprintf("Access to field '%s' of '%v' is not allowed", name, structType)
+	}
+	return ""
+}
+
+// Returns why the method named [name] of [structType] can't be called, or "" if it can.
+func (policy AccessPolicy) methodDenial(structType reflect.Type, name string) string {
+
+	if policy.DenyMethods {
+		return fmt.Sprintf("Call to method '%s' of '%v' is not allowed, method calls are disabled", name, structType)
+	}
+
+	access, listed := policy.typeAccess(structType)
+	if !listed {
+		if policy.DenyUnlistedTypes {
+			return fmt.Sprintf("Call to method '%s' of '%v' is not allowed, no members of that type are accessible", name, structType)
+		}
+		return ""
+	}
+
+	if !isAccessAllowed(name, access.AllowMethods, access.DenyMethods) {
+		return fmt.Sprintf("Call to method '%s' of '%v' is not allowed", name, structType)
+	}
+	return ""
+}
+
+// Returns why the field of [structType] at [index] can't be accessed, or "" if it can.
+// A promoted field is a member of each struct along the way to the embedded struct which declares it, so it's checked against each of them.
+func (policy AccessPolicy) promotedFieldDenial(structType reflect.Type, index []int) string {
+
+	field := structType.FieldByIndex(index)
+	currentType := structType
+
+	for i := 0; ; i++ {
+
+		denial := policy.fieldDenial(currentType, field.Name)
+		if denial != "" || i == len(index)-1 {
+			return denial
+		}
+
+		currentType = currentType.Field(index[i]).Type
+		if currentType.Kind() == reflect.Ptr {
+			currentType = currentType.Elem()
+		}
+	}
+}
+
+// Returns why the method named [name] of [structType] can't be called, or "" if it can.
+// Like fields, a promoted method is checked against each struct along the way to the embedded struct which declares it.
+func (policy AccessPolicy) promotedMethodDenial(structType reflect.Type, name string) string {
+
+	for _, owner := range methodOwners(structType, name, make(map[reflect.Type]bool)) {
+
+		denial := policy.methodDenial(owner, name)
+		if denial != "" {
+			return denial
+		}
+	}
+	return ""
+}
+
+// Returns [structType], and each struct it embeds which has the method named [name], which it may have been promoted from.
+// Since reflection doesn't tell promoted methods apart from those a struct declares itself, embedded structs are included even if
+// [structType]'s own method shadows theirs; so that a method denied to an embedded struct is never reachable through another.
+func methodOwners(structType reflect.Type, name string, visited map[reflect.Type]bool) []reflect.Type {
+
+	if visited[structType] {
+		return nil
+	}
+	visited[structType] = true
+
+	ret := []reflect.Type{structType}
+
+	for i := 0; i < structType.NumField(); i++ {
+
+		field := structType.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+
+		embeddedType := field.Type
+		if embeddedType.Kind() == reflect.Ptr {
+			embeddedType = embeddedType.Elem()
+		}
+
+		if embeddedType.Kind() != reflect.Struct {
+			continue
+		}
+
+		_, found := reflect.PtrTo(embeddedType).MethodByName(name)
+		if found {
+			ret = append(ret, methodOwners(embeddedType, name, visited)...)
+		}
+	}
+	return ret
+}
+
+func (policy AccessPolicy) typeAccess(structType reflect.Type) (TypeAccess, bool) {
+
+	access, found := policy.Types[structType]
+	if found {
+		return access, true
+	}
+
+	access, found = policy.Types[reflect.PtrTo(structType)]
+	return access, found
+}
+
+func isAccessAllowed(name string, allowed []string, denied []string) bool {
+
+	for _, deniedName := range denied {
+		if deniedName == name {
+			return false
+		}
+	}
+
+	if allowed == nil {
+		return true
+	}
+
+	for _, allowedName := range allowed {
+		if allowedName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the accessor [pair] (as held by an accessor token) against the policy, given the type of the parameter it starts from.
+// Follows the chain as far as the types of its links are known; links reached through interfaces or failed lookups are left to be checked when evaluated.
+func (policy AccessPolicy) checkAccessorType(pair []string, parameterType reflect.Type, tag string) error {
+
+	names, _, accessor := splitAccessor(pair)
+	currentType := parameterType
+
+	for i := 1; i < len(names) && currentType != nil; i++ {
+
+		name := names[i]
+
+		structType := currentType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+
+		if structType.Kind() != reflect.Struct {
+			return nil
+		}
+
+		field, found := findAccessorFields(structType, tag)[name]
+		if found {
+
+			goField := structType.FieldByIndex(field.index)
+
+			denial := policy.promotedFieldDenial(structType, field.index)
+			if denial != "" {
+				return &AccessorError{Accessor: accessor, Message: denial}
+			}
+
+			currentType = goField.Type
+			continue
+		}
+
+		method, found := structType.MethodByName(name)
+		if !found {
+			method, found = reflect.PtrTo(structType).MethodByName(name)
+		}
+		if !found {
+			return nil
+		}
+
+		denial := policy.promotedMethodDenial(structType, name)
+		if denial != "" {
+			return &AccessorError{Accessor: accessor, Message: denial}
+		}
+
+		currentType = nil
+		if method.Type.NumOut() > 0 {
+			currentType = method.Type.Out(0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Represents a test of an accessor against an access policy.
+// If [Expected] is set, the accessor should be denied with an error containing it; otherwise it should evaluate to [Result].
+type AccessPolicyTest struct {
+	Name     string
+	Input    string
+	Policy   AccessPolicy
+	Expected string
+	Result   interface{}
+}
+
+func TestAccessPolicy(test *testing.T) {
+
+	dummyType := reflect.TypeOf(dummyParameter{})
+
+	restricted := AccessPolicy{
+		Types: map[reflect.Type]TypeAccess{
+			dummyType: {
+				DenyFields:   []string{"Int"},
+				AllowMethods: []string{"Func", "FuncArgStr"},
+			},
+			reflect.TypeOf(&dummyTaggedParameter{}): {
+				DenyFields: []string{"CreatedAt"},
+			},
+		},
+	}
+
+	accessPolicyTests := []AccessPolicyTest{
+		{
+			Name:   "Allowed field",
+			Input:  "foo.String",
+			Policy: restricted,
+			Result: "string!",
+		},
+		{
+			Name:     "Denied field",
+			Input:    "foo.Int > 1",
+			Policy:   restricted,
+			Expected: "Access to field 'Int' of 'govaluate.dummyParameter' is not allowed",
+		},
+		{
+			Name:     "Denied field through a pointer",
+			Input:    "fooptr.Int",
+			Policy:   restricted,
+			Expected: "Access to field 'Int' of 'govaluate.dummyParameter' is not allowed",
+		},
+		{
+			Name:     "Denied field by its tag name",
+			Input:    "tagged.created_at",
+			Policy:   restricted,
+			Expected: "Access to field 'CreatedAt' of 'govaluate.dummyTaggedParameter' is not allowed",
+		},
+		{
+			Name:   "Allowed method",
+			Input:  "foo.FuncArgStr('a') + foo.Func()",
+			Policy: restricted,
+			Result: "afunk",
+		},
+		{
+			Name:     "Method not in allow list",
+			Input:    "foo.Func2()",
+			Policy:   restricted,
+			Expected: "Call to method 'Func2' of 'govaluate.dummyParameter' is not allowed",
+		},
+		{
+			Name:     "Pointer method not in allow list",
+			Input:    "fooptr.Func3()",
+			Policy:   restricted,
+			Expected: "Call to method 'Func3' of 'govaluate.dummyParameter' is not allowed",
+		},
+		{
+			Name:   "Unlisted type",
+			Input:  "foo.Nested.Dunk('x')",
+			Policy: restricted,
+			Result: "xdunk",
+		},
+		{
+			Name:     "Methods disabled",
+			Input:    "foo.Nested.Dunk('x')",
+			Policy:   AccessPolicy{DenyMethods: true},
+			Expected: "Call to method 'Dunk' of 'govaluate.dummyNestedParameter' is not allowed, method calls are disabled",
+		},
+		{
+			Name:   "Fields allowed while methods are disabled",
+			Input:  "foo.Nested.Funk",
+			Policy: AccessPolicy{DenyMethods: true},
+			Result: "funkalicious",
+		},
+		{
+			Name:  "Unlisted types denied",
+			Input: "foo.Nested.Funk",
+			Policy: AccessPolicy{
+				Types:             map[reflect.Type]TypeAccess{dummyType: {}},
+				DenyUnlistedTypes: true,
+			},
+			Expected: "Access to field 'Funk' of 'govaluate.dummyNestedParameter' is not allowed, no members of that type are accessible",
+		},
+		{
+			Name:  "Denied promoted field",
+			Input: "tagged.embedded_name",
+			Policy: AccessPolicy{Types: map[reflect.Type]TypeAccess{
+				reflect.TypeOf(dummyTaggedEmbedded{}): {DenyFields: []string{"EmbeddedName"}},
+			}},
+			Expected: "Access to field 'EmbeddedName' of 'govaluate.dummyTaggedEmbedded' is not allowed",
+		},
+		{
+			Name:  "Denied promoted method",
+			Input: "tagged.Reveal()",
+			Policy: AccessPolicy{Types: map[reflect.Type]TypeAccess{
+				reflect.TypeOf(DummyExportedEmbedded{}): {DenyMethods: []string{"Reveal"}},
+			}},
+			Expected: "Call to method 'Reveal' of 'govaluate.DummyExportedEmbedded' is not allowed",
+		},
+		{
+			Name:   "Allowed promoted method",
+			Input:  "tagged.Reveal()",
+			Policy: restricted,
+			Result: "code",
+		},
+		{
+			Name:  "Promoted field of an unlisted type",
+			Input: "tagged.Code",
+			Policy: AccessPolicy{
+				Types:             map[reflect.Type]TypeAccess{reflect.TypeOf(dummyTaggedParameter{}): {}},
+				DenyUnlistedTypes: true,
+			},
+			Expected: "Access to field 'Code' of 'govaluate.DummyExportedEmbedded' is not allowed, no members of that type are accessible",
+		},
+		{
+			Name:     "Field allow list",
+			Input:    "foo.String",
+			Policy:   AccessPolicy{Types: map[reflect.Type]TypeAccess{dummyType: {AllowFields: []string{}}}},
+			Expected: "Access to field 'String' of 'govaluate.dummyParameter' is not allowed",
+		},
+	}
+
+	parameters := map[string]interface{}{
+		"foo":    dummyParameterInstance,
+		"fooptr": &dummyParameterInstance,
+		"tagged": dummyTaggedParameterInstance,
+	}
+
+	parameterTypes := make(map[string]reflect.Type)
+	for name, value := range parameters {
+		parameterTypes[name] = reflect.TypeOf(value)
+	}
+
+	for _, policyTest := range accessPolicyTests {
+
+		// without the types of parameters, violations are only found when evaluated.
+		expression, err := NewExpressionWithOptions(policyTest.Input, ExpressionOptions{AccessPolicy: policyTest.Policy})
+		if err != nil {
+			test.Errorf("Test '%s' failed: unable to parse: %v", policyTest.Name, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+		checkAccessPolicyResult(test, policyTest, "evaluated", result, err)
+
+		// with them, violations are found while parsing.
+		expression, err = NewExpressionWithOptions(policyTest.Input, ExpressionOptions{AccessPolicy: policyTest.Policy, ParameterTypes: parameterTypes})
+		if err != nil || policyTest.Expected != "" {
+			checkAccessPolicyResult(test, policyTest, "parsed with parameter types", nil, err)
+			continue
+		}
+
+		result, err = expression.Evaluate(parameters)
+		checkAccessPolicyResult(test, policyTest, "evaluated with parameter types", result, err)
+	}
+}
+
+func checkAccessPolicyResult(test *testing.T, policyTest AccessPolicyTest, when string, result interface{}, err error) {
+
+	if policyTest.Expected == "" {
+		if err != nil || result != policyTest.Result {
+			test.Errorf("Test '%s' failed when %s: expected %v, got %v (%v)", policyTest.Name, when, policyTest.Result, result, err)
+		}
+		return
+	}
+
+	_, isAccessorError := err.(*AccessorError)
+	if !isAccessorError || !strings.Contains(err.Error(), policyTest.Expected) {
+		test.Errorf("Test '%s' failed when %s: expected an *AccessorError containing '%s', got %v", policyTest.Name, when, policyTest.Expected, err)
+	}
+}
+
+// Tests that violations are found while parsing (rather than evaluating) when the types of parameters are known.
+func TestAccessPolicyParsing(test *testing.T) {
+
+	options := ExpressionOptions{
+		AccessPolicy:   AccessPolicy{DenyMethods: true},
+		ParameterTypes: map[string]reflect.Type{"foo": reflect.TypeOf(dummyParameter{})},
+	}
+
+	_, err := NewExpressionWithOptions("foo.String == 'x' || foo.Func() == 'y'", options)
+	if err == nil || !strings.Contains(err.Error(), "Call to method 'Func'") {
+		test.Errorf("Expected the method call to be rejected while parsing, got %v", err)
+	}
+
+	// fields reached through interfaces can't be checked until evaluated.
+	_, err = NewExpressionWithOptions("foo.Nil.Something()", options)
+	if err != nil {
+		test.Errorf("Expected accessors through interfaces to be left for evaluation, got %v", err)
+	}
+}
+
+func TestAccessPolicyApproveCall(test *testing.T) {
+
+	var receivers []interface{}
+	var methods []string
+
+	options := ExpressionOptions{
+		AccessPolicy: AccessPolicy{
+			ApproveCall: func(receiver interface{}, method string, arguments []interface{}) error {
+
+				receivers = append(receivers, receiver)
+				methods = append(methods, method)
+
+				if len(arguments) > 0 && arguments[0] == "forbidden" {
+					return errors.New("forbidden argument")
+				}
+				return nil
+			},
+		},
+	}
+
+	expression, _ := NewExpressionWithOptions("foo.FuncArgStr(value)", options)
+
+	result, err := expression.Evaluate(map[string]interface{}{"foo": dummyParameterInstance, "value": "allowed"})
+	if err != nil || result != "allowed" {
+		test.Errorf("Expected approved call to succeed, got %v (%v)", result, err)
+	}
+
+	_, err = expression.Evaluate(map[string]interface{}{"foo": dummyParameterInstance, "value": "forbidden"})
+	if err == nil || !strings.Contains(err.Error(), "Call to method 'FuncArgStr' was not approved: forbidden argument") {
+		test.Errorf("Expected unapproved call to fail, got %v", err)
+	}
+
+	if len(methods) != 2 || methods[0] != "FuncArgStr" || receivers[0] != dummyParameterInstance {
+		test.Errorf("Expected approval to be asked for each call with its receiver, got %v on %v", methods, receivers)
+	}
+
+	// fields don't need approval.
+	methods = nil
+	expression, _ = NewExpressionWithOptions("foo.String", options)
+	expression.Evaluate(map[string]interface{}{"foo": dummyParameterInstance})
+
+	if len(methods) != 0 {
+		test.Errorf("Expected field access not to need approval, got %v", methods)
+	}
+}
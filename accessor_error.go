@@ -0,0 +1,29 @@
+package govaluate
+
+import (
+	"fmt"
+)
+
+// AccessorError is returned when an accessor (such as "foo.Bar.Baz") cannot be evaluated;
+// for instance because a link in the chain is nil, is not a struct, or has no such field or method.
+// Errors returned by methods called through an accessor are passed along as-is, not wrapped in an AccessorError.
+type AccessorError struct {
+
+	// Accessor is the full accessor being evaluated, as written in the expression.
+	Accessor string
+
+	// Message describes why the accessor could not be evaluated.
+	Message string
+
+	// Recovered holds the value of any panic that occurred while evaluating the accessor, or nil if there was none.
+	// Panics may be of any type, not just strings or errors.
+	Recovered interface{}
+}
+
+func (err *AccessorError) Error() string {
+
+	if err.Recovered != nil {
+		return fmt.Sprintf("Failed to access '%s': %v", err.Accessor, err.Recovered)
+	}
+	return err.Message
+}
@@ -0,0 +1,187 @@
+package govaluate
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// accessorField describes a single struct field that can be reached by an accessor,
+// along with the index path needed to reach it through any embedded structs.
+type accessorField struct {
+	name   string
+	index  []int
+	depth  int
+	tagged bool
+
+	// for Go names of renamed fields, the tag name that they alias.
+	aliasOf string
+}
+
+type accessorFieldsKey struct {
+	structType reflect.Type
+	tag        string
+}
+
+// Field lookups are cached per struct type (and tag), since reflecting over a type's fields is the expensive part of accessing them.
+var accessorFieldsCache sync.Map
+
+// Returns the fields accessible on [structType], keyed by the name an expression would use to reach them.
+func findAccessorFields(structType reflect.Type, tag string) map[string]accessorField {
+
+	key := accessorFieldsKey{structType, tag}
+
+	cached, found := accessorFieldsCache.Load(key)
+	if found {
+		return cached.(map[string]accessorField)
+	}
+
+	fields := buildAccessorFields(structType, tag)
+	accessorFieldsCache.Store(key, fields)
+	return fields
+}
+
+// Builds the name-to-field mapping for a struct type.
+// Names follow the same rules as encoding/json: a tag name takes precedence over the Go field name, fields tagged "-" are hidden,
+// and fields of untagged embedded structs are promoted, with shallower fields (then tagged fields) winning any conflicts.
+// Ambiguous names are dropped entirely.
+// For compatibility with expressions written before tags were honored, the Go name of a renamed field also resolves,
+// as does the name of an untagged embedded struct, so long as nothing else claims that name.
+func buildAccessorFields(structType reflect.Type, tag string) map[string]accessorField {
+
+	var candidates []accessorField
+	var aliases []accessorField
+
+	collectAccessorFields(structType, tag, nil, 0, map[reflect.Type]bool{}, &candidates, &aliases)
+
+	ret := make(map[string]accessorField)
+	ambiguous := make(map[string]bool)
+
+	for _, candidate := range candidates {
+
+		if ambiguous[candidate.name] {
+			continue
+		}
+
+		extant, found := ret[candidate.name]
+		if !found {
+			ret[candidate.name] = candidate
+			continue
+		}
+
+		if candidate.depth < extant.depth || (candidate.depth == extant.depth && candidate.tagged && !extant.tagged) {
+			ret[candidate.name] = candidate
+			continue
+		}
+
+		if candidate.depth == extant.depth && candidate.tagged == extant.tagged {
+			delete(ret, candidate.name)
+			ambiguous[candidate.name] = true
+		}
+	}
+
+	for _, alias := range aliases {
+
+		_, found := ret[alias.name]
+		if found || ambiguous[alias.name] {
+			continue
+		}
+
+		// only alias fields which actually won their tag name; embedded structs don't have one.
+		if alias.aliasOf != "" {
+
+			target, found := ret[alias.aliasOf]
+			if !found || !reflect.DeepEqual(target.index, alias.index) {
+				continue
+			}
+		}
+		ret[alias.name] = alias
+	}
+
+	return ret
+}
+
+func collectAccessorFields(structType reflect.Type, tag string, parentIndex []int, depth int, visited map[reflect.Type]bool, candidates *[]accessorField, aliases *[]accessorField) {
+
+	if visited[structType] {
+		return
+	}
+	visited[structType] = true
+	defer delete(visited, structType)
+
+	for i := 0; i < structType.NumField(); i++ {
+
+		field := structType.Field(i)
+
+		index := make([]int, len(parentIndex)+1)
+		copy(index, parentIndex)
+		index[len(parentIndex)] = i
+
+		tagName, hidden := parseAccessorTag(field.Tag.Get(tag))
+		if hidden {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		// untagged embedded structs have their fields promoted, even if the embedded type itself is unexported.
+		// Exported ones can also still be reached by their own name, as they could before tags were honored.
+		if field.Anonymous && tagName == "" && fieldType.Kind() == reflect.Struct {
+
+			if field.PkgPath == "" {
+				*aliases = append(*aliases, accessorField{name: field.Name, index: index, depth: depth})
+			}
+			collectAccessorFields(fieldType, tag, index, depth+1, visited, candidates, aliases)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if tagName == "" {
+			*candidates = append(*candidates, accessorField{name: field.Name, index: index, depth: depth})
+			continue
+		}
+
+		*candidates = append(*candidates, accessorField{name: tagName, index: index, depth: depth, tagged: true})
+		*aliases = append(*aliases, accessorField{name: field.Name, index: index, depth: depth, aliasOf: tagName})
+	}
+}
+
+// Returns the name given by a struct tag value, and whether or not the tag hides the field.
+func parseAccessorTag(tagValue string) (string, bool) {
+
+	if tagValue == "-" {
+		return "", true
+	}
+
+	name := tagValue
+	comma := strings.Index(tagValue, ",")
+	if comma >= 0 {
+		name = tagValue[:comma]
+	}
+
+	return name, false
+}
+
+// Like reflect.Value.FieldByIndex, except it returns false instead of panicking if an embedded struct pointer along the way is nil.
+func accessorFieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+
+	for i, fieldIndex := range index {
+
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+
+		value = value.Field(fieldIndex)
+	}
+
+	return value, true
+}
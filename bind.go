@@ -0,0 +1,335 @@
+package govaluate
+
+import (
+	"context"
+	"strings"
+)
+
+// Bind substitutes the given [parameters] into the expression, and returns the residual expression over the parameters which remain;
+// as small as it can be made while still evaluating the same as the original would, given the same parameters.
+// Everything which only depends on bound parameters is evaluated ahead of time, including calls to pure functions (see [FunctionDescriptor])
+// and accessors of bound parameters. Logical operators are short-circuited as they would be when evaluated,
+// so with "x" bound to false, "x && y > 1" binds to "false". Likewise, "true && y > 1" binds to "y > 1".
+//
+// Stages which would fail when evaluated (such as "1 + true") are left as they are, so that they fail in the same way.
+// Parameters whose values can't be written as literals (such as structs) are kept by the residual expression,
+// which uses them when evaluated; as it does for any parameters which are bound again.
+// The residual expression has the same options as this one, and its String() is the text of what remains.
+func (expr Expression) Bind(parameters map[string]interface{}) (*Expression, error) {
+
+	binder := &stageBinder{
+		options:    expr.options,
+		parameters: make(map[string]interface{}),
+		evaluator:  Expression{ChecksTypes: true, options: expr.options},
+	}
+
+	for name, value := range expr.boundParameters {
+		binder.parameters[name] = value
+	}
+	for name, value := range parameters {
+		binder.parameters[name] = castToFloat64(value)
+	}
+	binder.known = MapParameters(binder.parameters)
+
+	// the placeholder for the elements of collection functions can't be bound.
+	shadowed := map[string]bool{placeholderParameter: true}
+
+	ret, err := expr.rewrite(binder.bind(expr.evaluationStages, shadowed))
+	if err != nil {
+		return nil, err
+	}
+
+	ret.boundParameters = binder.parameters
+	return ret, nil
+}
+
+// Substitutes bound parameters into a copy of a tree of stages, and folds whatever it can.
+type stageBinder struct {
+	options    ExpressionOptions
+	parameters map[string]interface{}
+	known      Parameters
+
+	// evaluates the parts of the tree which only depend on bound parameters.
+	evaluator Expression
+}
+
+// Returns a copy of [stage], with every parameter that's bound (and not [shadowed] by the element of a collection function) substituted.
+func (binder *stageBinder) bind(stage *evaluationStage, shadowed map[string]bool) *evaluationStage {
+
+	if stage == nil {
+		return nil
+	}
+
+	ret := *stage
+	ret.leftStage = binder.bind(stage.leftStage, shadowed)
+
+	if stage.collection != nil {
+		shadowed = shadowWith(shadowed, stage.collection.parameterName)
+	}
+	ret.rightStage = binder.bind(stage.rightStage, shadowed)
+
+	return binder.fold(&ret, shadowed)
+}
+
+// Returns the stage which [stage] can be reduced to, now that its operands have been bound.
+func (binder *stageBinder) fold(stage *evaluationStage, shadowed map[string]bool) *evaluationStage {
+
+	left, leftKnown := binder.literalOf(stage.leftStage)
+
+	switch stage.symbol {
+
+	case value:
+		bound, found := binder.parameters[stage.name]
+		if found && !shadowed[stage.name] && binder.isWritable(bound) {
+			return literalStage(bound)
+		}
+		return stage
+
+	case access:
+		if binder.isBound(accessorRoot(stage.name), shadowed) && isConstantStage(stage.rightStage) {
+			return binder.evaluate(stage)
+		}
+		return stage
+
+	case functional:
+		if stage.function != nil && stage.function.pure && isConstantStage(stage.rightStage) {
+			return binder.evaluate(stage)
+		}
+		return stage
+
+	case collectionFunctional:
+		names := map[string]bool{stage.collection.parameterName: true}
+		if isConstantStage(stage.leftStage) && isClosedStage(stage.rightStage, names) {
+			return binder.evaluate(stage)
+		}
+		return stage
+
+	// these only make sense as part of the stages around them.
+	case separate, betweenBounds, mapEntry:
+		return stage
+
+	// parenthesis are only kept around what's left, since they also hold the arguments of function calls.
+	case noopSymbol:
+		if stage.rightStage != nil && stage.rightStage.symbol == literal {
+			return stage.rightStage
+		}
+		return stage
+
+	case and, or:
+		// the side which decides the result on its own, as in "false && x".
+		deciding := stage.symbol == or
+
+		if leftKnown && left == deciding {
+			return literalStage(deciding)
+		}
+		if leftKnown && left == !deciding && isBoolStage(stage.rightStage) {
+			return stage.rightStage
+		}
+
+		right, rightKnown := binder.literalOf(stage.rightStage)
+		if rightKnown && right == !deciding && isBoolStage(stage.leftStage) {
+			return stage.leftStage
+		}
+
+	case coalesce, ternaryFalse:
+		if (leftKnown && left != nil) || binder.isNeverNull(stage.leftStage) {
+			return stage.leftStage
+		}
+		if leftKnown {
+			return stage.rightStage
+		}
+
+		// once the ternary's condition is folded away, what's left chooses between values just as a coalesce does.
+		if stage.leftStage != nil && stage.leftStage.symbol != ternaryTrue {
+			stage.symbol = coalesce
+		}
+
+	case ternaryTrue:
+		if leftKnown && left == true {
+			return stage.rightStage
+		}
+		if leftKnown && (left == false || (left == nil && binder.options.ThreeValuedLogic)) {
+			return literalStage(nil)
+		}
+	}
+
+	if isConstantStage(stage.leftStage) && isConstantStage(stage.rightStage) && (stage.symbol != mapElement || stage.rightStage != nil) {
+		return binder.evaluate(stage)
+	}
+	return stage
+}
+
+// Evaluates [stage], which only depends on bound parameters, returning a literal of the result.
+// Returns the unmodified [stage] if it fails (or panics), or if its result can't be written as a literal.
+func (binder *stageBinder) evaluate(stage *evaluationStage) (ret *evaluationStage) {
+
+	defer func() {
+		if recover() != nil {
+			ret = stage
+		}
+	}()
+
+	result, err := binder.evaluator.evaluateStage(stage, &sanitizedParameters{orig: binder.known})
+	if err != nil || !binder.isWritable(result) {
+		return stage
+	}
+	return literalStage(result)
+}
+
+// Returns the value of [stage], if it is a literal.
+func (binder *stageBinder) literalOf(stage *evaluationStage) (interface{}, bool) {
+
+	if stage == nil || stage.symbol != literal {
+		return nil, false
+	}
+	return literalValueOf(stage), true
+}
+
+func (binder *stageBinder) isBound(name string, shadowed map[string]bool) bool {
+
+	_, found := binder.parameters[name]
+	return found && !shadowed[name]
+}
+
+// Returns true if [value] can be written out as a literal of the residual expression.
+func (binder *stageBinder) isWritable(value interface{}) bool {
+
+	formatter := &stageFormatter{options: binder.options}
+	return formatter.writeLiteral(value) == nil
+}
+
+func literalStage(value interface{}) *evaluationStage {
+
+	return &evaluationStage{
+		symbol:   literal,
+		operator: makeLiteralStage(value),
+	}
+}
+
+// Returns a copy of [shadowed] which also includes [name].
+func shadowWith(shadowed map[string]bool, name string) map[string]bool {
+
+	ret := map[string]bool{name: true}
+	for other := range shadowed {
+		ret[other] = true
+	}
+	return ret
+}
+
+// Returns the name of the parameter that an accessor (like "foo?.Bar.Baz") starts from.
+func accessorRoot(name string) string {
+
+	end := strings.IndexAny(name, ".?")
+	if end < 0 {
+		return name
+	}
+	return name[:end]
+}
+
+// Returns true if the given stage always produces a bool (or null, with three-valued logic), or fails.
+func isBoolStage(stage *evaluationStage) bool {
+
+	for stage != nil && stage.symbol == noopSymbol {
+		stage = stage.rightStage
+	}
+
+	if stage == nil {
+		return false
+	}
+
+	switch stage.symbol {
+	case literal:
+		return isBool(literalValueOf(stage))
+	case eq, neq, gt, lt, gte, lte, req, nreq, in, notIn, like, notLike, ilike, notIlike, glob, notGlob,
+		between, notBetween, is, isNot, and, or, xor, invert:
+		return true
+	}
+	return false
+}
+
+// Returns true if the given stage (which isn't a literal) can't produce null; that is, it either produces a value or fails.
+// With three-valued logic, any operator can produce null.
+func (binder *stageBinder) isNeverNull(stage *evaluationStage) bool {
+
+	for stage != nil && stage.symbol == noopSymbol {
+		stage = stage.rightStage
+	}
+
+	if stage == nil || binder.options.ThreeValuedLogic {
+		return false
+	}
+
+	switch stage.symbol {
+	case plus, minus, multiply, divide, modulus, exponent, negate,
+		bitwiseAnd, bitwiseOr, bitwiseXor, bitwiseLshift, bitwiseRshift, bitwiseNot:
+		return true
+	}
+	return isBoolStage(stage) && stage.symbol != literal
+}
+
+// Returns true if the given stage refers to no parameters other than [names], and calls no functions which aren't pure;
+// so that it evaluates the same way every time that those names have the same values.
+func isClosedStage(stage *evaluationStage, names map[string]bool) bool {
+
+	if stage == nil {
+		return true
+	}
+
+	switch stage.symbol {
+	case value:
+		return names[stage.name]
+	case access:
+		if !names[accessorRoot(stage.name)] {
+			return false
+		}
+	case functional:
+		if stage.function == nil || !stage.function.pure {
+			return false
+		}
+	case collectionFunctional:
+		return isClosedStage(stage.leftStage, names) && isClosedStage(stage.rightStage, shadowWith(names, stage.collection.parameterName))
+	}
+	return isClosedStage(stage.leftStage, names) && isClosedStage(stage.rightStage, names)
+}
+
+// boundParameters are the parameters given to Bind, which the residual expression uses before those it's evaluated with.
+type boundParameters struct {
+	bound  map[string]interface{}
+	parent Parameters
+}
+
+func (p boundParameters) Get(name string) (interface{}, error) {
+
+	value, found := p.bound[name]
+	if found {
+		return value, nil
+	}
+	return p.parent.Get(name)
+}
+
+func (p boundParameters) GetContext(ctx context.Context, name string) (interface{}, error) {
+
+	value, found := p.bound[name]
+	if found {
+		return value, nil
+	}
+
+	contextual, isContextual := p.parent.(ParametersContext)
+	if isContextual {
+		return contextual.GetContext(ctx, name)
+	}
+	return p.parent.Get(name)
+}
+
+// Returns [parameters] along with any that were bound to the expression, which take precedence.
+func (expr Expression) withBoundParameters(parameters Parameters) Parameters {
+
+	if len(expr.boundParameters) == 0 {
+		return parameters
+	}
+
+	if parameters == nil {
+		parameters = MapParameters(map[string]interface{}{})
+	}
+	return boundParameters{bound: expr.boundParameters, parent: parameters}
+}
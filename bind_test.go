@@ -0,0 +1,414 @@
+package govaluate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that stages written back out as expressions parse to stages which are written out the same way, and which evaluate the same.
+func TestStageFormatting(test *testing.T) {
+
+	parameters := map[string]interface{}{
+		"a":         3.0,
+		"b":         -2.0,
+		"c":         7.0,
+		"flag":      true,
+		"name":      "Alice",
+		"items":     []interface{}{1.0, 2.0, 3.0},
+		"missing":   nil,
+		"foo":       dummyParameterInstance,
+		"and":       "reserved",
+		"strlen":    1.0,
+		"with dash": 4.0,
+	}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"a - (b - c)", "a - (b - c)"},
+		{"(a - b) - c", "a - b - c"},
+		{"a * (b + c) ** 2", "a * (b + c) ** 2"},
+		{"-a + -(b * c) - -1", "-a + -(b * c) - -1"},
+		{"!(flag && a > b) || !flag", "!(flag && a > b) || !flag"},
+		{"a > 1 ? name : 'x' + name", "a > 1 ? name : 'x' + name"},
+		{"flag ? (a > 1 ? 'p' : 'q') : 'r'", "flag ? (a > 1 ? 'p' : 'q') : 'r'"},
+		{"missing ?? a + 1", "missing ?? a + 1"},
+		{"a in [1, 2, b] && name not in ['Bob',]", "a in [1, 2, b] && name not in ['Bob',]"},
+		{"'k' in {'k': a, 'j': [b,]} && a in [a,]", "'k' in {'k': a, 'j': [b,]} && a in [a,]"},
+		{"strlen(name) + [strlen]", "strlen(name) + [strlen]"},
+		{"[with dash] * 2", "[with dash] * 2"},
+		{"foo.String + foo.FuncArgStr('x') + foo?.Nested.Funk", "foo.String + foo.FuncArgStr('x') + foo?.Nested.Funk"},
+		{"any(items, # > 2) && all(items, x => x > 0) && count(items) == 3", "any(items, # > 2) && all(items, x => x > 0) && count(items) == 3"},
+		{"name =~ '^A' && name like 'A%' && name !~ \"it's\"", "name =~ '^A' && name like 'A%' && name !~ 'it\\'s'"},
+		{"'a\\'b\\\\c\"d' + name", "'a\\'b\\\\c\"d' + name"},
+		{"missing is null && name is not null", "missing is null && name is not null"},
+		{"(a | 1) << 2 & ~b", "(a | 1) << 2 & ~b"},
+		{"a + 1e21 + 0.5", "a + 1e+21 + 0.5"},
+	}
+
+	options := ExpressionOptions{
+		Functions: map[string]ExpressionFunction{
+			"strlen": func(arguments ...interface{}) (interface{}, error) {
+				return float64(len(arguments[0].(string))), nil
+			},
+		},
+		PatternOperators: true,
+	}
+
+	for _, formattingCase := range cases {
+
+		expression, err := NewExpressionWithOptions(formattingCase.input, options)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", formattingCase.input, err)
+			continue
+		}
+
+		formatted, err := formatStage(expression.evaluationStages, options)
+		if err != nil {
+			test.Errorf("Unable to format '%s': %v", formattingCase.input, err)
+			continue
+		}
+
+		if formattingCase.expected != "" && formatted != formattingCase.expected {
+			test.Errorf("Expected '%s' to be formatted as '%s', got '%s'", formattingCase.input, formattingCase.expected, formatted)
+		}
+
+		reparsed, err := NewExpressionWithOptions(formatted, options)
+		if err != nil {
+			test.Errorf("Unable to parse '%s', formatted from '%s': %v", formatted, formattingCase.input, err)
+			continue
+		}
+
+		reformatted, _ := formatStage(reparsed.evaluationStages, options)
+		if reformatted != formatted {
+			test.Errorf("Expected '%s' to be formatted the same way when parsed again, got '%s'", formatted, reformatted)
+		}
+
+		expected, err := expression.Evaluate(parameters)
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", formattingCase.input, err)
+			continue
+		}
+
+		actual, err := reparsed.Evaluate(parameters)
+		if err != nil || !reflect.DeepEqual(actual, expected) {
+			test.Errorf("Expected '%s' to evaluate to %v, as '%s' does; got %v (%v)", formatted, expected, formattingCase.input, actual, err)
+		}
+	}
+}
+
+func TestStageFormattingWordOperators(test *testing.T) {
+
+	options := ExpressionOptions{WordOperators: true}
+
+	expression, err := NewExpressionWithOptions("n + 1 between (lower or x) and upper xor [and] not between 1 and 2", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	formatted, err := formatStage(expression.evaluationStages, options)
+	expected := "n + 1 between (lower || x) and upper xor [and] not between 1 and 2"
+
+	if err != nil || formatted != expected {
+		test.Errorf("Expected '%s', got '%s' (%v)", expected, formatted, err)
+	}
+}
+
+func TestStageFormattingFailure(test *testing.T) {
+
+	for _, value := range []interface{}{"2014-01-02", dummyParameterInstance, []string{"a"}} {
+
+		_, err := formatStage(literalStage(value), ExpressionOptions{})
+		if err == nil {
+			test.Errorf("Expected the literal '%v' to fail to be written out", value)
+		}
+	}
+}
+
+// Represents a test of binding some of an expression's parameters. The residual expression should be written as [Residual],
+// and evaluate to the same result as the original expression, given each set of [Remaining] parameters along with the [Bound] ones.
+type BindTest struct {
+	Name      string
+	Input     string
+	Options   ExpressionOptions
+	Bound     map[string]interface{}
+	Residual  string
+	Remaining []map[string]interface{}
+}
+
+func TestBind(test *testing.T) {
+
+	bindTests := []BindTest{
+		{
+			Name:      "Arithmetic",
+			Input:     "(a + b) * c",
+			Bound:     map[string]interface{}{"a": 1, "b": 2.5},
+			Residual:  "3.5 * c",
+			Remaining: []map[string]interface{}{{"c": 2.0}},
+		},
+		{
+			Name:      "Short-circuited and",
+			Input:     "x && y > 1",
+			Bound:     map[string]interface{}{"x": false},
+			Residual:  "false",
+			Remaining: []map[string]interface{}{{"y": 3.0}, {}},
+		},
+		{
+			Name:      "Identity of and",
+			Input:     "x && y > 1",
+			Bound:     map[string]interface{}{"x": true},
+			Residual:  "y > 1",
+			Remaining: []map[string]interface{}{{"y": 3.0}, {"y": 0.0}},
+		},
+		{
+			Name:      "Identity of or on the right",
+			Input:     "(y == 'a' || y == 'b') || x",
+			Bound:     map[string]interface{}{"x": false},
+			Residual:  "y == 'a' || y == 'b'",
+			Remaining: []map[string]interface{}{{"y": "b"}, {"y": "c"}},
+		},
+		{
+			Name:      "Operand of unknown type is kept",
+			Input:     "x && y",
+			Bound:     map[string]interface{}{"x": true},
+			Residual:  "true && y",
+			Remaining: []map[string]interface{}{{"y": true}, {"y": false}},
+		},
+		{
+			Name:      "Ternary with a known condition",
+			Input:     "x > 1 ? y : z",
+			Bound:     map[string]interface{}{"x": 5},
+			Residual:  "y ?? z",
+			Remaining: []map[string]interface{}{{"y": "yes", "z": "no"}, {"y": nil, "z": "no"}},
+		},
+		{
+			Name:      "Ternary with a value which can't be null",
+			Input:     "tier == 'gold' ? price * 0.9 : price",
+			Bound:     map[string]interface{}{"tier": "gold"},
+			Residual:  "price * 0.9",
+			Remaining: []map[string]interface{}{{"price": 10.0}},
+		},
+		{
+			Name:      "Ternary with a false condition",
+			Input:     "x > 1 ? y : z + 1",
+			Bound:     map[string]interface{}{"x": 0},
+			Residual:  "z + 1",
+			Remaining: []map[string]interface{}{{"z": 1.0}},
+		},
+		{
+			Name:      "Ternary with a known value",
+			Input:     "w ? x : y",
+			Bound:     map[string]interface{}{"x": 1, "y": 2},
+			Residual:  "w ? 1 : 2",
+			Remaining: []map[string]interface{}{{"w": true}, {"w": false}},
+		},
+		{
+			Name:      "Coalesce",
+			Input:     "x ?? y ?? 3",
+			Options:   ExpressionOptions{WordOperators: true},
+			Bound:     map[string]interface{}{"x": nil},
+			Residual:  "y ?? 3",
+			Remaining: []map[string]interface{}{{"y": 1.0}, {"y": nil}},
+		},
+		{
+			Name:      "Null without the keyword",
+			Input:     "x ?? y ?? 3",
+			Bound:     map[string]interface{}{"x": nil},
+			Residual:  "x ?? y ?? 3",
+			Remaining: []map[string]interface{}{{"y": 1.0}, {"y": nil}},
+		},
+		{
+			Name:      "Membership",
+			Input:     "x in [a, b, 3] && name in names",
+			Bound:     map[string]interface{}{"a": 1, "b": 2, "names": []interface{}{"Alice", "Bob"}},
+			Residual:  "x in [1, 2, 3] && name in ['Alice', 'Bob']",
+			Remaining: []map[string]interface{}{{"x": 2.0, "name": "Bob"}, {"x": 4.0, "name": "Bob"}},
+		},
+		{
+			Name:      "Partially built array",
+			Input:     "[a, b, c]",
+			Bound:     map[string]interface{}{"a": 1, "c": 3},
+			Residual:  "[1, b, 3]",
+			Remaining: []map[string]interface{}{{"b": 2.0}},
+		},
+		{
+			Name:      "Map",
+			Input:     "key in {'a': a, 'b': b}",
+			Bound:     map[string]interface{}{"a": 1, "b": "x"},
+			Residual:  "key in {'a': 1, 'b': 'x'}",
+			Remaining: []map[string]interface{}{{"key": "b"}},
+		},
+		{
+			Name:      "Pure function",
+			Input:     "upper(name) == 'BOB' && len(tags) > n",
+			Options:   ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Bound:     map[string]interface{}{"name": "bob"},
+			Residual:  "len(tags) > n",
+			Remaining: []map[string]interface{}{{"tags": []interface{}{1.0}, "n": 0.0}},
+		},
+		{
+			Name:  "Impure function",
+			Input: "next(x) > 1",
+			Options: ExpressionOptions{Functions: map[string]ExpressionFunction{
+				"next": func(arguments ...interface{}) (interface{}, error) {
+					return arguments[0].(float64) + 1, nil
+				},
+			}},
+			Bound:     map[string]interface{}{"x": 1},
+			Residual:  "next(1) > 1",
+			Remaining: []map[string]interface{}{{}},
+		},
+		{
+			Name:      "Accessor",
+			Input:     "foo.Nested.Funk + foo.Func() == name",
+			Bound:     map[string]interface{}{"foo": dummyParameterInstance},
+			Residual:  "'funkaliciousfunk' == name",
+			Remaining: []map[string]interface{}{{"name": "funkaliciousfunk"}},
+		},
+		{
+			Name:      "Collection function",
+			Input:     "any(items, # > limit) || all(others, x => x > limit)",
+			Bound:     map[string]interface{}{"items": []interface{}{1.0, 5.0}, "limit": 3},
+			Residual:  "true",
+			Remaining: []map[string]interface{}{{}},
+		},
+		{
+			Name:      "Collection function over an unbound collection",
+			Input:     "any(items, x => x > limit + 1)",
+			Bound:     map[string]interface{}{"limit": 3, "x": 100},
+			Residual:  "any(items, x => x > 4)",
+			Remaining: []map[string]interface{}{{"items": []interface{}{1.0, 5.0}}, {"items": []interface{}{1.0}}},
+		},
+		{
+			Name:      "Failing stage is kept",
+			Input:     "x + 1 > y",
+			Bound:     map[string]interface{}{"x": true},
+			Residual:  "true + 1 > y",
+			Remaining: []map[string]interface{}{},
+		},
+		{
+			Name:      "Reserved parameter name",
+			Input:     "[in] > 1 && [b c] > 2",
+			Bound:     map[string]interface{}{"unused": 1},
+			Residual:  "[in] > 1 && [b c] > 2",
+			Remaining: []map[string]interface{}{{"in": 2.0, "b c": 3.0}},
+		},
+		{
+			Name:      "Three-valued logic",
+			Input:     "(x > 1 && y) || z",
+			Options:   ExpressionOptions{ThreeValuedLogic: true},
+			Bound:     map[string]interface{}{"x": nil},
+			Residual:  "null && y || z",
+			Remaining: []map[string]interface{}{{"y": false, "z": false}, {"y": true, "z": false}, {"y": true, "z": true}},
+		},
+		{
+			Name:      "Word operators",
+			Input:     "x between lower and upper and not flag",
+			Options:   ExpressionOptions{WordOperators: true},
+			Bound:     map[string]interface{}{"lower": 1, "upper": 5},
+			Residual:  "x between 1 and 5 && !flag",
+			Remaining: []map[string]interface{}{{"x": 3.0, "flag": false}, {"x": 6.0, "flag": false}},
+		},
+		{
+			Name:      "Everything bound",
+			Input:     "a > 1 && 'b' + b == 'bc'",
+			Bound:     map[string]interface{}{"a": 2, "b": "c"},
+			Residual:  "true",
+			Remaining: []map[string]interface{}{{}},
+		},
+	}
+
+	for _, bindTest := range bindTests {
+
+		expression, err := NewExpressionWithOptions(bindTest.Input, bindTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", bindTest.Name, err)
+			continue
+		}
+
+		residual, err := expression.Bind(bindTest.Bound)
+		if err != nil {
+			test.Errorf("Test '%s' failed to bind: %v", bindTest.Name, err)
+			continue
+		}
+
+		if residual.String() != bindTest.Residual {
+			test.Errorf("Test '%s' failed: expected the residual '%s', got '%s'", bindTest.Name, bindTest.Residual, residual.String())
+		}
+
+		for _, remaining := range bindTest.Remaining {
+
+			parameters := make(map[string]interface{})
+			for name, value := range bindTest.Bound {
+				parameters[name] = value
+			}
+			for name, value := range remaining {
+				parameters[name] = value
+			}
+
+			expected, expectedErr := expression.Evaluate(parameters)
+			actual, err := residual.Evaluate(remaining)
+
+			if (err != nil) != (expectedErr != nil) || !reflect.DeepEqual(actual, expected) {
+				test.Errorf("Test '%s' failed: expected the residual to evaluate to %v (%v), got %v (%v), given %v",
+					bindTest.Name, expected, expectedErr, actual, err, remaining)
+			}
+		}
+	}
+}
+
+// Tests that parameters which can't be written as literals are kept by the residual expression.
+func TestBindKeepsParameters(test *testing.T) {
+
+	expression, err := NewExpression("foo.Func3() + name + bar")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	residual, err := expression.Bind(map[string]interface{}{"foo": &dummyParameterInstance, "name": "2014-01-02"})
+	if err != nil {
+		test.Fatalf("Unable to bind: %v", err)
+	}
+
+	if residual.String() != "'fronk' + name + bar" {
+		test.Errorf("Expected the accessor to be evaluated and the date-like string to be kept, got '%s'", residual.String())
+	}
+
+	if vars := residual.Vars(); !reflect.DeepEqual(vars, []string{"bar"}) {
+		test.Errorf("Expected only the unbound parameter to remain, got %v", vars)
+	}
+
+	result, err := residual.Evaluate(map[string]interface{}{"bar": "!", "name": "ignored"})
+	if err != nil || result != "fronk2014-01-02!" {
+		test.Errorf("Expected kept parameters to take precedence, got %v (%v)", result, err)
+	}
+
+	again, err := residual.Bind(map[string]interface{}{"bar": "?"})
+	if err != nil || again.String() != "'fronk' + name + '?'" {
+		test.Fatalf("Expected binding again to keep the earlier parameters, got %v (%v)", again, err)
+	}
+
+	result, err = again.Evaluate(nil)
+	if err != nil || result != "fronk2014-01-02?" {
+		test.Errorf("Expected 'fronk2014-01-02?', got %v (%v)", result, err)
+	}
+}
+
+func TestBindFailure(test *testing.T) {
+
+	expression, err := NewExpressionFromTokens([]ExpressionToken{
+		{Kind: function, Value: ExpressionFunction(func(arguments ...interface{}) (interface{}, error) { return 1.0, nil })},
+		{Kind: clause, Value: '('},
+		{Kind: clauseClose, Value: ')'},
+	})
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, err = expression.Bind(nil)
+	if err == nil || !strings.Contains(err.Error(), "unnamed function") {
+		test.Errorf("Expected a function without a name to fail to bind, got %v", err)
+	}
+}
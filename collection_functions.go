@@ -0,0 +1,203 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// The name of the parameter which holds the current element within a collection function's predicate,
+// unless the predicate is a lambda which names its own parameter (like "x => x > 1").
+const placeholderParameter string = "#"
+
+// collectionPredicate evaluates a collection function's predicate (or other expression) for a single element.
+type collectionPredicate func(element interface{}) (interface{}, error)
+
+// collectionFunctionDefinition describes one of the built-in functions which apply an expression to each element of an array,
+// such as "any(items, #.Price > 100)".
+type collectionFunctionDefinition struct {
+
+	// whether or not the function can be called without a predicate, like "count(items)".
+	predicateOptional bool
+
+	// evaluates the function over the given elements. [predicate] is nil if none was given.
+	evaluate func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error)
+}
+
+// collectionFunctionCall holds everything a planned collection function stage needs to know about how it was called.
+type collectionFunctionCall struct {
+	name       string
+	definition collectionFunctionDefinition
+
+	// the name that the predicate uses to refer to each element.
+	parameterName string
+}
+
+// all built-in collection functions, by name.
+// These are only recognized when called (that is, when followed by parenthesis), so they can still be used as parameter names.
+var collectionFunctions = map[string]collectionFunctionDefinition{
+	"any": {
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+			for i := 0; i < elements.Len(); i++ {
+
+				matched, err := applyCollectionPredicate(name, elements.Index(i), predicate)
+				if err != nil {
+					return nil, err
+				}
+				if matched {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	},
+	"all": {
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+			for i := 0; i < elements.Len(); i++ {
+
+				matched, err := applyCollectionPredicate(name, elements.Index(i), predicate)
+				if err != nil {
+					return nil, err
+				}
+				if !matched {
+					return false, nil
+				}
+			}
+			return true, nil
+		},
+	},
+	"none": {
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+			for i := 0; i < elements.Len(); i++ {
+
+				matched, err := applyCollectionPredicate(name, elements.Index(i), predicate)
+				if err != nil {
+					return nil, err
+				}
+				if matched {
+					return false, nil
+				}
+			}
+			return true, nil
+		},
+	},
+	"filter": {
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+
+			ret := []interface{}{}
+
+			for i := 0; i < elements.Len(); i++ {
+
+				matched, err := applyCollectionPredicate(name, elements.Index(i), predicate)
+				if err != nil {
+					return nil, err
+				}
+				if matched {
+					ret = append(ret, castToFloat64(elements.Index(i).Interface()))
+				}
+			}
+			return ret, nil
+		},
+	},
+	"map": {
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+
+			ret := make([]interface{}, elements.Len())
+
+			for i := 0; i < elements.Len(); i++ {
+
+				value, err := predicate(castToFloat64(elements.Index(i).Interface()))
+				if err != nil {
+					return nil, err
+				}
+				ret[i] = value
+			}
+			return ret, nil
+		},
+	},
+	"count": {
+		predicateOptional: true,
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+
+			if predicate == nil {
+				return float64(elements.Len()), nil
+			}
+
+			var ret float64
+			for i := 0; i < elements.Len(); i++ {
+
+				matched, err := applyCollectionPredicate(name, elements.Index(i), predicate)
+				if err != nil {
+					return nil, err
+				}
+				if matched {
+					ret++
+				}
+			}
+			return ret, nil
+		},
+	},
+	"sum": {
+		predicateOptional: true,
+		evaluate: func(name string, elements reflect.Value, predicate collectionPredicate) (interface{}, error) {
+
+			var ret float64
+			var value interface{}
+			var err error
+
+			for i := 0; i < elements.Len(); i++ {
+
+				value = castToFloat64(elements.Index(i).Interface())
+				if predicate != nil {
+					value, err = predicate(value)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				if !isFloat64(value) {
+					errorMsg := fmt.Sprintf("Value '%v' cannot be summed by '%s', it is not a number", value, name)
+					return nil, errors.New(errorMsg)
+				}
+				ret += value.(float64)
+			}
+			return ret, nil
+		},
+	},
+}
+
+// Runs the predicate of a boolean collection function (such as "any") for one element, making sure that it produces a bool.
+func applyCollectionPredicate(name string, element reflect.Value, predicate collectionPredicate) (bool, error) {
+
+	result, err := predicate(castToFloat64(element.Interface()))
+	if err != nil {
+		return false, err
+	}
+
+	matched, ok := result.(bool)
+	if !ok {
+		errorMsg := fmt.Sprintf("Value '%v' returned by the predicate of '%s' is not a bool", result, name)
+		return false, errors.New(errorMsg)
+	}
+	return matched, nil
+}
+
+// lambdaParameters makes the current element of a collection function available to its predicate,
+// while leaving all other parameters available as usual.
+type lambdaParameters struct {
+	parent Parameters
+	name   string
+	value  interface{}
+}
+
+func (p lambdaParameters) Get(name string) (interface{}, error) {
+
+	if name == p.name {
+		return p.value, nil
+	}
+	return p.parent.Get(name)
+}
+
+func (p lambdaParameters) evaluationState() *evaluationState {
+	return stateOf(p.parent)
+}
@@ -0,0 +1,220 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Coverage counts how often each stage of an expression is evaluated, skipped, true, or false, across every evaluation of it
+// since it was attached with [Expression.CollectCoverage]. It's safe to evaluate the expression concurrently while collecting coverage.
+type Coverage struct {
+
+	// the expression as it's written out, which stages are found in by their spans.
+	source string
+	spans  map[*evaluationStage]stageSpan
+
+	// the stages which are counted, in the order they're reported.
+	stages   []*evaluationStage
+	counters map[*evaluationStage]*stageCounters
+}
+
+// StageCoverage is the coverage of one stage of an expression, such as "age > 30".
+type StageCoverage struct {
+
+	// Expression is the text of the stage.
+	Expression string
+
+	// Offset is where the stage starts within the text of the whole expression, in bytes, as it's written out by [Coverage.Report].
+	Offset int
+
+	// Evaluated is how many times the stage was evaluated, including those which failed.
+	Evaluated int64
+
+	// Skipped is how many times the stage was short-circuited, because its result wasn't needed; like "b", in "false && b".
+	Skipped int64
+
+	// True and False are how many times the stage's result was true, and false.
+	True  int64
+	False int64
+}
+
+// The counts of a stage, which are changed atomically.
+type stageCounters struct {
+	evaluated, skipped, trueCount, falseCount int64
+}
+
+// CollectCoverage attaches a new Coverage to this expression, which counts how each of its stages are evaluated by Eval
+// (and its variants) from then on; replacing any Coverage which was already attached.
+// Parenthesis aren't counted, and neither are literals within the expression, unless they might be skipped.
+//
+// This should be called before the expression is evaluated concurrently, since attaching it isn't synchronized with evaluation.
+// Copies of the expression made before it's attached don't collect coverage, and those made after share it.
+// Fails if the expression can't be written out, which is how it's reported.
+func (expr *Expression) CollectCoverage() (*Coverage, error) {
+
+	source, spans, err := formatStageWithSpans(expr.evaluationStages, expr.options)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Coverage{
+		source:   source,
+		spans:    spans,
+		counters: make(map[*evaluationStage]*stageCounters),
+	}
+
+	ret.addStages(expr.evaluationStages, true)
+
+	expr.coverage = ret
+	return ret, nil
+}
+
+// Adds [stage], and those within it, to the stages which are counted. They're added in the order they're written out,
+// since a stage starts no later than its operands, and its left operand is written before its right.
+// Literals are only counted if they might be skipped, like the branches of a ternary, or if they're the whole expression.
+func (coverage *Coverage) addStages(stage *evaluationStage, skippable bool) {
+
+	if stage == nil {
+		return
+	}
+
+	if stage.symbol == noopSymbol {
+		coverage.addStages(stage.rightStage, skippable)
+		return
+	}
+
+	_, written := coverage.spans[stage]
+	if written && stage.symbol != separate && stage.symbol != betweenBounds && (stage.symbol != literal || skippable) {
+
+		coverage.stages = append(coverage.stages, stage)
+		coverage.counters[stage] = &stageCounters{}
+	}
+
+	coverage.addStages(stage.leftStage, false)
+	coverage.addStages(stage.rightStage, stage.isShortCircuitable())
+}
+
+// Stages returns the coverage of each stage which is counted, in the order they're written out;
+// those which contain others come before them.
+// The counts are read while evaluation may still be adding to them, so they're only consistent with each other if it isn't.
+func (coverage *Coverage) Stages() []StageCoverage {
+
+	ret := make([]StageCoverage, 0, len(coverage.stages))
+
+	for _, stage := range coverage.stages {
+
+		span := coverage.spans[stage]
+		counters := coverage.counters[stage]
+
+		ret = append(ret, StageCoverage{
+			Expression: coverage.source[span.start:span.end],
+			Offset:     span.start,
+			Evaluated:  atomic.LoadInt64(&counters.evaluated),
+			Skipped:    atomic.LoadInt64(&counters.skipped),
+			True:       atomic.LoadInt64(&counters.trueCount),
+			False:      atomic.LoadInt64(&counters.falseCount),
+		})
+	}
+	return ret
+}
+
+// Reset sets every count back to zero.
+func (coverage *Coverage) Reset() {
+
+	for _, counters := range coverage.counters {
+		atomic.StoreInt64(&counters.evaluated, 0)
+		atomic.StoreInt64(&counters.skipped, 0)
+		atomic.StoreInt64(&counters.trueCount, 0)
+		atomic.StoreInt64(&counters.falseCount, 0)
+	}
+}
+
+// Report returns the expression as it's written out, with a line for each stage underneath which marks where it is,
+// and how often it was evaluated, skipped, true, and false; such as:
+//
+//	a || b
+//	------ evaluated 3, true 2, false 1
+//	-      evaluated 3, true 1, false 2
+//	     ^ evaluated 2, true 1, false 1, skipped 1
+//
+// Stages which were never evaluated, or which are boolean and were never true (or never false), are marked with "^" rather than "-".
+func (coverage *Coverage) Report() string {
+
+	var builder strings.Builder
+
+	width := utf8.RuneCountInString(coverage.source)
+
+	builder.WriteString(coverage.source)
+	builder.WriteString("\n")
+
+	for _, stage := range coverage.Stages() {
+
+		column := utf8.RuneCountInString(coverage.source[:stage.Offset])
+		length := utf8.RuneCountInString(stage.Expression)
+
+		marker := "-"
+		if stage.Evaluated == 0 || (stage.True+stage.False > 0 && (stage.True == 0 || stage.False == 0)) {
+			marker = "^"
+		}
+
+		builder.WriteString(strings.Repeat(" ", column))
+		builder.WriteString(strings.Repeat(marker, length))
+		builder.WriteString(strings.Repeat(" ", width-column-length+1))
+		builder.WriteString(describeStageCoverage(stage))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func describeStageCoverage(stage StageCoverage) string {
+
+	var parts []string
+
+	if stage.Evaluated == 0 {
+		parts = append(parts, "never evaluated")
+	} else {
+		parts = append(parts, fmt.Sprintf("evaluated %d", stage.Evaluated))
+	}
+
+	if stage.True+stage.False > 0 {
+		parts = append(parts, fmt.Sprintf("true %d", stage.True), fmt.Sprintf("false %d", stage.False))
+	}
+
+	if stage.Skipped > 0 {
+		parts = append(parts, fmt.Sprintf("skipped %d", stage.Skipped))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Counts an evaluation of [stage], which gave [result].
+func (coverage *Coverage) recordEvaluation(stage *evaluationStage, result interface{}) {
+
+	counters := coverage.counters[stage]
+	if counters == nil {
+		return
+	}
+
+	atomic.AddInt64(&counters.evaluated, 1)
+
+	switch result {
+	case true:
+		atomic.AddInt64(&counters.trueCount, 1)
+	case false:
+		atomic.AddInt64(&counters.falseCount, 1)
+	}
+}
+
+// Counts [stage] as skipped, since its result wasn't needed.
+func (coverage *Coverage) recordSkip(stage *evaluationStage) {
+
+	if stage == nil {
+		return
+	}
+
+	counters := coverage.counters[unwrapNoopStage(stage)]
+	if counters != nil {
+		atomic.AddInt64(&counters.skipped, 1)
+	}
+}
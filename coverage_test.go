@@ -0,0 +1,226 @@
+package govaluate
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type CoverageTest struct {
+	Name       string
+	Input      string
+	Options    ExpressionOptions
+	Parameters []map[string]interface{}
+	Expected   string
+}
+
+func TestCoverage(test *testing.T) {
+
+	coverageTests := []CoverageTest{
+		{
+			Name:  "Short-circuited branch",
+			Input: "age > 30 && country == 'NZ' || vip",
+			Parameters: []map[string]interface{}{
+				{"age": 35, "country": "NZ", "vip": false},
+				{"age": 20, "country": "AU", "vip": true},
+				{"age": 40, "country": "AU", "vip": false},
+			},
+			Expected: `age > 30 && country == 'NZ' || vip
+---------------------------------- evaluated 3, true 2, false 1
+---------------------------        evaluated 3, true 1, false 2
+--------                           evaluated 3, true 2, false 1
+---                                evaluated 3
+            ---------------        evaluated 2, true 1, false 1, skipped 1
+            -------                evaluated 2
+                               --- evaluated 2, true 1, false 1, skipped 1
+`,
+		},
+		{
+			Name:  "Never exercised",
+			Input: "vip || (age >= 18 && country == 'NZ')",
+			Parameters: []map[string]interface{}{
+				{"vip": true},
+				{"vip": true},
+			},
+			Expected: `vip || age >= 18 && country == 'NZ'
+^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^ evaluated 2, true 2, false 0
+^^^                                 evaluated 2, true 2, false 0
+       ^^^^^^^^^^^^^^^^^^^^^^^^^^^^ never evaluated, skipped 2
+       ^^^^^^^^^                    never evaluated
+       ^^^                          never evaluated
+                    ^^^^^^^^^^^^^^^ never evaluated
+                    ^^^^^^^         never evaluated
+`,
+		},
+		{
+			Name:  "Ternary branches",
+			Input: "x > 1 ? 'many' : 'few'",
+			Parameters: []map[string]interface{}{
+				{"x": 2},
+				{"x": 0},
+				{"x": 1},
+			},
+			Expected: `x > 1 ? 'many' : 'few'
+---------------------- evaluated 3
+--------------         evaluated 3
+-----                  evaluated 3, true 1, false 2
+-                      evaluated 3
+        ------         evaluated 1, skipped 2
+                 ----- evaluated 2, skipped 1
+`,
+		},
+		{
+			Name:  "Collection functions",
+			Input: "any(items, # > 2)",
+			Parameters: []map[string]interface{}{
+				{"items": []interface{}{1.0, 3.0}},
+				{"items": []interface{}{}},
+			},
+			Expected: `any(items, # > 2)
+----------------- evaluated 2, true 1, false 1
+    -----         evaluated 2
+           -----  evaluated 2, true 1, false 1
+           -      evaluated 2
+`,
+		},
+		{
+			Name:  "Failures",
+			Input: "name == 'é' && x > 1",
+			Parameters: []map[string]interface{}{
+				{"name": "é", "x": "text"},
+			},
+			Expected: `name == 'é' && x > 1
+-------------------- evaluated 1
+^^^^^^^^^^^          evaluated 1, true 1, false 0
+----                 evaluated 1
+               ----- evaluated 1
+               -     evaluated 1
+`,
+		},
+		{
+			Name:    "Three-valued logic",
+			Input:   "a ?? false",
+			Options: ExpressionOptions{ThreeValuedLogic: true},
+			Parameters: []map[string]interface{}{
+				{"a": nil},
+				{"a": true},
+			},
+			Expected: `a ?? false
+---------- evaluated 2, true 1, false 1
+^          evaluated 2, true 1, false 0
+     ^^^^^ evaluated 1, true 0, false 1, skipped 1
+`,
+		},
+	}
+
+	for _, coverageTest := range coverageTests {
+
+		expression, err := NewExpressionWithOptions(coverageTest.Input, coverageTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", coverageTest.Name, err)
+			continue
+		}
+
+		coverage, err := expression.CollectCoverage()
+		if err != nil {
+			test.Errorf("Test '%s' failed to collect coverage: %v", coverageTest.Name, err)
+			continue
+		}
+
+		for _, parameters := range coverageTest.Parameters {
+			expression.Evaluate(parameters)
+		}
+
+		if coverage.Report() != coverageTest.Expected {
+			test.Errorf("Test '%s' failed: expected the report\n%s\ngot\n%s", coverageTest.Name, coverageTest.Expected, coverage.Report())
+		}
+	}
+}
+
+func TestConcurrentCoverage(test *testing.T) {
+
+	expression, err := NewExpression("a && b")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	coverage, err := expression.CollectCoverage()
+	if err != nil {
+		test.Fatalf("Unable to collect coverage: %v", err)
+	}
+
+	var group sync.WaitGroup
+	for i := 0; i < 8; i++ {
+
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+
+			parameters := MapParameters{"a": i%2 == 0, "b": true}
+			for j := 0; j < 100; j++ {
+
+				// each way of evaluating collects coverage.
+				switch j % 3 {
+				case 0:
+					expression.Eval(parameters)
+				case 1:
+					expression.EvalContext(context.Background(), parameters)
+				case 2:
+					expression.EvalWithTrace(parameters)
+				}
+			}
+		}(i)
+	}
+	group.Wait()
+
+	expected := []StageCoverage{
+		{Expression: "a && b", Offset: 0, Evaluated: 800, True: 400, False: 400},
+		{Expression: "a", Offset: 0, Evaluated: 800, True: 400, False: 400},
+		{Expression: "b", Offset: 5, Evaluated: 400, Skipped: 400, True: 400},
+	}
+
+	stages := coverage.Stages()
+	if len(stages) != len(expected) {
+		test.Fatalf("Expected %d stages, got %v", len(expected), stages)
+	}
+
+	for i, stage := range stages {
+		if stage != expected[i] {
+			test.Errorf("Expected %v, got %v", expected[i], stage)
+		}
+	}
+
+	coverage.Reset()
+	for _, stage := range coverage.Stages() {
+		if stage.Evaluated != 0 || stage.Skipped != 0 || stage.True != 0 || stage.False != 0 {
+			test.Errorf("Expected the counts to be reset, got %v", stage)
+		}
+	}
+}
+
+func TestCoverageIsOptIn(test *testing.T) {
+
+	expression, _ := NewExpression("a || b")
+	copied := *expression
+
+	coverage, err := expression.CollectCoverage()
+	if err != nil {
+		test.Fatalf("Unable to collect coverage: %v", err)
+	}
+
+	copied.Evaluate(map[string]interface{}{"a": true})
+
+	if coverage.Stages()[0].Evaluated != 0 {
+		test.Errorf("Expected a copy made before coverage was collected not to collect it, got %v", coverage.Stages())
+	}
+
+	// replacing the coverage leaves the old one as it was.
+	expression.Evaluate(map[string]interface{}{"a": true})
+
+	replacement, _ := expression.CollectCoverage()
+	expression.Evaluate(map[string]interface{}{"a": true})
+
+	if coverage.Stages()[0].Evaluated != 1 || replacement.Stages()[0].Evaluated != 1 {
+		test.Errorf("Expected each coverage to count one evaluation, got %v and %v", coverage.Stages(), replacement.Stages())
+	}
+}
@@ -52,8 +52,13 @@ func (dp dummyParameter) AlwaysFail() (interface{}, error) {
 	return nil, errors.New("function should always fail")
 }
 
+func (dp dummyParameter) AlwaysPanic() string {
+	panic(errors.New("function should always panic"))
+}
+
 type dummyNestedParameter struct {
 	Funk string
+	Ptr  *dummyNestedParameter
 }
 
 func (dnp dummyNestedParameter) Dunk(arg1 string) string {
@@ -84,3 +89,53 @@ var fooFailureParameters = map[string]interface{}{
 	"foo":    fooParameter.Value,
 	"fooptr": &fooPtrParameter.Value,
 }
+
+// dummyTaggedParameter is used to test accessors which are resolved through struct tags.
+type dummyTaggedParameter struct {
+	dummyTaggedEmbedded
+	*dummyTaggedPointerEmbedded
+	DummyExportedEmbedded
+
+	CreatedAt string `json:"created_at" expr:"created"`
+	Hidden    string `json:"-"`
+	Untagged  string
+	Options   string `json:",omitempty"`
+	shadowed  string
+}
+
+type dummyTaggedEmbedded struct {
+	EmbeddedName string `json:"embedded_name"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type dummyTaggedPointerEmbedded struct {
+	PointerName string `json:"pointer_name"`
+}
+
+type DummyExportedEmbedded struct {
+	Code string
+}
+
+func (de DummyExportedEmbedded) Reveal() string {
+	return de.Code
+}
+
+var dummyTaggedParameterInstance = dummyTaggedParameter{
+	dummyTaggedEmbedded: dummyTaggedEmbedded{
+		EmbeddedName: "embedded",
+		CreatedAt:    "shadowed",
+	},
+	DummyExportedEmbedded: DummyExportedEmbedded{
+		Code: "code",
+	},
+	CreatedAt: "yesterday",
+	Hidden:    "hidden",
+	Untagged:  "untagged",
+	Options:   "options",
+	shadowed:  "unexported",
+}
+
+var taggedParameter = EvaluationParameter{
+	Name:  "tagged",
+	Value: dummyTaggedParameterInstance,
+}
@@ -0,0 +1,178 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type contextKey string
+
+// Parameters which resolve every name from the value of the context, and count how often they're used without one.
+type contextDummyParameters struct {
+	plainGets int
+}
+
+func (p *contextDummyParameters) Get(name string) (interface{}, error) {
+	p.plainGets++
+	return nil, errors.New("No context given")
+}
+
+func (p *contextDummyParameters) GetContext(ctx context.Context, name string) (interface{}, error) {
+
+	value := ctx.Value(contextKey(name))
+	if value == nil {
+		return nil, errors.New("No parameter '" + name + "' found.")
+	}
+	return value, nil
+}
+
+func TestEvalContext(test *testing.T) {
+
+	var cancel context.CancelFunc
+	var calls int
+
+	options := ExpressionOptions{
+		ContextFunctions: map[string]ExpressionFunctionContext{
+			"user": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+				value, _ := ctx.Value(contextKey("user")).(string)
+				return value, nil
+			},
+			"cancel": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+				calls++
+				cancel()
+				return true, nil
+			},
+			"count": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+				calls++
+				return float64(len(arguments)), nil
+			},
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("user"), "alice")
+
+	expression, _ := NewExpressionWithOptions("user() == 'alice' && count(1, 2) == 2 && count() == 0", options)
+	result, err := expression.EvalContext(ctx, nil)
+	if err != nil || result != true {
+		test.Errorf("Expected context functions to be given the evaluation's context, got %v (%v)", result, err)
+	}
+
+	// without a context, functions are given the background context.
+	result, err = expression.Evaluate(nil)
+	if err != nil || result != false {
+		test.Errorf("Expected context functions to be given the background context, got %v (%v)", result, err)
+	}
+
+	// cancelling part-way through should stop evaluation before the next function is called.
+	ctx, cancel = context.WithCancel(context.Background())
+	calls = 0
+
+	expression, _ = NewExpressionWithOptions("cancel() && count(1) == 1", options)
+	result, err = expression.EvalContext(ctx, nil)
+	if err != context.Canceled {
+		test.Errorf("Expected evaluation to be cancelled, got %v (%v)", result, err)
+	}
+	if calls != 1 {
+		test.Errorf("Expected evaluation to stop once cancelled, but functions were called %d times", calls)
+	}
+
+	// and the same within each element of a collection function.
+	ctx, cancel = context.WithCancel(context.Background())
+	calls = 0
+
+	expression, _ = NewExpressionWithOptions("all([1, 2, 3], cancel())", options)
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.Canceled || calls != 1 {
+		test.Errorf("Expected collection function to stop once cancelled, got %v after %d calls", err, calls)
+	}
+
+	// a context which is already done shouldn't evaluate anything.
+	calls = 0
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.Canceled || calls != 0 {
+		test.Errorf("Expected a cancelled context to fail immediately, got %v after %d calls", err, calls)
+	}
+	cancel()
+}
+
+func TestEvalContextDeadline(test *testing.T) {
+
+	options := ExpressionOptions{
+		ContextFunctions: map[string]ExpressionFunctionContext{
+			"slow": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Second):
+					return true, nil
+				}
+			},
+		},
+	}
+
+	expression, _ := NewExpressionWithOptions("slow() && slow()", options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := expression.EvalContext(ctx, nil)
+
+	if err != context.DeadlineExceeded {
+		test.Errorf("Expected deadline to be exceeded, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		test.Errorf("Expected evaluation to stop promptly once the deadline passed, took %v", time.Since(start))
+	}
+}
+
+func TestParametersContext(test *testing.T) {
+
+	parameters := &contextDummyParameters{}
+	ctx := context.WithValue(context.Background(), contextKey("foo"), 2)
+
+	expression, _ := NewExpression("foo * 2 == 4")
+
+	result, err := expression.EvalContext(ctx, parameters)
+	if err != nil || result != true {
+		test.Errorf("Expected parameters to be resolved with the context, got %v (%v)", result, err)
+	}
+	if parameters.plainGets != 0 {
+		test.Errorf("Expected GetContext to be used instead of Get, but Get was used %d times", parameters.plainGets)
+	}
+
+	// evaluating without a context still uses Get.
+	_, err = expression.Eval(parameters)
+	if err == nil || !strings.Contains(err.Error(), "No context given") {
+		test.Errorf("Expected Get to be used without a context, got %v", err)
+	}
+
+	// parameters which aren't aware of contexts are used as usual.
+	result, err = expression.EvalContext(ctx, MapParameters{"foo": 2})
+	if err != nil || result != true {
+		test.Errorf("Expected plain parameters to be usable with a context, got %v (%v)", result, err)
+	}
+}
+
+func TestInvalidContextFunctions(test *testing.T) {
+
+	function := func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	_, err := NewExpressionWithOptions("1", ExpressionOptions{
+		Functions:        map[string]ExpressionFunction{"foo": func(arguments ...interface{}) (interface{}, error) { return nil, nil }},
+		ContextFunctions: map[string]ExpressionFunctionContext{"foo": function},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Function 'foo' is defined more than once") {
+		test.Errorf("Expected duplicate function names to be rejected, got %v", err)
+	}
+
+	_, err = NewExpressionWithOptions("1", ExpressionOptions{ContextFunctions: map[string]ExpressionFunctionContext{"foo": nil}})
+	if err == nil || !strings.Contains(err.Error(), "Context function 'foo' is nil") {
+		test.Errorf("Expected nil context functions to be rejected, got %v", err)
+	}
+}
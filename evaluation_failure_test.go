@@ -17,6 +17,7 @@ type EvaluationFailureTest struct {
 	Name       string
 	Input      string
 	Functions  map[string]ExpressionFunction
+	Options    ExpressionOptions
 	Parameters map[string]interface{}
 	Expected   string
 }
@@ -395,6 +396,67 @@ func TestFunctionExecution(test *testing.T) {
 	runEvaluationFailureTests(evaluationTests, test)
 }
 
+func TestCollectionLiteralTyping(test *testing.T) {
+
+	evaluationTests := []EvaluationFailureTest{
+		{
+			Name:     "Numeric map key",
+			Input:    "{1: 'one'}",
+			Expected: "cannot be used as a map key",
+		},
+		{
+			Name:     "Membership in number",
+			Input:    "1 in 2",
+			Expected: "membership requires",
+		},
+		{
+			Name:     "Number membership in string",
+			Input:    "1 in 'abc'",
+			Expected: "membership requires",
+		},
+		{
+			Name:     "Array arithmetic",
+			Input:    "[1,] + 1",
+			Expected: invalidModifierTypes,
+		},
+	}
+
+	runEvaluationFailureTests(evaluationTests, test)
+}
+
+func TestCollectionFunctionTyping(test *testing.T) {
+
+	evaluationTests := []EvaluationFailureTest{
+		{
+			Name:     "Collection function on a number",
+			Input:    "any(1, # > 0)",
+			Expected: "cannot be used with the collection function 'any'",
+		},
+		{
+			Name:     "Collection function on a string",
+			Input:    "count('abc')",
+			Expected: "cannot be used with the collection function 'count'",
+		},
+		{
+			Name:     "Non-boolean predicate",
+			Input:    "all([1, 2], # + 1)",
+			Expected: "is not a bool",
+		},
+		{
+			Name:     "Summing strings",
+			Input:    "sum(['a', 'b'])",
+			Expected: "cannot be summed",
+		},
+		{
+			Name:     "Predicate error",
+			Input:    "any([1,], # > 'a')",
+			Expected: invalidComparatorTypes,
+		},
+	}
+
+	runEvaluationFailureTests(evaluationTests, test)
+}
+
 func TestInvalidParameterCalls(test *testing.T) {
 
 	evaluationTests := []EvaluationFailureTest{
@@ -404,6 +466,30 @@ func TestInvalidParameterCalls(test *testing.T) {
 			Parameters: fooFailureParameters,
 			Expected:   invalidParameterCall,
 		},
+		{
+			Name:       "Unexported parameter field reference",
+			Input:      "foo.bar",
+			Parameters: fooFailureParameters,
+			Expected:   invalidParameterCall,
+		},
+		{
+			Name:       "Hidden tagged field reference",
+			Input:      "tagged.Hidden",
+			Parameters: map[string]interface{}{"tagged": dummyTaggedParameterInstance},
+			Expected:   invalidParameterCall,
+		},
+		{
+			Name:       "Unexported tagged field reference",
+			Input:      "tagged.shadowed",
+			Parameters: map[string]interface{}{"tagged": dummyTaggedParameterInstance},
+			Expected:   invalidParameterCall,
+		},
+		{
+			Name:       "Field reference through nil embedded pointer",
+			Input:      "tagged.pointer_name",
+			Parameters: map[string]interface{}{"tagged": dummyTaggedParameterInstance},
+			Expected:   "embedded struct",
+		},
 		{
 			Name:       "Parameter method call on missing function",
 			Input:      "foo.NotExist()",
@@ -457,7 +543,7 @@ func runEvaluationFailureTests(evaluationTests []EvaluationFailureTest, test *te
 		if len(testCase.Functions) > 0 {
 			expression, err = NewExpressionWithFunctions(testCase.Input, testCase.Functions)
 		} else {
-			expression, err = NewExpression(testCase.Input)
+			expression, err = NewExpressionWithOptions(testCase.Input, testCase.Options)
 		}
 
 		if err != nil {
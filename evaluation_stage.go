@@ -1,6 +1,7 @@
 package govaluate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -15,6 +16,9 @@ const (
 	comparatorErrorFormat string = "Value '%v' cannot be used with the comparator '%v', it is not a number"
 	ternaryErrorFormat    string = "Value '%v' cannot be used with the ternary operator '%v', it is not a bool"
 	prefixErrorFormat     string = "Value '%v' cannot be used with the prefix '%v'"
+	mapKeyErrorFormat     string = "Value '%v' cannot be used as a map key with '%v', it is not a string"
+	membershipErrorFormat string = "Value '%v' cannot be used with the comparator '%v', membership requires an array, a map, or two strings"
+	betweenErrorFormat    string = "Value '%v' cannot be used with the comparator '%v', it and both bounds must all be numbers or all be strings"
 )
 
 type evaluationOperator func(left interface{}, right interface{}, parameters Parameters) (interface{}, error)
@@ -40,6 +44,17 @@ type evaluationStage struct {
 
 	// regardless of which type check is used, this string format will be used as the error message for type errors
 	typeErrorFormat string
+
+	// for collection functions (like "any(items, #.Price > 100)"), which evaluate their right stage once per element of
+	// the collection in their left stage, instead of using [operator].
+	collection *collectionFunctionCall
+
+	// for calls to functions whose signature is known ahead of time.
+	function *functionSignature
+
+	// the name of the parameter, function, or accessor (as written, like "foo?.Bar") which this stage refers to, if any.
+	// Only used to describe the stage, such as when writing it back out as an expression.
+	name string
 }
 
 var (
@@ -62,6 +77,9 @@ func (es *evaluationStage) setToNonStage(other evaluationStage) {
 	es.rightTypeCheck = other.rightTypeCheck
 	es.typeCheck = other.typeCheck
 	es.typeErrorFormat = other.typeErrorFormat
+	es.collection = other.collection
+	es.function = other.function
+	es.name = other.name
 }
 
 func (es *evaluationStage) isShortCircuitable() bool {
@@ -160,6 +178,47 @@ func orStage(left interface{}, right interface{}, parameters Parameters) (interf
 	return boolIface(left.(bool) || right.(bool)), nil
 }
 
+func xorStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(left.(bool) != right.(bool)), nil
+}
+
+// Returns whether [left] is null. The right side of "is null" is always the null literal.
+func isNullStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(left == nil), nil
+}
+
+func isNotNullStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(left != nil), nil
+}
+
+// Pairs the lower and upper bounds of a "between", which are given to it as its right side.
+func betweenBoundsStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return []interface{}{left, right}, nil
+}
+
+// Returns whether [left] is within the pair of bounds (inclusive) which make up [right].
+func betweenStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	bounds := right.([]interface{})
+
+	if isString(left) {
+		value := left.(string)
+		return boolIface(value >= bounds[0].(string) && value <= bounds[1].(string)), nil
+	}
+
+	value := left.(float64)
+	return boolIface(value >= bounds[0].(float64) && value <= bounds[1].(float64)), nil
+}
+
+func notBetweenStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	result, err := betweenStage(left, right, parameters)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(!result.(bool)), nil
+}
+
 func negateStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	return -right.(float64), nil
 }
@@ -186,32 +245,59 @@ func ternaryElseStage(left interface{}, right interface{}, parameters Parameters
 	return right, nil
 }
 
-func regexStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	var (
-		pattern *regexp.Regexp
-		err     error
-	)
+// Returns the operator for '=~' (or '!~', if [negated]). Patterns which aren't precompiled are compiled through [cache], within the regex [limits].
+func makeRegexStage(cache *RegexCache, limits Limits, negated bool) evaluationOperator {
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+		var (
+			pattern *regexp.Regexp
+			err     error
+		)
 
-	switch right.(type) {
-	case string:
-		pattern, err = regexp.Compile(right.(string))
-		if err != nil {
-			return nil, fmt.Errorf("Unable to compile regexp pattern '%v': %v", right, err)
+		switch right.(type) {
+		case string:
+			pattern, err = compileRegex(right.(string), cache, limits)
+			if err != nil {
+				if _, isLimit := err.(*LimitError); isLimit {
+					return nil, err
+				}
+				return nil, fmt.Errorf("Unable to compile regexp pattern '%v': %v", right, err)
+			}
+		case *regexp.Regexp:
+			pattern = right.(*regexp.Regexp)
 		}
-	case *regexp.Regexp:
-		pattern = right.(*regexp.Regexp)
+
+		return pattern.Match([]byte(left.(string))) != negated, nil
 	}
+}
 
-	return pattern.Match([]byte(left.(string))), nil
+// Returns the operator for a pattern matching comparator, such as 'like' or 'not glob'. Patterns are compiled through [cache], within the regex [limits].
+func makeMatchStage(symbol OperatorSymbol, cache *RegexCache, limits Limits) evaluationOperator {
+
+	negated := symbol == notLike || symbol == notIlike || symbol == notGlob
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+		pattern, err := compileMatchPattern(symbol, right.(string), cache, limits)
+		if err != nil {
+			return nil, err
+		}
+		return pattern.MatchString(left.(string)) != negated, nil
+	}
 }
 
-func notRegexStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	ret, err := regexStage(left, right, parameters)
+// Returns the operator for a pattern matching comparator whose pattern is the constant [source], which is compiled right away.
+func makeConstantMatchStage(symbol OperatorSymbol, source string, cache *RegexCache, limits Limits) (evaluationOperator, error) {
+
+	pattern, err := compileMatchPattern(symbol, source, cache, limits)
 	if err != nil {
 		return nil, err
 	}
 
-	return !(ret.(bool)), nil
+	negated := symbol == notLike || symbol == notIlike || symbol == notGlob
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+		return pattern.MatchString(left.(string)) != negated, nil
+	}, nil
 }
 
 func bitwiseOrStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
@@ -251,21 +337,51 @@ func makeLiteralStage(literal interface{}) evaluationOperator {
 	}
 }
 
-func makeFunctionStage(function ExpressionFunction) evaluationOperator {
+// [hasArguments] distinguishes a call with no arguments from one whose only argument is nil.
+func makeFunctionStage(function ExpressionFunction, hasArguments bool) evaluationOperator {
 	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-		if right == nil {
+		if !hasArguments {
 			return function()
 		}
 
 		switch right.(type) {
-		case []interface{}:
-			return function(right.([]interface{})...)
+		case argumentList:
+			return function(right.(argumentList)...)
 		default:
 			return function(right)
 		}
 	}
 }
 
+// Like makeFunctionStage, except the function is given the context of the evaluation (or context.Background(), if there is none).
+func makeContextFunctionStage(function ExpressionFunctionContext, hasArguments bool) evaluationOperator {
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+		ctx := contextOf(parameters)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if !hasArguments {
+			return function(ctx)
+		}
+
+		switch right.(type) {
+		case argumentList:
+			return function(ctx, right.(argumentList)...)
+		default:
+			return function(ctx, right)
+		}
+	}
+}
+
+// Adapts a context function to be called without a context, as the value of its function token.
+func withBackgroundContext(function ExpressionFunctionContext) ExpressionFunction {
+	return func(arguments ...interface{}) (interface{}, error) {
+		return function(context.Background(), arguments...)
+	}
+}
+
 func typeConvertParam(p reflect.Value, t reflect.Type) (ret reflect.Value, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -307,14 +423,51 @@ func typeConvertParams(method reflect.Value, params []reflect.Value) ([]reflect.
 	return params, nil
 }
 
-func makeAccessorStage(pair []string) evaluationOperator {
-	reconstructed := strings.Join(pair, ".")
+// Optional links in an accessor chain (such as "foo?.Bar") are marked by prefixing the name of the member being accessed.
+const optionalAccessPrefix string = "?"
+
+// Splits the [pair] held by an accessor token into the names of each link, and whether or not each link is optional;
+// along with the accessor as it was written in the expression.
+func splitAccessor(pair []string) ([]string, []bool, string) {
+
+	names := make([]string, len(pair))
+	optional := make([]bool, len(pair))
+
+	reconstructed := pair[0]
+	names[0] = pair[0]
+
+	for i := 1; i < len(pair); i++ {
+
+		names[i] = strings.TrimPrefix(pair[i], optionalAccessPrefix)
+		optional[i] = names[i] != pair[i]
+
+		if optional[i] {
+			reconstructed += "?." + names[i]
+		} else {
+			reconstructed += "." + names[i]
+		}
+	}
+
+	return names, optional, reconstructed
+}
+
+func makeAccessorStage(pair []string, tag string, policy AccessPolicy) evaluationOperator {
+
+	names, optional, reconstructed := splitAccessor(pair)
+	checksPolicy := !policy.isUnrestricted()
+
+	accessorError := func(message string) error {
+		return &AccessorError{
+			Accessor: reconstructed,
+			Message:  message,
+		}
+	}
 
 	return func(left interface{}, right interface{}, parameters Parameters) (ret interface{}, err error) {
 
 		var params []reflect.Value
 
-		value, err := parameters.Get(pair[0])
+		value, err := parameters.Get(names[0])
 		if err != nil {
 			return nil, err
 		}
@@ -324,18 +477,28 @@ func makeAccessorStage(pair []string) evaluationOperator {
 		// therefore every call to an accessor sets up a defer that tries to recover from panics, converting them to errors.
 		defer func() {
 			if r := recover(); r != nil {
-				errorMsg := fmt.Sprintf("Failed to access '%s': %v", reconstructed, r.(string))
-				err = errors.New(errorMsg)
+				err = &AccessorError{
+					Accessor:  reconstructed,
+					Recovered: r,
+				}
 				ret = nil
 			}
 		}()
 
-		for i := 1; i < len(pair); i++ {
+		for i := 1; i < len(names); i++ {
 
 			coreValue := reflect.ValueOf(value)
 
 			var corePtrVal reflect.Value
 
+			// nil links either end an optional chain, or can't be accessed at all.
+			if isNilValue(coreValue) {
+				if optional[i] {
+					return nil, nil
+				}
+				return nil, accessorError("Unable to access '" + names[i] + "', '" + names[i-1] + "' is nil")
+			}
+
 			// if this is a pointer, resolve it.
 			if coreValue.Kind() == reflect.Ptr {
 				corePtrVal = coreValue
@@ -343,29 +506,49 @@ func makeAccessorStage(pair []string) evaluationOperator {
 			}
 
 			if coreValue.Kind() != reflect.Struct {
-				return nil, errors.New("Unable to access '" + pair[i] + "', '" + pair[i-1] + "' is not a struct")
+				return nil, accessorError("Unable to access '" + names[i] + "', '" + names[i-1] + "' is not a struct")
 			}
 
-			field := coreValue.FieldByName(pair[i])
-			if field != (reflect.Value{}) {
+			accessorField, found := findAccessorFields(coreValue.Type(), tag)[names[i]]
+			if found {
+
+				if checksPolicy {
+					denial := policy.promotedFieldDenial(coreValue.Type(), accessorField.index)
+					if denial != "" {
+						return nil, accessorError(denial)
+					}
+				}
+
+				field, reachable := accessorFieldByIndex(coreValue, accessorField.index)
+				if !reachable {
+					return nil, accessorError("Unable to access '" + names[i] + "', an embedded struct of '" + names[i-1] + "' is nil")
+				}
+
 				value = field.Interface()
 				continue
 			}
 
-			method := coreValue.MethodByName(pair[i])
+			method := coreValue.MethodByName(names[i])
 			if method == (reflect.Value{}) {
 				if corePtrVal.IsValid() {
-					method = corePtrVal.MethodByName(pair[i])
+					method = corePtrVal.MethodByName(names[i])
 				}
 				if method == (reflect.Value{}) {
-					return nil, errors.New("No method or field '" + pair[i] + "' present on parameter '" + pair[i-1] + "'")
+					return nil, accessorError("No method or field '" + names[i] + "' present on parameter '" + names[i-1] + "'")
+				}
+			}
+
+			if checksPolicy {
+				denial := policy.promotedMethodDenial(coreValue.Type(), names[i])
+				if denial != "" {
+					return nil, accessorError(denial)
 				}
 			}
 
 			switch right.(type) {
-			case []interface{}:
+			case argumentList:
 
-				givenParams := right.([]interface{})
+				givenParams := right.(argumentList)
 				params = make([]reflect.Value, len(givenParams))
 				for idx := range givenParams {
 					params[idx] = reflect.ValueOf(givenParams[idx])
@@ -384,14 +567,27 @@ func makeAccessorStage(pair []string) evaluationOperator {
 			params, err = typeConvertParams(method, params)
 
 			if err != nil {
-				return nil, errors.New("Method call failed - '" + pair[0] + "." + pair[1] + "': " + err.Error())
+				return nil, accessorError("Method call failed - '" + names[0] + "." + names[1] + "': " + err.Error())
+			}
+
+			if policy.ApproveCall != nil {
+
+				arguments := make([]interface{}, len(params))
+				for idx := range params {
+					arguments[idx] = params[idx].Interface()
+				}
+
+				err = policy.ApproveCall(value, names[i], arguments)
+				if err != nil {
+					return nil, accessorError("Call to method '" + names[i] + "' was not approved: " + err.Error())
+				}
 			}
 
 			returned := method.Call(params)
 			retLength := len(returned)
 
 			if retLength == 0 {
-				return nil, errors.New("Method call '" + pair[i-1] + "." + pair[i] + "' did not return any values.")
+				return nil, accessorError("Method call '" + names[i-1] + "." + names[i] + "' did not return any values.")
 			}
 
 			if retLength == 1 {
@@ -413,7 +609,7 @@ func makeAccessorStage(pair []string) evaluationOperator {
 				continue
 			}
 
-			return nil, errors.New("Method call '" + pair[0] + "." + pair[1] + "' did not return either one value, or a value and an error. Cannot interpret meaning.")
+			return nil, accessorError("Method call '" + names[0] + "." + names[1] + "' did not return either one value, or a value and an error. Cannot interpret meaning.")
 		}
 
 		value = castToFloat64(value)
@@ -421,6 +617,20 @@ func makeAccessorStage(pair []string) evaluationOperator {
 	}
 }
 
+// Returns true if the given value is nil, or a nil pointer or interface.
+func isNilValue(value reflect.Value) bool {
+
+	if !value.IsValid() {
+		return true
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
 func separatorStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	var ret []interface{}
 
@@ -434,13 +644,118 @@ func separatorStage(left interface{}, right interface{}, parameters Parameters)
 	return ret, nil
 }
 
+// argumentList holds the arguments to a function or method call.
+// It's kept distinct from []interface{} so that an array passed as a single argument isn't spread across several arguments.
+type argumentList []interface{}
+
+func argumentSeparatorStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	var ret argumentList
+
+	switch left.(type) {
+	case argumentList:
+		ret = append(left.(argumentList), right)
+	default:
+		ret = argumentList{left, right}
+	}
+
+	return ret, nil
+}
+
+// Appends one element to an array literal. [left] is always the array built so far by the preceding elements,
+// which is private to this evaluation, so it can be appended to in place.
+func arrayElementStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return append(left.([]interface{}), right), nil
+}
+
+// mapLiteralEntry is a single key/value pair from a map literal, on its way to being added to the map.
+type mapLiteralEntry struct {
+	key   string
+	value interface{}
+}
+
+func mapEntryStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return mapLiteralEntry{left.(string), right}, nil
+}
+
+// Adds one entry to a map literal. The first stage of every map literal has neither a left nor right,
+// and creates the (empty) map that each following entry is added to.
+func mapElementStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	var ret map[string]interface{}
+
+	if left == nil {
+		ret = make(map[string]interface{})
+	} else {
+		ret = left.(map[string]interface{})
+	}
+
+	if right != nil {
+		entry := right.(mapLiteralEntry)
+		ret[entry.key] = entry.value
+	}
+
+	return ret, nil
+}
+
 func inStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	for _, value := range right.([]interface{}) {
-		if left == value {
-			return true, nil
+
+	// strings contain substrings
+	if isString(right) {
+		return boolIface(strings.Contains(right.(string), left.(string))), nil
+	}
+
+	container := reflect.ValueOf(right)
+
+	switch container.Kind() {
+	case reflect.Map:
+		return boolIface(mapHasKey(container, left)), nil
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < container.Len(); i++ {
+			if membersEqual(left, container.Index(i).Interface()) {
+				return _true, nil
+			}
+		}
+	}
+
+	return _false, nil
+}
+
+func notInStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	ret, err := inStage(left, right, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return boolIface(!ret.(bool)), nil
+}
+
+// Checks whether [candidate] is equal to [member] of an array or map, after the member has been normalized the same way parameters are.
+// This way numbers of any width (such as the elements of an []int) can be matched by the float64 values that expressions use.
+func membersEqual(candidate interface{}, member interface{}) bool {
+	return reflect.DeepEqual(candidate, castToFloat64(member))
+}
+
+func mapHasKey(container reflect.Value, key interface{}) bool {
+
+	keyType := container.Type().Key()
+
+	// most maps can be looked up directly; the key just needs to be of the right type.
+	if key != nil {
+
+		keyValue := reflect.ValueOf(key)
+		if keyValue.Type().AssignableTo(keyType) && keyValue.Type().Comparable() {
+			return container.MapIndex(keyValue).IsValid()
+		}
+	}
+
+	// otherwise (such as when looking up a float64 in a map with int keys), compare against each normalized key.
+	for _, mapKey := range container.MapKeys() {
+		if membersEqual(key, mapKey.Interface()) {
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 func isString(value interface{}) bool {
@@ -502,19 +817,52 @@ func comparatorTypeCheck(left interface{}, right interface{}) bool {
 	return false
 }
 
+// Checks that a value and both of its bounds (paired by betweenBoundsStage) can be compared with each other.
+func betweenTypeCheck(left interface{}, right interface{}) bool {
+
+	bounds, isBounds := right.([]interface{})
+	if !isBounds || len(bounds) != 2 {
+		return false
+	}
+	return comparatorTypeCheck(left, bounds[0]) && comparatorTypeCheck(left, bounds[1])
+}
+
 // Equality checks should happend between same types.
 func equalityTypeCheck(left interface{}, right interface{}) bool {
 	return reflect.TypeOf(left) == reflect.TypeOf(right)
 }
 
+// Where "null" is a keyword, anything can also be checked for equality with null.
+func nullableEqualityTypeCheck(left interface{}, right interface{}) bool {
+	return left == nil || right == nil || reflect.TypeOf(left) == reflect.TypeOf(right)
+}
+
 func isArray(value interface{}) bool {
-	switch value.(type) {
-	case []interface{}:
+
+	if value == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
 		return true
 	}
 	return false
 }
 
+func isMap(value interface{}) bool {
+	return value != nil && reflect.TypeOf(value).Kind() == reflect.Map
+}
+
+// Membership can be checked in any array or map, or (for substrings) between two strings.
+func membershipTypeCheck(left interface{}, right interface{}) bool {
+
+	if isString(right) {
+		return isString(left)
+	}
+	return isArray(right) || isMap(right)
+}
+
 // Converting a boolean to an interface{} requires an allocation.
 // We can use interned bools to avoid this cost.
 func boolIface(b bool) interface{} {
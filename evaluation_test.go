@@ -3,6 +3,7 @@ package govaluate
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ type EvaluationTest struct {
 	Name       string
 	Input      string
 	Functions  map[string]ExpressionFunction
+	Options    ExpressionOptions
 	Parameters []EvaluationParameter
 	Expected   interface{}
 }
@@ -503,6 +505,72 @@ func TestNoParameterEvaluation(test *testing.T) {
 			Input:    "!(1 in (1, 2, 3))",
 			Expected: false,
 		},
+		{
+
+			Name:     "Single-element array membership",
+			Input:    "1 in [1,]",
+			Expected: true,
+		},
+		{
+
+			Name:     "Array literal membership",
+			Input:    "'b' in ['a', 'b', 'c']",
+			Expected: true,
+		},
+		{
+
+			Name:     "Empty array membership",
+			Input:    "1 in []",
+			Expected: false,
+		},
+		{
+
+			Name:     "Array literal equality",
+			Input:    "[1, [2, 3]] == [1, [2, 3]]",
+			Expected: true,
+		},
+		{
+
+			Name:     "Map literal equality",
+			Input:    "{'a': 1, 'b': [2,]} == {'b': [2,], 'a': 1}",
+			Expected: true,
+		},
+		{
+
+			Name:     "Negated array membership",
+			Input:    "4 not in [1, 2, 3]",
+			Expected: true,
+		},
+		{
+
+			Name:     "Negated array membership, upper case",
+			Input:    "1 NOT IN [1, 2, 3]",
+			Expected: false,
+		},
+		{
+
+			Name:     "Substring membership",
+			Input:    "'ell' in 'hello'",
+			Expected: true,
+		},
+		{
+
+			Name:     "Negated substring membership",
+			Input:    "'elk' not in 'hello'",
+			Expected: true,
+		},
+		{
+
+			Name:     "Map key membership",
+			Input:    "'a' in {'a': 1}",
+			Expected: true,
+		},
+		{
+
+			Name:     "Nested array membership",
+			Input:    "[1,] in [[1,], [2,]]",
+			Expected: true,
+		},
 		{
 
 			Name:     "Logical operator reordering (#30)",
@@ -1417,6 +1485,132 @@ func TestParameterizedEvaluation(test *testing.T) {
 			Parameters: []EvaluationParameter{fooParameter},
 			Expected:   false,
 		},
+		{
+
+			Name:       "Membership in string slice parameter",
+			Input:      "'b' in letters",
+			Parameters: []EvaluationParameter{{Name: "letters", Value: []string{"a", "b"}}},
+			Expected:   true,
+		},
+		{
+
+			Name:       "Membership in int slice parameter",
+			Input:      "2 in numbers",
+			Parameters: []EvaluationParameter{{Name: "numbers", Value: []int{1, 2, 3}}},
+			Expected:   true,
+		},
+		{
+
+			Name:       "Membership in uint8 array parameter",
+			Input:      "number in numbers",
+			Parameters: []EvaluationParameter{{Name: "number", Value: int64(3)}, {Name: "numbers", Value: [3]uint8{1, 2, 3}}},
+			Expected:   true,
+		},
+		{
+
+			Name:       "Negated membership in float32 slice parameter",
+			Input:      "1.5 not in numbers",
+			Parameters: []EvaluationParameter{{Name: "numbers", Value: []float32{1.5, 2}}},
+			Expected:   false,
+		},
+		{
+
+			Name:       "Membership in string-keyed map parameter",
+			Input:      "'a' in counts",
+			Parameters: []EvaluationParameter{{Name: "counts", Value: map[string]int{"a": 1}}},
+			Expected:   true,
+		},
+		{
+
+			Name:       "Membership in int-keyed map parameter",
+			Input:      "2 in names",
+			Parameters: []EvaluationParameter{{Name: "names", Value: map[int]string{1: "one", 2: "two"}}},
+			Expected:   true,
+		},
+		{
+
+			Name:       "Missing key in int-keyed map parameter",
+			Input:      "2.5 in names",
+			Parameters: []EvaluationParameter{{Name: "names", Value: map[int]string{1: "one", 2: "two"}}},
+			Expected:   false,
+		},
+		{
+
+			Name:       "Optional accessor on nil link",
+			Input:      "foo.Nested.Ptr?.Funk",
+			Parameters: []EvaluationParameter{fooParameter},
+			Expected:   nil,
+		},
+		{
+
+			Name:       "Optional accessor on nil parameter",
+			Input:      "nothing?.Nested.Funk ?? 'default'",
+			Parameters: []EvaluationParameter{{Name: "nothing", Value: nil}},
+			Expected:   "default",
+		},
+		{
+
+			Name:       "Optional accessor on nil pointer parameter",
+			Input:      "nothing?.Nested.Funk ?? 'default'",
+			Parameters: []EvaluationParameter{{Name: "nothing", Value: (*dummyParameter)(nil)}},
+			Expected:   "default",
+		},
+		{
+
+			Name:       "Optional accessor on non-nil link",
+			Input:      "foo?.Nested?.Funk",
+			Parameters: []EvaluationParameter{fooParameter},
+			Expected:   "funkalicious",
+		},
+		{
+
+			Name:       "Tagged field access",
+			Input:      "tagged.created_at",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "yesterday",
+		},
+		{
+
+			Name:       "Tagged field access by Go name",
+			Input:      "tagged.CreatedAt",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "yesterday",
+		},
+		{
+
+			Name:       "Untagged field access",
+			Input:      "tagged.Untagged",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "untagged",
+		},
+		{
+
+			Name:       "Field access with options-only tag",
+			Input:      "tagged.Options",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "options",
+		},
+		{
+
+			Name:       "Promoted embedded field access",
+			Input:      "tagged.embedded_name",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "embedded",
+		},
+		{
+
+			Name:       "Promoted field of an exported embedded struct",
+			Input:      "tagged.Code",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "code",
+		},
+		{
+
+			Name:       "Exported embedded struct access by name",
+			Input:      "tagged.DummyExportedEmbedded.Code",
+			Parameters: []EvaluationParameter{taggedParameter},
+			Expected:   "code",
+		},
 	}
 
 	runEvaluationTests(evaluationTests, test)
@@ -1474,7 +1668,7 @@ func runEvaluationTests(evaluationTests []EvaluationTest, test *testing.T) {
 		if evaluationTest.Functions != nil {
 			expression, err = NewExpressionWithFunctions(evaluationTest.Input, evaluationTest.Functions)
 		} else {
-			expression, err = NewExpression(evaluationTest.Input)
+			expression, err = NewExpressionWithOptions(evaluationTest.Input, evaluationTest.Options)
 		}
 
 		if err != nil {
@@ -1508,3 +1702,251 @@ func runEvaluationTests(evaluationTests []EvaluationTest, test *testing.T) {
 		}
 	}
 }
+
+// Tests that accessors can be resolved through a custom struct tag.
+func TestCustomAccessorTag(test *testing.T) {
+
+	expression, err := NewExpressionWithOptions("tagged.created", ExpressionOptions{AccessorTag: "expr"})
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"tagged": &dummyTaggedParameterInstance})
+	if err != nil {
+		test.Fatalf("Unable to evaluate expression: %v", err)
+	}
+
+	if result != "yesterday" {
+		test.Errorf("Expected 'yesterday', got '%v'", result)
+	}
+
+	// the json tag shouldn't be consulted when a different tag is used.
+	expression, _ = NewExpressionWithOptions("tagged.created_at", ExpressionOptions{AccessorTag: "expr"})
+	_, err = expression.Evaluate(map[string]interface{}{"tagged": dummyTaggedParameterInstance})
+	if err == nil {
+		test.Errorf("Expected json-tagged field name to be unavailable under the 'expr' tag")
+	}
+}
+
+// Tests that accessor failures, including panics of any type, are reported as AccessorErrors.
+func TestAccessorErrors(test *testing.T) {
+
+	cases := map[string]interface{}{
+		"foo.Nested.Ptr.Funk": nil,
+		"foo.AlwaysPanic()":   errors.New("function should always panic"),
+	}
+
+	for input, recovered := range cases {
+
+		expression, err := NewExpression(input)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		_, err = expression.Evaluate(map[string]interface{}{"foo": dummyParameterInstance})
+
+		accessorErr, ok := err.(*AccessorError)
+		if !ok {
+			test.Errorf("Expected '%s' to fail with an AccessorError, got '%v'", input, err)
+			continue
+		}
+
+		if accessorErr.Accessor != input && accessorErr.Accessor+"()" != input {
+			test.Errorf("Expected AccessorError for '%s', got one for '%s'", input, accessorErr.Accessor)
+		}
+
+		if fmt.Sprintf("%v", accessorErr.Recovered) != fmt.Sprintf("%v", recovered) {
+			test.Errorf("Expected '%s' to recover '%v', got '%v'", input, recovered, accessorErr.Recovered)
+		}
+	}
+}
+
+// Tests that array and map literals evaluate to the expected values,
+// and are passed to functions as a single argument.
+func TestCollectionLiterals(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"args": func(arguments ...interface{}) (interface{}, error) {
+			return arguments, nil
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[]", []interface{}{}},
+		{"{}", map[string]interface{}{}},
+		{"[1, 'two', true]", []interface{}{1.0, "two", true}},
+		{"[foo,]", []interface{}{5.0}},
+		{"[1, 2,]", []interface{}{1.0, 2.0}},
+		{"[[1, 2], [3,]]", []interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0}}},
+		{"[[1, 2]]", []interface{}{[]interface{}{1.0, 2.0}}},
+		{"[foo * 2, foo > 1 ? 'big' : 'small']", []interface{}{10.0, "big"}},
+		{"{'a': 1, 'b': {'c': [foo,]}}", map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": []interface{}{5.0}}}},
+		{"args([1, 2])", []interface{}{[]interface{}{1.0, 2.0}}},
+		{"args([1,], [2,], 3)", []interface{}{[]interface{}{1.0}, []interface{}{2.0}, 3.0}},
+		{"args({'a': 1})", []interface{}{map[string]interface{}{"a": 1.0}}},
+		{"args(list)", []interface{}{[]interface{}{1.0, 2.0}}},
+		{"args(1, 2)", []interface{}{1.0, 2.0}},
+	}
+
+	parameters := map[string]interface{}{
+		"foo":  5,
+		"list": []interface{}{1.0, 2.0},
+	}
+
+	for _, testCase := range cases {
+
+		expression, err := NewExpressionWithFunctions(testCase.input, functions)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", testCase.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, testCase.expected) {
+			test.Errorf("Expected '%s' to evaluate to %#v, got %#v", testCase.input, testCase.expected, result)
+		}
+	}
+}
+
+// Tests that collection functions evaluate their predicates against each element,
+// using either the "#" placeholder or a named lambda parameter.
+func TestCollectionFunctions(test *testing.T) {
+
+	type lineItem struct {
+		Name  string
+		Price float64
+	}
+
+	cases := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"any(items, #.Price > 100)", true},
+		{"any(items, # .Price > 1000)", false},
+		{"any([], true)", false},
+		{"all(nums, # > 0)", true},
+		{"all(nums, # > 1)", false},
+		{"all([], false)", true},
+		{"none(nums, # > 3)", true},
+		{"none(nums, # == 2)", false},
+		{"filter(nums, # >= 2)", []interface{}{2.0, 3.0}},
+		{"filter(nums, false)", []interface{}{}},
+		{"map(nums, # * 2)", []interface{}{2.0, 4.0, 6.0}},
+		{"map(items, #.Name)", []interface{}{"pen", "desk"}},
+		{"count(nums)", 3.0},
+		{"count(nums, # != 2)", 2.0},
+		{"sum(nums)", 6.0},
+		{"sum(items, #.Price)", 252.5},
+		{"sum(map(nums, # * limit))", 12.0},
+		{"any(nums, n => n == limit)", true},
+		{"filter(items, item => item.Price > limit)", []interface{}{lineItem{"pen", 2.5}, lineItem{"desk", 250}}},
+		{"any(groups, group => all(group, # > 0))", true},
+		{"count(groups, g => any(g, x => x in g && x > 4))", 1.0},
+		{"count > 1 && any(nums, # == count)", true},
+	}
+
+	parameters := map[string]interface{}{
+		"items":  []lineItem{{"pen", 2.5}, {"desk", 250}},
+		"nums":   []int{1, 2, 3},
+		"groups": [][]int{{1, 5}, {-1, 2}},
+		"limit":  2,
+		"count":  3,
+	}
+
+	for _, testCase := range cases {
+
+		expression, err := NewExpression(testCase.input)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", testCase.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, testCase.expected) {
+			test.Errorf("Expected '%s' to evaluate to %#v, got %#v", testCase.input, testCase.expected, result)
+		}
+	}
+}
+
+// Tests that boolean collection functions stop evaluating their predicate as soon as the result is known.
+func TestCollectionFunctionShortCircuit(test *testing.T) {
+
+	var calls int
+	functions := map[string]ExpressionFunction{
+		"track": func(arguments ...interface{}) (interface{}, error) {
+			calls++
+			return arguments[0], nil
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected int
+	}{
+		{"any(flags, track(#))", 2},
+		{"all(flags, track(#))", 1},
+		{"none(flags, track(#))", 2},
+		{"count(flags, track(#))", 3},
+	}
+
+	parameters := map[string]interface{}{
+		"flags": []bool{false, true, false},
+	}
+
+	for _, testCase := range cases {
+
+		expression, err := NewExpressionWithFunctions(testCase.input, functions)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.input, err)
+			continue
+		}
+
+		calls = 0
+		_, err = expression.Evaluate(parameters)
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", testCase.input, err)
+			continue
+		}
+
+		if calls != testCase.expected {
+			test.Errorf("Expected '%s' to evaluate its predicate %d times, got %d", testCase.input, testCase.expected, calls)
+		}
+	}
+}
+
+// Tests that a user-defined function takes precedence over a built-in collection function of the same name.
+func TestCollectionFunctionOverride(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"sum": func(arguments ...interface{}) (interface{}, error) {
+			return "user", nil
+		},
+	}
+
+	expression, err := NewExpressionWithFunctions("sum(nums)", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"nums": []int{1}})
+	if err != nil {
+		test.Fatalf("Unable to evaluate: %v", err)
+	}
+
+	if result != "user" {
+		test.Errorf("Expected user-defined 'sum' to be called, got %v", result)
+	}
+}
@@ -1,8 +1,11 @@
 package govaluate
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"time"
 )
 
 const (
@@ -29,6 +32,16 @@ type Expression struct {
 	tokens           []ExpressionToken
 	evaluationStages *evaluationStage
 	inputExpression  string
+	options          ExpressionOptions
+
+	// the parameters given to Bind which were kept by the expression, since they couldn't be written as literals.
+	boundParameters map[string]interface{}
+
+	// counts how each stage is evaluated, if not nil; see CollectCoverage.
+	coverage *Coverage
+
+	// records how long each stage takes to evaluate, if not nil; see CollectProfile.
+	profile *expressionProfile
 }
 
 // NewExpression Parses a new Expression from the given [expression] string.
@@ -57,12 +70,12 @@ func NewExpressionFromTokens(tokens []ExpressionToken) (*Expression, error) {
 		return nil, err
 	}
 
-	ret.tokens, err = optimizeTokens(tokens)
+	ret.tokens, err = optimizeTokens(tokens, nil, Limits{})
 	if err != nil {
 		return nil, err
 	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	ret.evaluationStages, err = planStages(ret.tokens, ret.options, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +87,42 @@ func NewExpressionFromTokens(tokens []ExpressionToken) (*Expression, error) {
 // NewExpressionWithFunctions is similar to [NewExpression], except enables the use of user-defined functions.
 // Functions passed into this will be available to the expression.
 func NewExpressionWithFunctions(expression string, functions map[string]ExpressionFunction) (*Expression, error) {
+	return NewExpressionWithOptions(expression, ExpressionOptions{Functions: functions})
+}
+
+// NewExpressionWithOptions is similar to [NewExpression], except that parsing (and later evaluation) is configured by the given [options].
+func NewExpressionWithOptions(expression string, options ExpressionOptions) (*Expression, error) {
 	var ret *Expression
+	var functionNames map[int]string
+	var spans []tokenSpan
 	var err error
 
 	ret = new(Expression)
 	ret.QueryDateFormat = isoDateFormat
 	ret.inputExpression = expression
+	ret.options = options
+
+	functions, signatures, err := options.functions()
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkLimit("MaxInputBytes", options.Limits.MaxInputBytes, len(expression))
+	if err != nil {
+		return nil, err
+	}
 
-	ret.tokens, err = parseTokens(expression, functions)
+	ret.tokens, functionNames, spans, err = parseTokens(expression, functions, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkOperatorFamilies(expression, ret.tokens, spans, options.DisabledOperators)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkLimit("MaxTokens", options.Limits.MaxTokens, len(ret.tokens))
 	if err != nil {
 		return nil, err
 	}
@@ -96,12 +137,22 @@ func NewExpressionWithFunctions(expression string, functions map[string]Expressi
 		return nil, err
 	}
 
-	ret.tokens, err = optimizeTokens(ret.tokens)
+	ret.tokens, err = optimizeTokens(ret.tokens, options.RegexCache, options.Limits)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	err = checkLimit("MaxDepth", options.Limits.MaxDepth, tokenNestingDepth(ret.tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	ret.evaluationStages, err = planStages(ret.tokens, ret.options, functionNames, functionCalls(functionNames, signatures))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkLimit("MaxDepth", options.Limits.MaxDepth, stageDepth(ret.evaluationStages))
 	if err != nil {
 		return nil, err
 	}
@@ -133,8 +184,17 @@ func (expr Expression) Eval(parameters Parameters) (interface{}, error) {
 		return nil, nil
 	}
 
+	parameters = expr.withBoundParameters(parameters)
+
+	var state *evaluationState
+	if expr.options.Limits.appliesToEvaluation() || expr.coverage != nil || expr.profile != nil {
+		state = &evaluationState{limits: expr.options.Limits, coverage: expr.coverage, profile: expr.profile}
+	}
+
 	if parameters != nil {
-		parameters = &sanitizedParameters{parameters}
+		parameters = &sanitizedParameters{orig: parameters, evaluation: state}
+	} else if state != nil {
+		parameters = &sanitizedParameters{orig: MapParameters(map[string]interface{}{}), evaluation: state}
 	} else {
 		parameters = MapParameters(map[string]interface{}{})
 	}
@@ -142,35 +202,119 @@ func (expr Expression) Eval(parameters Parameters) (interface{}, error) {
 	return expr.evaluateStage(expr.evaluationStages, parameters)
 }
 
+// EvalContext is like Eval, except that evaluation stops as soon as [ctx] is done, returning ctx.Err().
+// The context is checked between each stage of evaluation, and given to any functions registered in [ExpressionOptions].ContextFunctions,
+// as well as to [parameters] if they implement ParametersContext.
+// A function or parameter lookup which doesn't use the context can't be interrupted, but its result is discarded if the context is done by then.
+func (expr Expression) EvalContext(ctx context.Context, parameters Parameters) (interface{}, error) {
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.evaluationStages == nil {
+		return nil, nil
+	}
+
+	if parameters == nil {
+		parameters = MapParameters(map[string]interface{}{})
+	}
+	parameters = expr.withBoundParameters(parameters)
+
+	state := &evaluationState{ctx: ctx, limits: expr.options.Limits, coverage: expr.coverage, profile: expr.profile}
+
+	result, err := expr.evaluateStage(expr.evaluationStages, &sanitizedParameters{orig: parameters, evaluation: state})
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (expr Expression) evaluateStage(stage *evaluationStage, parameters Parameters) (interface{}, error) {
 
+	state := stateOf(parameters)
+	if state == nil || (state.tracer == nil && state.coverage == nil && state.profile == nil) {
+		return expr.evaluateStageWith(stage, parameters, state)
+	}
+
+	var ret interface{}
+	var err error
+	var started time.Time
+
+	if state.profile != nil {
+		started = time.Now()
+	}
+
+	if state.tracer != nil {
+		ret, err = state.tracer.trace(expr, stage, func() (interface{}, error) {
+			return expr.evaluateStageWith(stage, parameters, state)
+		})
+	} else {
+		ret, err = expr.evaluateStageWith(stage, parameters, state)
+	}
+
+	if state.profile != nil {
+		state.profile.record(stage, time.Since(started))
+	}
+
+	if state.coverage != nil {
+		state.coverage.recordEvaluation(stage, ret)
+	}
+	return ret, err
+}
+
+// Evaluates [stage], given the [state] of the evaluation it's part of (which may be nil).
+func (expr Expression) evaluateStageWith(stage *evaluationStage, parameters Parameters, state *evaluationState) (interface{}, error) {
+
 	var left, right interface{}
 	var err error
 
+	if state != nil {
+		err = state.step()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if stage.leftStage != nil {
 		left, err = expr.evaluateStage(stage.leftStage, parameters)
 		if err != nil {
 			return nil, err
 		}
+
+		if state != nil && state.tracer != nil {
+			state.tracer.recordInput(left)
+		}
+	}
+
+	// collection functions evaluate their right stage once per element, rather than once here.
+	if stage.collection != nil {
+		return expr.evaluateCollectionStage(stage, left, parameters)
 	}
 
 	if stage.isShortCircuitable() {
 		switch stage.symbol {
 		case and:
 			if left == false {
-				return false, nil
+				return expr.shortCircuit(stage, false, state)
 			}
 		case or:
 			if left == true {
-				return true, nil
+				return expr.shortCircuit(stage, true, state)
 			}
 		case coalesce:
 			if left != nil {
-				return left, nil
+				return expr.shortCircuit(stage, left, state)
 			}
 
 		case ternaryTrue:
-			if left == false {
+			if left == false || (expr.options.ThreeValuedLogic && left == nil) {
 				right = shortCircuitHolder
 			}
 		case ternaryFalse:
@@ -178,6 +322,10 @@ func (expr Expression) evaluateStage(stage *evaluationStage, parameters Paramete
 				right = shortCircuitHolder
 			}
 		}
+
+		if right == shortCircuitHolder && state != nil && state.coverage != nil {
+			state.coverage.recordSkip(stage.rightStage)
+		}
 	}
 
 	if right != shortCircuitHolder && stage.rightStage != nil {
@@ -185,6 +333,17 @@ func (expr Expression) evaluateStage(stage *evaluationStage, parameters Paramete
 		if err != nil {
 			return nil, err
 		}
+
+		if state != nil && state.tracer != nil {
+			state.tracer.recordInput(right)
+		}
+	}
+
+	if expr.options.ThreeValuedLogic {
+		result, handled := evaluateThreeValued(stage, left, right)
+		if handled {
+			return result, nil
+		}
 	}
 
 	if expr.ChecksTypes {
@@ -208,7 +367,59 @@ func (expr Expression) evaluateStage(stage *evaluationStage, parameters Paramete
 		}
 	}
 
-	return stage.operator(left, right, parameters)
+	ret, err := stage.operator(left, right, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// only concatenation and functions build new strings; others just pass along strings from parameters or literals.
+	if state != nil && (stage.symbol == plus || stage.symbol == functional) {
+
+		str, isString := ret.(string)
+		if isString {
+			err = checkLimit("MaxStringLength", state.limits.MaxStringLength, len(str))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Returns [result] as the result of [stage], without evaluating its right stage.
+func (expr Expression) shortCircuit(stage *evaluationStage, result interface{}, state *evaluationState) (interface{}, error) {
+
+	if state != nil && state.coverage != nil {
+		state.coverage.recordSkip(stage.rightStage)
+	}
+	return result, nil
+}
+
+// Evaluates a collection function over the collection [left], evaluating the predicate (right stage) lazily for each element.
+func (expr Expression) evaluateCollectionStage(stage *evaluationStage, left interface{}, parameters Parameters) (interface{}, error) {
+
+	var predicate collectionPredicate
+
+	if !isArray(left) {
+		errorMsg := fmt.Sprintf(stage.typeErrorFormat, left, stage.symbol.String())
+		return nil, errors.New(errorMsg)
+	}
+
+	call := stage.collection
+
+	if stage.rightStage != nil {
+		predicate = func(element interface{}) (interface{}, error) {
+
+			scope := lambdaParameters{
+				parent: parameters,
+				name:   call.parameterName,
+				value:  element,
+			}
+			return expr.evaluateStage(stage.rightStage, scope)
+		}
+	}
+
+	return call.definition.evaluate(call.name, reflect.ValueOf(left), predicate)
 }
 
 func typeCheck(check stageTypeCheck, value interface{}, symbol OperatorSymbol, format string) error {
@@ -236,10 +447,26 @@ func (expr Expression) String() string {
 }
 
 // Vars returns an array representing the variables contained in this Expression.
+// Names which refer to the elements of a collection function (such as "#", or "x" in "x => x > 1") are not included,
+// and neither are parameters which were kept by [Expression.Bind].
 func (expr Expression) Vars() []string {
 	var varlist []string
-	for _, val := range expr.Tokens() {
-		if val.Kind == variable {
+
+	tokens := expr.Tokens()
+	lambdaNames := map[string]bool{placeholderParameter: true}
+
+	for name := range expr.boundParameters {
+		lambdaNames[name] = true
+	}
+
+	for i, val := range tokens {
+		if val.Kind == variable && i+1 < len(tokens) && tokens[i+1].Kind == lambda {
+			lambdaNames[val.Value.(string)] = true
+		}
+	}
+
+	for _, val := range tokens {
+		if val.Kind == variable && !lambdaNames[val.Value.(string)] {
 			varlist = append(varlist, val.Value.(string))
 		}
 	}
@@ -1,6 +1,15 @@
 package govaluate
 
+import (
+	"context"
+)
+
 // ExpressionFunction represents a function that can be called from within an expression.
 // This method must return an error if, for any reason, it is unable to produce exactly one unambiguous result.
 // An error returned will halt execution of the expression.
 type ExpressionFunction func(arguments ...interface{}) (interface{}, error)
+
+// ExpressionFunctionContext is like ExpressionFunction, except that it is given the context of the evaluation which called it.
+// When an expression is evaluated with [Expression.EvalContext], this is the context given there; otherwise it's context.Background().
+// Functions which may take a long time should stop and return an error when the context is done.
+type ExpressionFunctionContext func(ctx context.Context, arguments ...interface{}) (interface{}, error)
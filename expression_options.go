@@ -0,0 +1,174 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const defaultAccessorTag string = "json"
+
+// ExpressionOptions holds the settings used when parsing an Expression with [NewExpressionWithOptions].
+// The zero value is valid, and behaves the same as [NewExpression].
+type ExpressionOptions struct {
+
+	// Functions are the user-defined functions available to the expression, as with [NewExpressionWithFunctions].
+	Functions map[string]ExpressionFunction
+
+	// TypedFunctions are user-defined functions given as ordinary Go funcs, such as `func(s string, n int) (bool, error)`,
+	// which are adapted to be called from the expression (see [NewTypedFunction]).
+	// Unlike Functions, calls to these with the wrong number of arguments are caught when the expression is parsed.
+	// Each argument is converted to the type the func expects when called, and the call fails if that isn't possible.
+	TypedFunctions map[string]interface{}
+
+	// FunctionDescriptors are user-defined functions given along with what's known about them ahead of time,
+	// such as how many arguments they take and whether or not they're pure (see [FunctionDescriptor]).
+	FunctionDescriptors map[string]FunctionDescriptor
+
+	// ContextFunctions are user-defined functions which are given the context of the evaluation that calls them (see [Expression.EvalContext]).
+	ContextFunctions map[string]ExpressionFunctionContext
+
+	// AccessorTag is the struct tag consulted when resolving accessor fields, such as "foo.created_at".
+	// A field whose tag gives it a name is accessible by that name, a field tagged "-" is hidden,
+	// and untagged fields are accessible by their Go name.
+	// Defaults to "json".
+	AccessorTag string
+
+	// AccessPolicy restricts which fields and methods accessors may reach, such as "foo.Bar" (see [AccessPolicy]).
+	// By default, accessors may reach any exported field or method.
+	AccessPolicy AccessPolicy
+
+	// ParameterTypes gives the Go types of parameters, by name, if they're known ahead of time.
+	// Accessors which start from these parameters are checked against the AccessPolicy when the expression is parsed.
+	ParameterTypes map[string]reflect.Type
+
+	// DisabledOperators are the families of operators which the expression may not use, such as `RegexOperators | BitwiseOperators`.
+	// Using any of them fails to parse with a *DisabledOperatorError. By default, every family is enabled.
+	DisabledOperators OperatorFamily
+
+	// RegexCache holds the compiled regex patterns used by the expression. Defaults to DefaultRegexCache, which is shared by all expressions.
+	RegexCache *RegexCache
+
+	// Limits bounds the resources the expression may use when parsed and evaluated, for expressions from untrusted sources.
+	// By default, there are no limits.
+	Limits Limits
+
+	// WordOperators enables SQL-style keywords, matched in any case: "and", "or", "xor", and "not" as logical operators
+	// (where, as in SQL, "not" binds more loosely than comparators), "x between a and b" (and "not between"), "true" and "false",
+	// and "null" (a literal which anything can be compared with, so "x == null" is true only if "x" is nil). It also makes "in" (and "like", "ilike", and "glob", if enabled) match in any case.
+	// These words can then only be used as parameter names in brackets, as in "[and]".
+	WordOperators bool
+
+	// PatternOperators enables the pattern matching comparators "like", "ilike", and "glob" (and "not like", and so on), as simpler alternatives to regex.
+	// Like "in", they're matched in all lower or all upper case, and can then only be used as parameter names in brackets, as in "[like]".
+	PatternOperators bool
+
+	// ThreeValuedLogic makes null behave as it does in SQL: comparisons and arithmetic with null give null, "&&" and "||" follow SQL's
+	// truth tables (so "null && false" is false, and "null || true" is true), and a null condition of a ternary counts as false.
+	// "is null" and "is not null" are unaffected. By default, null is a value like any other.
+	// It also makes "null" (and "NULL") the literal, which can then only be used as a parameter name in brackets, as in "[null]".
+	// Without this or WordOperators, "null" is a parameter name like any other, so "x == null" fails unless a parameter named
+	// "null" is given; use "x is null" instead, which works either way.
+	ThreeValuedLogic bool
+}
+
+// accessorTag returns the struct tag that accessors should use, applying the default if none was given.
+func (options ExpressionOptions) accessorTag() string {
+
+	if options.AccessorTag == "" {
+		return defaultAccessorTag
+	}
+	return options.AccessorTag
+}
+
+// hasNullKeyword returns true if "null" is a literal, rather than a parameter name; which it is with three-valued logic, or word operators.
+func (options ExpressionOptions) hasNullKeyword() bool {
+	return options.ThreeValuedLogic || options.WordOperators
+}
+
+// hasFunction returns true if a user-defined function of the given name is available to the expression.
+func (options ExpressionOptions) hasFunction(name string) bool {
+
+	_, found := options.Functions[name]
+	if !found {
+		_, found = options.TypedFunctions[name]
+	}
+	if !found {
+		_, found = options.FunctionDescriptors[name]
+	}
+	if !found {
+		_, found = options.ContextFunctions[name]
+	}
+	return found
+}
+
+// functions returns every user-defined function available to the expression, including adapted TypedFunctions, FunctionDescriptors, and ContextFunctions,
+// along with the signatures of those functions which are known ahead of time.
+// A name can only be used once among Functions, TypedFunctions, FunctionDescriptors, and ContextFunctions.
+func (options ExpressionOptions) functions() (map[string]ExpressionFunction, map[string]functionSignature, error) {
+
+	functions := make(map[string]ExpressionFunction)
+	signatures := make(map[string]functionSignature)
+
+	for name, function := range options.Functions {
+		functions[name] = function
+	}
+
+	for name, function := range options.TypedFunctions {
+
+		if _, found := functions[name]; found {
+			return nil, nil, duplicateFunctionError(name)
+		}
+
+		typed, err := newTypedFunction(name, function)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		functions[name] = typed.call
+		signatures[name] = typed.signature
+	}
+
+	for name, descriptor := range options.FunctionDescriptors {
+
+		if _, found := functions[name]; found {
+			return nil, nil, duplicateFunctionError(name)
+		}
+
+		signature, err := newDescribedSignature(name, descriptor)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		functions[name] = descriptor.Function
+		signatures[name] = signature
+	}
+
+	for name, function := range options.ContextFunctions {
+
+		if _, found := functions[name]; found {
+			return nil, nil, duplicateFunctionError(name)
+		}
+
+		if function == nil {
+			errorMsg := fmt.Sprintf("Context function '%s' is nil", name)
+			return nil, nil, errors.New(errorMsg)
+		}
+
+		functions[name] = withBackgroundContext(function)
+		signatures[name] = functionSignature{
+			name:            name,
+			minArguments:    0,
+			maxArguments:    UnlimitedArguments,
+			cost:            1,
+			contextFunction: function,
+		}
+	}
+
+	return functions, signatures, nil
+}
+
+func duplicateFunctionError(name string) error {
+	errorMsg := fmt.Sprintf("Function '%s' is defined more than once among Functions, TypedFunctions, FunctionDescriptors, and ContextFunctions", name)
+	return errors.New(errorMsg)
+}
@@ -60,8 +60,13 @@ func (expr Expression) findNextSQLString(stream *tokenStream, transactions *expr
 			ret = "AND"
 		case or:
 			ret = "OR"
+		case xor:
+			ret = "XOR"
 		}
 
+	case nullToken:
+		ret = "NULL"
+
 	case boolean:
 		if token.Value.(bool) {
 			ret = "1"
@@ -76,7 +81,13 @@ func (expr Expression) findNextSQLString(stream *tokenStream, transactions *expr
 		ret = fmt.Sprintf("%g", token.Value.(float64))
 
 	case comparator:
-		switch comparatorSymbols[token.Value.(string)] {
+
+		symbol, found := comparatorSymbols[token.Value.(string)]
+		if !found {
+			symbol = betweenSymbols[token.Value.(string)]
+		}
+
+		switch symbol {
 
 		case eq:
 			ret = "="
@@ -86,10 +97,35 @@ func (expr Expression) findNextSQLString(stream *tokenStream, transactions *expr
 			ret = "RLIKE"
 		case nreq:
 			ret = "NOT RLIKE"
+		case notIn:
+			ret = "NOT IN"
+		case like:
+			ret = "LIKE"
+		case notLike:
+			ret = "NOT LIKE"
+		case ilike:
+			ret = "ILIKE"
+		case notIlike:
+			ret = "NOT ILIKE"
+		case glob:
+			ret = "GLOB"
+		case notGlob:
+			ret = "NOT GLOB"
+		case is:
+			ret = "IS"
+		case isNot:
+			ret = "IS NOT"
+		case between:
+			ret = "BETWEEN"
+		case notBetween:
+			ret = "NOT BETWEEN"
 		default:
 			ret = fmt.Sprintf("%s", token.Value.(string))
 		}
 
+	case boundsSeparator:
+		ret = "AND"
+
 	case ternary:
 
 		switch ternarySymbols[token.Value.(string)] {
@@ -109,6 +145,11 @@ func (expr Expression) findNextSQLString(stream *tokenStream, transactions *expr
 			return "", errors.New("Ternary operators are unsupported in SQL output")
 		}
 	case prefix:
+		if token.Value == "not" {
+			ret = "NOT"
+			break
+		}
+
 		switch prefixSymbols[token.Value.(string)] {
 
 		case invert:
@@ -151,6 +192,22 @@ func (expr Expression) findNextSQLString(stream *tokenStream, transactions *expr
 		ret = "("
 	case clauseClose:
 		ret = ")"
+	case arrayClause:
+		ret = "("
+	case arrayClauseClose:
+		// SQL lists can't have a trailing comma, as array literals can (or must, if they have one element).
+		if stream.index >= 2 && stream.tokens[stream.index-2].Kind == separator {
+			transactions.rollback()
+		}
+		ret = ")"
+	case mapClause:
+		fallthrough
+	case mapClauseClose:
+		return "", errors.New("Map literals are unsupported in SQL output")
+	case collectionFunction:
+		fallthrough
+	case lambda:
+		return "", errors.New("Collection functions are unsupported in SQL output")
 	case separator:
 		ret = ","
 
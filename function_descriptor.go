@@ -0,0 +1,175 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// UnlimitedArguments is used as a [FunctionDescriptor]'s MaxArguments, for functions which accept any number of arguments.
+const UnlimitedArguments int = -1
+
+// ValueType describes the type of a value in an expression, such as the value that a function returns.
+type ValueType int
+
+const (
+	// AnyType means that the type is not known ahead of time.
+	AnyType ValueType = iota
+	NumberType
+	StringType
+	BoolType
+	ArrayType
+	MapType
+)
+
+func (valueType ValueType) String() string {
+
+	switch valueType {
+	case NumberType:
+		return "number"
+	case StringType:
+		return "string"
+	case BoolType:
+		return "bool"
+	case ArrayType:
+		return "array"
+	case MapType:
+		return "map"
+	}
+	return "any"
+}
+
+// FunctionDescriptor describes a function, along with what the planner may assume about it.
+// Descriptors are given to [ExpressionOptions].FunctionDescriptors, keyed by the function's name.
+type FunctionDescriptor struct {
+
+	// Function is the function being described.
+	Function ExpressionFunction
+
+	// MinArguments and MaxArguments are the fewest and most arguments the function accepts.
+	// Every call is checked against them when the expression is parsed.
+	// Use UnlimitedArguments as the MaxArguments of functions which accept any number of arguments.
+	// Note that the zero value of both describes a function which takes no arguments.
+	MinArguments int
+	MaxArguments int
+
+	// Pure means that the function always returns the same result for the same arguments, and has no side effects.
+	// Calls to pure functions whose arguments are all constant (like "lower('ABC')") are evaluated once, when the expression is parsed.
+	// If such a call fails, or returns an array or map, it is left to be evaluated as usual.
+	Pure bool
+
+	// Cost is a hint of how expensive the function is to call, relative to an operator. Zero is treated as 1.
+	Cost int
+
+	// ReturnType is the type of value the function returns, if known. Values of other types are still allowed to be returned,
+	// but the result of a call is checked against the operator it's used with when the expression is parsed;
+	// so "lower(name) - 1" fails to parse if "lower" is described as returning a string.
+	ReturnType ValueType
+}
+
+// functionSignature describes what is known about a function ahead of time, from a descriptor or the Go signature of a typed function.
+type functionSignature struct {
+	name string
+
+	// the fewest and most arguments the function accepts. A [maxArguments] of -1 means there is no maximum.
+	minArguments int
+	maxArguments int
+
+	pure       bool
+	cost       int
+	returnType ValueType
+
+	// set for functions which are given the context of the evaluation that calls them.
+	contextFunction ExpressionFunctionContext
+}
+
+func newDescribedSignature(name string, descriptor FunctionDescriptor) (functionSignature, error) {
+
+	if descriptor.Function == nil {
+		errorMsg := fmt.Sprintf("Descriptor of function '%s' has no Function", name)
+		return functionSignature{}, errors.New(errorMsg)
+	}
+
+	if descriptor.MinArguments < 0 ||
+		descriptor.MaxArguments < UnlimitedArguments ||
+		(descriptor.MaxArguments != UnlimitedArguments && descriptor.MaxArguments < descriptor.MinArguments) {
+
+		errorMsg := fmt.Sprintf("Descriptor of function '%s' has an invalid number of arguments (%d to %d)", name, descriptor.MinArguments, descriptor.MaxArguments)
+		return functionSignature{}, errors.New(errorMsg)
+	}
+
+	cost := descriptor.Cost
+	if cost <= 0 {
+		cost = 1
+	}
+
+	return functionSignature{
+		name:         name,
+		minArguments: descriptor.MinArguments,
+		maxArguments: descriptor.MaxArguments,
+		pure:         descriptor.Pure,
+		cost:         cost,
+		returnType:   descriptor.ReturnType,
+	}, nil
+}
+
+// functionCalls returns the signature of each function call whose signature is known, keyed by the index of the call's token.
+func functionCalls(functionNames map[int]string, signatures map[string]functionSignature) map[int]functionSignature {
+
+	ret := make(map[int]functionSignature)
+
+	for index, name := range functionNames {
+
+		signature, found := signatures[name]
+		if found {
+			ret[index] = signature
+		}
+	}
+	return ret
+}
+
+// Returns the ValueType that values of the given Go type will have within an expression.
+func valueTypeOf(goType reflect.Type) ValueType {
+
+	// operators only recognize unnamed numbers, strings, and bools as such; and only those numbers that castToFloat64 converts.
+	// So a plain uint, or a named string type, could be anything as far as the planner is concerned.
+	switch goType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if goType.PkgPath() == "" {
+			return NumberType
+		}
+	case reflect.String:
+		if goType.PkgPath() == "" {
+			return StringType
+		}
+	case reflect.Bool:
+		if goType.PkgPath() == "" {
+			return BoolType
+		}
+	case reflect.Slice, reflect.Array:
+		return ArrayType
+	case reflect.Map:
+		return MapType
+	}
+	return AnyType
+}
+
+// Returns an example value of the given type, which stands in for the result of a function when checking types ahead of time.
+func exampleValueOf(valueType ValueType) interface{} {
+
+	switch valueType {
+	case NumberType:
+		return 0.0
+	case StringType:
+		return ""
+	case BoolType:
+		return false
+	case ArrayType:
+		return []interface{}{}
+	case MapType:
+		return map[string]interface{}{}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package govaluate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Returns descriptors of functions which count how many times they're called, in [calls].
+func makeCountingDescriptors(calls *int) map[string]FunctionDescriptor {
+
+	upper := func(arguments ...interface{}) (interface{}, error) {
+		*calls++
+		if !isString(arguments[0]) {
+			return nil, errors.New("Not a string")
+		}
+		return strings.ToUpper(arguments[0].(string)), nil
+	}
+
+	return map[string]FunctionDescriptor{
+		"upper": {
+			Function:     upper,
+			MinArguments: 1,
+			MaxArguments: 1,
+			Pure:         true,
+			ReturnType:   StringType,
+		},
+		"impureUpper": {
+			Function:     upper,
+			MinArguments: 1,
+			MaxArguments: 1,
+			ReturnType:   StringType,
+		},
+		"concat": {
+			Function: func(arguments ...interface{}) (interface{}, error) {
+				*calls++
+				var ret string
+				for _, argument := range arguments {
+					ret += argument.(string)
+				}
+				return ret, nil
+			},
+			MaxArguments: UnlimitedArguments,
+			Pure:         true,
+		},
+		"words": {
+			Function: func(arguments ...interface{}) (interface{}, error) {
+				*calls++
+				return []interface{}{"a", "b"}, nil
+			},
+			Pure:       true,
+			ReturnType: ArrayType,
+		},
+	}
+}
+
+// Tests that calls to pure functions with constant arguments are evaluated while parsing, and only then.
+func TestPureFunctionFolding(test *testing.T) {
+
+	cases := []struct {
+		input         string
+		expected      interface{}
+		parsingCalls  int
+		evaluateCalls int
+	}{
+		{"upper('abc')", "ABC", 1, 0},
+		{"upper('a' + 'b') == 'AB'", true, 1, 0},
+		{"upper(upper('a'))", "A", 2, 0},
+		{"concat('a', 'b', upper('c'))", "abC", 2, 0},
+		{"concat() + 'x'", "x", 1, 0},
+		{"upper(name)", "FOO", 0, 1},
+		{"concat('a', name)", "afoo", 0, 1},
+		{"impureUpper('a')", "A", 0, 1},
+		{"words() == ['a', 'b']", true, 1, 1},
+		{"upper(1) ?? 'failed'", "", 1, 1},
+	}
+
+	for _, testCase := range cases {
+
+		var calls int
+		options := ExpressionOptions{FunctionDescriptors: makeCountingDescriptors(&calls)}
+
+		expression, err := NewExpressionWithOptions(testCase.input, options)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.input, err)
+			continue
+		}
+
+		if calls != testCase.parsingCalls {
+			test.Errorf("Expected '%s' to call functions %d times while parsing, got %d", testCase.input, testCase.parsingCalls, calls)
+		}
+
+		calls = 0
+		result, err := expression.Evaluate(map[string]interface{}{"name": "foo"})
+
+		if testCase.expected == "" {
+			if err == nil {
+				test.Errorf("Expected '%s' to fail when evaluated, got %v", testCase.input, result)
+			}
+		} else if err != nil || result != testCase.expected {
+			test.Errorf("Expected '%s' to evaluate to %v, got %v (%v)", testCase.input, testCase.expected, result, err)
+		}
+
+		if calls != testCase.evaluateCalls {
+			test.Errorf("Expected '%s' to call functions %d times when evaluated, got %d", testCase.input, testCase.evaluateCalls, calls)
+		}
+	}
+}
+
+// Tests that function arity and result types are checked while parsing.
+func TestFunctionDescriptorChecks(test *testing.T) {
+
+	var calls int
+
+	parsingTests := []ParsingFailureTest{
+		{
+			Name:     "Too few arguments",
+			Input:    "upper()",
+			Expected: "Function 'upper' expects 1 argument, got 0",
+		},
+		{
+			Name:     "Too many arguments",
+			Input:    "upper('a', 'b')",
+			Expected: "Function 'upper' expects 1 argument, got 2",
+		},
+		{
+			Name:     "No arguments expected",
+			Input:    "words(1)",
+			Expected: "Function 'words' expects 0 arguments, got 1",
+		},
+		{
+			Name:     "String result used arithmetically",
+			Input:    "upper(name) - 1",
+			Expected: "Function 'upper' returns a string, which cannot be used with '-'",
+		},
+		{
+			Name:     "Parenthesized result used arithmetically",
+			Input:    "1 * (upper(name))",
+			Expected: "Function 'upper' returns a string, which cannot be used with '*'",
+		},
+		{
+			Name:     "String result compared to number",
+			Input:    "upper(name) > 1",
+			Expected: "Function 'upper' returns a string, which cannot be used with '>'",
+		},
+		{
+			Name:     "String result used logically",
+			Input:    "impureUpper(name) && true",
+			Expected: "Function 'impureUpper' returns a string, which cannot be used with '&&'",
+		},
+		{
+			Name:     "String result negated",
+			Input:    "-upper(name)",
+			Expected: "Function 'upper' returns a string, which cannot be used with '-'",
+		},
+		{
+			Name:     "Typed function result",
+			Input:    "repeat('a', 2) - 1",
+			Expected: "Function 'repeat' returns a string, which cannot be used with '-'",
+		},
+	}
+
+	for _, testCase := range parsingTests {
+
+		options := ExpressionOptions{
+			FunctionDescriptors: makeCountingDescriptors(&calls),
+			TypedFunctions:      map[string]interface{}{"repeat": typedFunctions["repeat"]},
+		}
+		_, err := NewExpressionWithOptions(testCase.Input, options)
+
+		if err == nil || !strings.Contains(err.Error(), testCase.Expected) {
+			test.Errorf("Test '%s' failed: expected error '%s', got %v", testCase.Name, testCase.Expected, err)
+		}
+	}
+
+	// results which may be valid should still parse.
+	validInputs := []string{
+		"upper(name) + 1",
+		"upper(name) == 'FOO'",
+		"upper(name) > 'A'",
+		"upper(name) =~ 'F'",
+		"concat(name) - 1",
+		"upper(name) in ['FOO']",
+	}
+
+	for _, input := range validInputs {
+
+		_, err := NewExpressionWithOptions(input, ExpressionOptions{FunctionDescriptors: makeCountingDescriptors(&calls)})
+		if err != nil {
+			test.Errorf("Expected '%s' to parse, got %v", input, err)
+		}
+	}
+}
+
+func TestInvalidFunctionDescriptors(test *testing.T) {
+
+	function := func(arguments ...interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	cases := []struct {
+		name        string
+		descriptors map[string]FunctionDescriptor
+		expected    string
+	}{
+		{
+			name:        "No function",
+			descriptors: map[string]FunctionDescriptor{"foo": {MaxArguments: 1}},
+			expected:    "Descriptor of function 'foo' has no Function",
+		},
+		{
+			name:        "Maximum below minimum",
+			descriptors: map[string]FunctionDescriptor{"foo": {Function: function, MinArguments: 2, MaxArguments: 1}},
+			expected:    "Descriptor of function 'foo' has an invalid number of arguments (2 to 1)",
+		},
+		{
+			name:        "Negative minimum",
+			descriptors: map[string]FunctionDescriptor{"foo": {Function: function, MinArguments: -1}},
+			expected:    "has an invalid number of arguments",
+		},
+	}
+
+	for _, testCase := range cases {
+
+		_, err := NewExpressionWithOptions("1", ExpressionOptions{FunctionDescriptors: testCase.descriptors})
+
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			test.Errorf("Test '%s' failed: expected error '%s', got %v", testCase.name, testCase.expected, err)
+		}
+	}
+
+	_, err := NewExpressionWithOptions("1", ExpressionOptions{
+		Functions:           map[string]ExpressionFunction{"foo": function},
+		FunctionDescriptors: map[string]FunctionDescriptor{"foo": {Function: function}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Function 'foo' is defined more than once") {
+		test.Errorf("Expected duplicate function names to be rejected, got %v", err)
+	}
+}
+
+func TestStandardFunctionDescriptors(test *testing.T) {
+
+	options := ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()}
+
+	expression, err := NewExpressionWithOptions("lower('ABC') + round(1.25, 1)", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	if expression.evaluationStages.symbol != literal {
+		test.Errorf("Expected calls with constant arguments to be folded into a literal")
+	}
+
+	result, err := expression.Evaluate(nil)
+	if err != nil || result != "abc1.3" {
+		test.Errorf("Expected 'abc1.3', got %v (%v)", result, err)
+	}
+
+	expression, err = NewExpressionWithOptions("now() > 0", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	if expression.evaluationStages.symbol == literal {
+		test.Errorf("Expected 'now()' not to be folded")
+	}
+
+	parsingFailures := map[string]string{
+		"len(1, 2)":    "Function 'len' expects 1 argument, got 2",
+		"upper(x) * 2": "Function 'upper' returns a string, which cannot be used with '*'",
+		"contains(x)":  "Function 'contains' expects 2 arguments, got 1",
+	}
+
+	for input, expected := range parsingFailures {
+
+		_, err = NewExpressionWithOptions(input, options)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			test.Errorf("Expected '%s' to fail to parse with '%s', got %v", input, expected, err)
+		}
+	}
+}
@@ -23,13 +23,17 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
 			variable,
 			pattern,
 			function,
+			collectionFunction,
 			accessor,
 			stringToken,
 			timeToken,
 			clause,
+			arrayClause,
+			mapClause,
 		},
 	},
 	{
@@ -40,13 +44,17 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
 			variable,
 			pattern,
 			function,
+			collectionFunction,
 			accessor,
 			stringToken,
 			timeToken,
 			clause,
+			arrayClause,
+			mapClause,
 			clauseClose,
 		},
 	},
@@ -56,8 +64,11 @@ var validLexerStates = []lexerState{
 		isNullable: true,
 		validNextKinds: []TokenKind{
 			comparator,
+			boundsSeparator,
 			modifier,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			logicalop,
 			ternary,
 			separator,
@@ -70,8 +81,11 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			ternary,
 			separator,
 		},
@@ -83,8 +97,27 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
+			ternary,
+			separator,
+		},
+	},
+	{
+		kind:       nullToken,
+		isEOF:      true,
+		isNullable: true,
+		validNextKinds: []TokenKind{
+			modifier,
+			comparator,
+			boundsSeparator,
+			logicalop,
+			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			ternary,
 			separator,
 		},
@@ -96,8 +129,11 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			ternary,
 			separator,
 		},
@@ -109,8 +145,11 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			separator,
 		},
 	},
@@ -121,8 +160,11 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			separator,
 		},
 	},
@@ -131,10 +173,14 @@ var validLexerStates = []lexerState{
 		isEOF:      true,
 		isNullable: false,
 		validNextKinds: []TokenKind{
+			lambda,
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			ternary,
 			separator,
 		},
@@ -148,9 +194,11 @@ var validLexerStates = []lexerState{
 			numeric,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
 			stringToken,
 			boolean,
+			nullToken,
 			clause,
 		},
 	},
@@ -162,12 +210,16 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
 			stringToken,
 			timeToken,
 			clause,
+			arrayClause,
+			mapClause,
 			pattern,
 		},
 	},
@@ -179,12 +231,36 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
 			stringToken,
 			timeToken,
 			clause,
+			arrayClause,
+			mapClause,
+		},
+	},
+	{
+		kind:       boundsSeparator,
+		isEOF:      false,
+		isNullable: false,
+		validNextKinds: []TokenKind{
+			prefix,
+			numeric,
+			boolean,
+			nullToken,
+			variable,
+			function,
+			collectionFunction,
+			accessor,
+			stringToken,
+			timeToken,
+			clause,
+			arrayClause,
+			mapClause,
 		},
 	},
 	{
@@ -194,8 +270,10 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 			numeric,
 			boolean,
+			nullToken,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
 			clause,
 		},
@@ -208,12 +286,16 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
 			stringToken,
 			timeToken,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
 			clause,
+			arrayClause,
+			mapClause,
 			separator,
 		},
 	},
@@ -223,6 +305,33 @@ var validLexerStates = []lexerState{
 		isNullable:     false,
 		validNextKinds: []TokenKind{clause},
 	},
+	{
+		kind:           collectionFunction,
+		isEOF:          false,
+		isNullable:     false,
+		validNextKinds: []TokenKind{clause},
+	},
+	{
+		kind:       lambda,
+		isEOF:      false,
+		isNullable: false,
+		validNextKinds: []TokenKind{
+			prefix,
+			numeric,
+			boolean,
+			nullToken,
+			variable,
+			pattern,
+			function,
+			collectionFunction,
+			accessor,
+			stringToken,
+			timeToken,
+			clause,
+			arrayClause,
+			mapClause,
+		},
+	},
 	{
 		kind:       accessor,
 		isEOF:      true,
@@ -230,8 +339,11 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{clause,
 			modifier,
 			comparator,
+			boundsSeparator,
 			logicalop,
 			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
 			ternary,
 			separator,
 		},
@@ -244,12 +356,90 @@ var validLexerStates = []lexerState{
 			prefix,
 			numeric,
 			boolean,
+			nullToken,
+			stringToken,
+			timeToken,
+			variable,
+			function,
+			collectionFunction,
+			accessor,
+			clause,
+			arrayClause,
+			mapClause,
+			arrayClauseClose,
+			mapClauseClose,
+		},
+	},
+	{
+		kind:       arrayClause,
+		isEOF:      false,
+		isNullable: true,
+		validNextKinds: []TokenKind{
+			prefix,
+			numeric,
+			boolean,
+			nullToken,
+			variable,
+			pattern,
+			function,
+			collectionFunction,
+			accessor,
 			stringToken,
 			timeToken,
+			clause,
+			arrayClause,
+			arrayClauseClose,
+			mapClause,
+		},
+	},
+	{
+		kind:       arrayClauseClose,
+		isEOF:      true,
+		isNullable: true,
+		validNextKinds: []TokenKind{
+			comparator,
+			boundsSeparator,
+			modifier,
+			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
+			logicalop,
+			ternary,
+			separator,
+		},
+	},
+	{
+		kind:       mapClause,
+		isEOF:      false,
+		isNullable: true,
+		validNextKinds: []TokenKind{
+			prefix,
+			numeric,
+			boolean,
+			nullToken,
 			variable,
 			function,
+			collectionFunction,
 			accessor,
+			stringToken,
 			clause,
+			mapClauseClose,
+		},
+	},
+	{
+		kind:       mapClauseClose,
+		isEOF:      true,
+		isNullable: true,
+		validNextKinds: []TokenKind{
+			comparator,
+			boundsSeparator,
+			modifier,
+			clauseClose,
+			arrayClauseClose,
+			mapClauseClose,
+			logicalop,
+			ternary,
+			separator,
 		},
 	},
 }
@@ -0,0 +1,138 @@
+package govaluate
+
+import (
+	"fmt"
+	"regexp/syntax"
+)
+
+// Limits bounds the resources an expression may use, for expressions which come from untrusted sources.
+// Each limit applies only if it is positive; the zero value imposes no limits at all.
+// Exceeding any limit fails with a *LimitError.
+type Limits struct {
+
+	// MaxInputBytes is the longest (in bytes) that an expression's text may be.
+	MaxInputBytes int
+
+	// MaxTokens is the most tokens that an expression may consist of.
+	MaxTokens int
+
+	// MaxDepth is the deepest that an expression's stages may be nested, such as by parentheses, function calls, or a chain of operators.
+	// Nesting of parentheses, brackets, and braces is checked before the expression is planned, so overly nested expressions are rejected early.
+	MaxDepth int
+
+	// MaxOperations is the most stages that may be evaluated during a single evaluation of the expression,
+	// including each time the predicate of a collection function is evaluated.
+	MaxOperations int
+
+	// MaxStringLength is the longest (in bytes) that any string built by concatenation or returned by a function may be.
+	// Strings given as parameters are not checked, unless passed through a function.
+	MaxStringLength int
+
+	// MaxRegexSize is the most instructions that a regex pattern may compile to, whether it's a constant (checked when parsed)
+	// or comes from a parameter (checked when evaluated).
+	MaxRegexSize int
+
+	// MaxRegexLength is the longest (in bytes) that a regex pattern may be, whether it's a constant or comes from a parameter.
+	MaxRegexLength int
+}
+
+// LimitError is returned when an expression exceeds one of its [Limits], either when parsed or evaluated.
+type LimitError struct {
+
+	// Limit is the name of the field of Limits which was exceeded, such as "MaxTokens".
+	Limit string
+
+	// Max is the value of that limit.
+	Max int
+
+	// Actual is how much the expression used, or tried to use, when it exceeded the limit.
+	Actual int
+}
+
+func (err *LimitError) Error() string {
+
+	switch err.Limit {
+	case "MaxInputBytes":
+		return fmt.Sprintf("Expression is %d bytes long, which exceeds the limit of %d", err.Actual, err.Max)
+	case "MaxTokens":
+		return fmt.Sprintf("Expression has %d tokens, which exceeds the limit of %d", err.Actual, err.Max)
+	case "MaxDepth":
+		return fmt.Sprintf("Expression is nested %d levels deep, which exceeds the limit of %d", err.Actual, err.Max)
+	case "MaxOperations":
+		return fmt.Sprintf("Evaluation exceeded the limit of %d operations", err.Max)
+	case "MaxStringLength":
+		return fmt.Sprintf("String of %d bytes exceeds the limit of %d", err.Actual, err.Max)
+	case "MaxRegexSize":
+		return fmt.Sprintf("Regex pattern compiles to %d instructions, which exceeds the limit of %d", err.Actual, err.Max)
+	case "MaxRegexLength":
+		return fmt.Sprintf("Regex pattern is %d bytes long, which exceeds the limit of %d", err.Actual, err.Max)
+	}
+	return fmt.Sprintf("Expression exceeds %s (%d > %d)", err.Limit, err.Actual, err.Max)
+}
+
+// Returns an error if [actual] exceeds the [limit] (if positive) named [name].
+func checkLimit(name string, limit int, actual int) error {
+
+	if limit > 0 && actual > limit {
+		return &LimitError{Limit: name, Max: limit, Actual: actual}
+	}
+	return nil
+}
+
+// Returns true if any of the limits need to be checked while evaluating, rather than just while parsing.
+func (limits Limits) appliesToEvaluation() bool {
+	return limits.MaxOperations > 0 || limits.MaxStringLength > 0
+}
+
+// Returns how deeply parentheses, brackets, and braces are nested among the given tokens.
+// The planner recurses at least this deep, so this is checked before planning.
+func tokenNestingDepth(tokens []ExpressionToken) int {
+
+	var depth, ret int
+
+	for _, token := range tokens {
+
+		switch token.Kind {
+		case clause, arrayClause, mapClause:
+			depth++
+			if depth > ret {
+				ret = depth
+			}
+		case clauseClose, arrayClauseClose, mapClauseClose:
+			depth--
+		}
+	}
+	return ret
+}
+
+// Returns how deeply the given stages are nested.
+func stageDepth(stage *evaluationStage) int {
+
+	if stage == nil {
+		return 0
+	}
+
+	left := stageDepth(stage.leftStage)
+	right := stageDepth(stage.rightStage)
+
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// Returns the number of instructions that the given regex pattern compiles to.
+// Patterns which fail to compile at all count as empty, and are left for regexp.Compile to report.
+func regexProgramSize(pattern string) int {
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+
+	program, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return 0
+	}
+	return len(program.Inst)
+}
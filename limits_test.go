@@ -0,0 +1,177 @@
+package govaluate
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// Represents a test of an expression which should exceed one of its limits.
+// If [Parameters] is nil, the expression is expected to fail to parse; otherwise, to fail when evaluated with them.
+type LimitTest struct {
+	Name       string
+	Input      string
+	Limits     Limits
+	Parameters map[string]interface{}
+	Limit      string
+	Expected   string
+}
+
+func TestLimits(test *testing.T) {
+
+	numbers := make([]interface{}, 100)
+	for i := range numbers {
+		numbers[i] = float64(i)
+	}
+
+	limitTests := []LimitTest{
+		{
+			Name:     "Input too long",
+			Input:    "1 + 1",
+			Limits:   Limits{MaxInputBytes: 3},
+			Limit:    "MaxInputBytes",
+			Expected: "Expression is 5 bytes long, which exceeds the limit of 3",
+		},
+		{
+			Name:     "Too many tokens",
+			Input:    "1 + 2 + 3",
+			Limits:   Limits{MaxTokens: 3},
+			Limit:    "MaxTokens",
+			Expected: "Expression has 5 tokens, which exceeds the limit of 3",
+		},
+		{
+			Name:     "Nested parentheses",
+			Input:    "((((1))))",
+			Limits:   Limits{MaxDepth: 3},
+			Limit:    "MaxDepth",
+			Expected: "Expression is nested 4 levels deep, which exceeds the limit of 3",
+		},
+		{
+			Name:     "Deeply nested parentheses",
+			Input:    strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000),
+			Limits:   Limits{MaxDepth: 100},
+			Limit:    "MaxDepth",
+			Expected: "Expression is nested 100000 levels deep",
+		},
+		{
+			Name:     "Chain of operators",
+			Input:    "a + a + a + a + a",
+			Limits:   Limits{MaxDepth: 3},
+			Limit:    "MaxDepth",
+			Expected: "which exceeds the limit of 3",
+		},
+		{
+			Name:     "Long chain of operators",
+			Input:    strings.Repeat("a+", 3000000) + "a",
+			Limits:   Limits{MaxDepth: 50},
+			Limit:    "MaxDepth",
+			Expected: "Expression is nested 51 levels deep, which exceeds the limit of 50",
+		},
+
+		{
+			Name:       "Too many operations",
+			Input:      "all(numbers, # >= 0)",
+			Limits:     Limits{MaxOperations: 50},
+			Parameters: map[string]interface{}{"numbers": numbers},
+			Limit:      "MaxOperations",
+			Expected:   "Evaluation exceeded the limit of 50 operations",
+		},
+		{
+			Name:       "Concatenated string too long",
+			Input:      "name + name",
+			Limits:     Limits{MaxStringLength: 5},
+			Parameters: map[string]interface{}{"name": "abc"},
+			Limit:      "MaxStringLength",
+			Expected:   "String of 6 bytes exceeds the limit of 5",
+		},
+		{
+			Name:     "Constant regex too large",
+			Input:    "name =~ 'a{1000}'",
+			Limits:   Limits{MaxRegexSize: 100},
+			Limit:    "MaxRegexSize",
+			Expected: "which exceeds the limit of 100",
+		},
+		{
+			Name:       "Parameter regex too large",
+			Input:      "name =~ pattern",
+			Limits:     Limits{MaxRegexSize: 100},
+			Parameters: map[string]interface{}{"name": "abc", "pattern": "a{1000}"},
+			Limit:      "MaxRegexSize",
+			Expected:   "which exceeds the limit of 100",
+		},
+	}
+
+	for _, limitTest := range limitTests {
+
+		expression, err := NewExpressionWithOptions(limitTest.Input, ExpressionOptions{Limits: limitTest.Limits})
+		if limitTest.Parameters != nil {
+
+			if err != nil {
+				test.Errorf("Test '%s' failed: unable to parse: %v", limitTest.Name, err)
+				continue
+			}
+			_, err = expression.Evaluate(limitTest.Parameters)
+		}
+
+		limitError, ok := err.(*LimitError)
+		if !ok {
+			test.Errorf("Test '%s' failed: expected a *LimitError, got %v", limitTest.Name, err)
+			continue
+		}
+
+		if limitError.Limit != limitTest.Limit || !strings.Contains(err.Error(), limitTest.Expected) {
+			test.Errorf("Test '%s' failed: expected %s to be exceeded with '%s', got %s: %v", limitTest.Name, limitTest.Limit, limitTest.Expected, limitError.Limit, err)
+		}
+	}
+}
+
+// Tests that expressions within their limits behave as usual.
+func TestWithinLimits(test *testing.T) {
+
+	limits := Limits{
+		MaxInputBytes:   100,
+		MaxTokens:       20,
+		MaxDepth:        10,
+		MaxOperations:   20,
+		MaxStringLength: 10,
+		MaxRegexSize:    100,
+	}
+
+	parameters := map[string]interface{}{
+		"name":    "abc",
+		"long":    strings.Repeat("x", 100),
+		"pattern": "^a",
+	}
+
+	cases := map[string]interface{}{
+		"name + name":              "abcabc",
+		"(long ?? '') == long":     true,
+		"name =~ pattern":          true,
+		"any([1, 2, 3], # == 2)":   true,
+		"(1 + 2) * 3 > 4 && true":  true,
+		"name != 'x' ? long : 'y'": strings.Repeat("x", 100),
+	}
+
+	for input, expected := range cases {
+
+		expression, err := NewExpressionWithOptions(input, ExpressionOptions{Limits: limits})
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", input, err)
+			continue
+		}
+
+		// operations are counted per evaluation, so evaluating repeatedly shouldn't exceed them.
+		for i := 0; i < 3; i++ {
+
+			result, err := expression.Evaluate(parameters)
+			if err != nil || result != expected {
+				test.Errorf("Expected '%s' to evaluate to %v, got %v (%v)", input, expected, result, err)
+			}
+		}
+
+		result, err := expression.EvalContext(context.Background(), MapParameters(parameters))
+		if err != nil || result != expected {
+			test.Errorf("Expected '%s' to evaluate to %v with a context, got %v (%v)", input, expected, result, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package govaluate
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Translates the 'like' [pattern] into an equivalent Go regex, which must match the whole of a string.
+// '%' matches any run of characters, '_' matches any single character, and a backslash makes the character after it literal.
+func translateLikePattern(pattern string, caseInsensitive bool) (string, error) {
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(?s")
+	if caseInsensitive {
+		buffer.WriteString("i")
+	}
+	buffer.WriteString(")^")
+
+	characters := []rune(pattern)
+
+	for i := 0; i < len(characters); i++ {
+
+		switch characters[i] {
+		case '%':
+			buffer.WriteString(".*")
+		case '_':
+			buffer.WriteString(".")
+		case '\\':
+			i++
+			if i >= len(characters) {
+				return "", fmt.Errorf("Like pattern '%s' ends with an unfinished escape", pattern)
+			}
+			buffer.WriteString(regexp.QuoteMeta(string(characters[i])))
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(characters[i])))
+		}
+	}
+
+	buffer.WriteString("$")
+	return buffer.String(), nil
+}
+
+// Translates the 'glob' [pattern] into an equivalent Go regex, which must match the whole of a string.
+// '*' matches any run of characters, '?' matches any single character, and '[...]' matches any one of the characters (or ranges, like 'a-z')
+// between the brackets, or any character but those if the first is '!' or '^'. A backslash makes the character after it literal.
+func translateGlobPattern(pattern string) (string, error) {
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(?s)^")
+
+	characters := []rune(pattern)
+
+	for i := 0; i < len(characters); i++ {
+
+		switch characters[i] {
+		case '*':
+			buffer.WriteString(".*")
+		case '?':
+			buffer.WriteString(".")
+		case '\\':
+			i++
+			if i >= len(characters) {
+				return "", fmt.Errorf("Glob pattern '%s' ends with an unfinished escape", pattern)
+			}
+			buffer.WriteString(regexp.QuoteMeta(string(characters[i])))
+		case '[':
+			end, class := translateGlobClass(characters, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("Glob pattern '%s' has an unclosed character class", pattern)
+			}
+			buffer.WriteString(class)
+			i = end
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(characters[i])))
+		}
+	}
+
+	buffer.WriteString("$")
+	return buffer.String(), nil
+}
+
+// Translates the glob character class which starts at [start] (just after its opening bracket) into a regex class.
+// Returns the index of the closing bracket, or -1 if there isn't one.
+// As in shells, a ']' which comes first in the class is one of its characters, rather than closing it.
+func translateGlobClass(characters []rune, start int) (int, string) {
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("[")
+
+	i := start
+	if i < len(characters) && (characters[i] == '!' || characters[i] == '^') {
+		buffer.WriteString("^")
+		i++
+	}
+
+	for first := i; i < len(characters); i++ {
+
+		character := characters[i]
+
+		if character == ']' && i > first {
+			buffer.WriteString("]")
+			return i, buffer.String()
+		}
+
+		if character == '\\' && i+1 < len(characters) {
+			i++
+			character = characters[i]
+		} else if character == '-' {
+			buffer.WriteRune(character)
+			continue
+		}
+
+		// within a class, only these have any special meaning to Go's regex syntax.
+		if strings.ContainsRune(`\[]^-`, character) {
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(character)
+	}
+	return -1, ""
+}
+
+// Returns the regex which is equivalent to the [pattern] of the given pattern matching comparator (such as 'like' or 'not glob').
+func translateMatchPattern(symbol OperatorSymbol, pattern string) (string, error) {
+
+	switch symbol {
+	case like, notLike:
+		return translateLikePattern(pattern, false)
+	case ilike, notIlike:
+		return translateLikePattern(pattern, true)
+	}
+	return translateGlobPattern(pattern)
+}
+
+// Returns true if the given symbol is one of the pattern matching comparators, 'like', 'ilike', or 'glob' (or their negations).
+func isMatchSymbol(symbol OperatorSymbol) bool {
+
+	switch symbol {
+	case like, notLike, ilike, notIlike, glob, notGlob:
+		return true
+	}
+	return false
+}
+
+// Translates and compiles the [pattern] of the given pattern matching comparator, through [cache] and within the regex [limits].
+func compileMatchPattern(symbol OperatorSymbol, pattern string, cache *RegexCache, limits Limits) (*regexp.Regexp, error) {
+
+	translated, err := translateMatchPattern(symbol, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, err := compileRegex(translated, cache, limits)
+	if err != nil {
+		if _, isLimit := err.(*LimitError); isLimit {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Unable to compile %s pattern '%v': %v", symbol.String(), pattern, err)
+	}
+	return regex, nil
+}
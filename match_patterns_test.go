@@ -0,0 +1,234 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchOperators(test *testing.T) {
+
+	evaluationTests := []EvaluationTest{
+		{
+			Name:     "Like with a trailing wildcard",
+			Input:    "'hello world' like 'hello%'",
+			Expected: true,
+		},
+		{
+			Name:     "Like is anchored",
+			Input:    "'say hello' like 'hello%'",
+			Expected: false,
+		},
+		{
+			Name:     "Like with a single character wildcard",
+			Input:    "'cat' like 'c_t' && 'cart' not like 'c_t'",
+			Expected: true,
+		},
+		{
+			Name:     "Like is case sensitive",
+			Input:    "'Hello' like 'hello'",
+			Expected: false,
+		},
+		{
+			Name:     "Like with escaped wildcards",
+			Input:    "'100%' like '100\\\\%' && '1000' not like '100\\\\%'",
+			Expected: true,
+		},
+		{
+			Name:     "Like treats regex syntax literally",
+			Input:    "'a.c' like 'a.c' && 'abc' not like 'a.c'",
+			Expected: true,
+		},
+		{
+			Name:     "Like matches across lines",
+			Input:    "'a\\nb' like 'a%b'",
+			Expected: true,
+		},
+		{
+			Name:     "Negated like",
+			Input:    "'hello' not like 'h%'",
+			Expected: false,
+		},
+		{
+			Name:     "Upper case like",
+			Input:    "'hello' LIKE 'h%' && 'hello' NOT LIKE 'x%'",
+			Expected: true,
+		},
+		{
+			Name:     "Ilike",
+			Input:    "'HeLLo' ilike 'hello' && 'HELLO' ilike 'h_llo%'",
+			Expected: true,
+		},
+		{
+			Name:     "Negated ilike",
+			Input:    "'HELLO' not ilike 'h%'",
+			Expected: false,
+		},
+		{
+			Name:     "Glob with wildcards",
+			Input:    "'report.pdf' glob '*.pdf' && 'report.pdf' glob 'rep?rt.*'",
+			Expected: true,
+		},
+		{
+			Name:     "Glob character class",
+			Input:    "'b1' glob '[abc][0-9]' && 'd1' not glob '[abc][0-9]'",
+			Expected: true,
+		},
+		{
+			Name:     "Glob negated character class",
+			Input:    "'a' glob '[!abc]' || 'a' glob '[^abc]'",
+			Expected: false,
+		},
+		{
+			Name:     "Glob class starting with a bracket",
+			Input:    "']' glob '[]a]'",
+			Expected: true,
+		},
+		{
+			Name:     "Glob with escaped wildcards",
+			Input:    "'a*' glob 'a\\\\*' && 'ab' not glob 'a\\\\*'",
+			Expected: true,
+		},
+		{
+			Name:  "Like against a parameter pattern",
+			Input: "name like pattern",
+			Parameters: []EvaluationParameter{
+				{Name: "name", Value: "foobar"},
+				{Name: "pattern", Value: "foo%"},
+			},
+			Expected: true,
+		},
+		{
+			Name:  "Like between other comparators",
+			Input: "name like 'foo%' == flag",
+			Parameters: []EvaluationParameter{
+				{Name: "flag", Value: true},
+				{Name: "name", Value: "foobar"},
+			},
+			Expected: true,
+		},
+	}
+
+	for i := range evaluationTests {
+		evaluationTests[i].Options = ExpressionOptions{PatternOperators: true}
+	}
+	runEvaluationTests(evaluationTests, test)
+}
+
+func TestMatchOperatorFailures(test *testing.T) {
+
+	evaluationTests := []EvaluationFailureTest{
+		{
+			Name:       "Like with a non-string pattern",
+			Input:      "name like 1",
+			Parameters: map[string]interface{}{"name": "foo"},
+			Expected:   invalidComparatorTypes,
+		},
+		{
+			Name:       "Glob against a number",
+			Input:      "number glob '*'",
+			Parameters: map[string]interface{}{"number": 1.0},
+			Expected:   invalidComparatorTypes,
+		},
+		{
+			Name:       "Unfinished escape in a parameter pattern",
+			Input:      "name like pattern",
+			Parameters: map[string]interface{}{"name": "foo", "pattern": "foo\\"},
+			Expected:   "Like pattern 'foo\\' ends with an unfinished escape",
+		},
+		{
+			Name:       "Unclosed class in a parameter pattern",
+			Input:      "name glob pattern",
+			Parameters: map[string]interface{}{"name": "foo", "pattern": "[foo"},
+			Expected:   "Glob pattern '[foo' has an unclosed character class",
+		},
+	}
+
+	for i := range evaluationTests {
+		evaluationTests[i].Options = ExpressionOptions{PatternOperators: true}
+	}
+	runEvaluationFailureTests(evaluationTests, test)
+}
+
+// Tests that constant patterns are compiled when the expression is parsed.
+func TestMatchPatternPrecompilation(test *testing.T) {
+
+	_, err := NewExpressionWithOptions("name glob '[abc'", ExpressionOptions{PatternOperators: true})
+	if err == nil || !strings.Contains(err.Error(), "unclosed character class") {
+		test.Errorf("Expected an invalid constant pattern to fail parsing, got %v", err)
+	}
+
+	// including those which are reordered among other comparators after being planned.
+	for _, input := range []string{"name like 'foo%'", "name like 'foo%' == flag != false"} {
+
+		cache := NewRegexCache(10)
+		options := ExpressionOptions{RegexCache: cache, PatternOperators: true}
+
+		expression, err := NewExpressionWithOptions(input, options)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		if cache.Stats().Misses != 1 {
+			test.Errorf("Expected the pattern of '%s' to be compiled while parsing, got %+v", input, cache.Stats())
+		}
+
+		for i := 0; i < 3; i++ {
+
+			result, err := expression.Evaluate(map[string]interface{}{"name": "foobar", "flag": true})
+			if err != nil || result != true {
+				test.Errorf("Expected '%s' to be true, got %v (%v)", input, result, err)
+			}
+		}
+
+		stats := cache.Stats()
+		if stats.Misses != 1 || stats.Hits != 0 {
+			test.Errorf("Expected the precompiled pattern of '%s' to be used when evaluated, got %+v", input, stats)
+		}
+	}
+}
+
+// "not like" (and the like) need whitespace between their words, so these can't be run through combineWhitespaceExpressions.
+func TestMatchOperatorParsing(test *testing.T) {
+
+	tokenParsingTests := []TokenParsingTest{
+		{
+			Name:    "Like operators",
+			Input:   "a ILIKE 'x' && a not like 'y' && a NOT GLOB 'z'",
+			Options: ExpressionOptions{PatternOperators: true},
+			Expected: []ExpressionToken{
+				{Kind: variable, Value: "a"},
+				{Kind: comparator, Value: "ilike"},
+				{Kind: stringToken, Value: "x"},
+				{Kind: logicalop, Value: "&&"},
+				{Kind: variable, Value: "a"},
+				{Kind: comparator, Value: "not like"},
+				{Kind: stringToken, Value: "y"},
+				{Kind: logicalop, Value: "&&"},
+				{Kind: variable, Value: "a"},
+				{Kind: comparator, Value: "not glob"},
+				{Kind: stringToken, Value: "z"},
+			},
+		},
+		{
+			Name:  "Pattern comparators not enabled",
+			Input: "like == glob",
+			Expected: []ExpressionToken{
+				{Kind: variable, Value: "like"},
+				{Kind: comparator, Value: "=="},
+				{Kind: variable, Value: "glob"},
+			},
+		},
+		{
+			Name:    "Parameter named 'not' before a similar word",
+			Input:   "not == likely",
+			Options: ExpressionOptions{PatternOperators: true},
+			Expected: []ExpressionToken{
+				{Kind: variable, Value: "not"},
+				{Kind: comparator, Value: "=="},
+				{Kind: variable, Value: "likely"},
+			},
+		},
+	}
+
+	runTokenParsingTest(tokenParsingTests, test)
+}
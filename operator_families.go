@@ -0,0 +1,139 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// OperatorFamily is a set of related operators or constructs, which can be disabled as a whole with [ExpressionOptions].DisabledOperators.
+// Families can be combined with |, as in `RegexOperators | BitwiseOperators`.
+type OperatorFamily int
+
+const (
+	// RegexOperators are the regex comparators, `=~` and `!~`.
+	RegexOperators OperatorFamily = 1 << iota
+
+	// BitwiseOperators are `&`, `|`, `^`, `<<`, `>>`, and the prefix `~`.
+	BitwiseOperators
+
+	// TernaryOperators are `?` and `:`. Null coalescence (`??`) is not included.
+	TernaryOperators
+
+	// AccessorOperators are accessors of fields and methods, such as `foo.Bar` and `foo?.Bar()`.
+	AccessorOperators
+
+	// FunctionCalls are calls to functions, including the built-in collection functions such as `any`.
+	FunctionCalls
+
+	// DateLiterals are string constants which are parsed as dates, such as '2014-01-02'.
+	DateLiterals
+
+	// AllOperatorFamilies is every family. Use `AllOperatorFamilies &^ FunctionCalls` to disable all but one.
+	AllOperatorFamilies = RegexOperators | BitwiseOperators | TernaryOperators | AccessorOperators | FunctionCalls | DateLiterals
+)
+
+func (family OperatorFamily) String() string {
+
+	switch family {
+	case RegexOperators:
+		return "Regex operator"
+	case BitwiseOperators:
+		return "Bitwise operator"
+	case TernaryOperators:
+		return "Ternary operator"
+	case AccessorOperators:
+		return "Accessor"
+	case FunctionCalls:
+		return "Function call"
+	case DateLiterals:
+		return "Date literal"
+	}
+	return fmt.Sprintf("OperatorFamily(%d)", int(family))
+}
+
+// tokenSpan is where a token was read from in an expression's text, as byte offsets.
+type tokenSpan struct {
+	start int
+	end   int
+}
+
+// Returns the family that the given token belongs to, or zero if it doesn't belong to any which can be disabled.
+func familyOf(token ExpressionToken) OperatorFamily {
+
+	switch token.Kind {
+
+	case comparator:
+		symbol := comparatorSymbols[token.Value.(string)]
+		if symbol == req || symbol == nreq {
+			return RegexOperators
+		}
+
+	case modifier:
+		_, bitwise := bitwiseSymbols[token.Value.(string)]
+		_, shift := bitwiseShiftSymbols[token.Value.(string)]
+		if bitwise || shift {
+			return BitwiseOperators
+		}
+
+	case prefix:
+		if prefixSymbols[token.Value.(string)] == bitwiseNot {
+			return BitwiseOperators
+		}
+
+	case ternary:
+		symbol := ternarySymbols[token.Value.(string)]
+		if symbol == ternaryTrue || symbol == ternaryFalse {
+			return TernaryOperators
+		}
+
+	case accessor:
+		return AccessorOperators
+	case function, collectionFunction:
+		return FunctionCalls
+	case timeToken:
+		return DateLiterals
+	}
+	return 0
+}
+
+// Returns an error naming the first token which belongs to one of the [disabled] families, along with where it is in the [expression].
+func checkOperatorFamilies(expression string, tokens []ExpressionToken, spans []tokenSpan, disabled OperatorFamily) error {
+
+	if disabled == 0 {
+		return nil
+	}
+
+	for i, token := range tokens {
+
+		family := familyOf(token)
+		if family == 0 || disabled&family == 0 {
+			continue
+		}
+
+		span := spans[i]
+		text := strings.TrimSpace(expression[span.start:span.end])
+		position := utf8.RuneCountInString(expression[:span.start]) + 1
+
+		return &DisabledOperatorError{Family: family, Text: text, Position: position}
+	}
+	return nil
+}
+
+// DisabledOperatorError is returned when parsing an expression which uses an operator (or other construct)
+// whose family is disabled by [ExpressionOptions].DisabledOperators.
+type DisabledOperatorError struct {
+
+	// Family is the disabled family that the operator belongs to.
+	Family OperatorFamily
+
+	// Text is the operator as written in the expression, such as "=~" or "foo.Bar".
+	Text string
+
+	// Position is where the operator starts in the expression, counted in characters from 1.
+	Position int
+}
+
+func (err *DisabledOperatorError) Error() string {
+	return fmt.Sprintf("%s '%s' at position %d is not allowed", err.Family.String(), err.Text, err.Position)
+}
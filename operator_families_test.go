@@ -0,0 +1,76 @@
+package govaluate
+
+import (
+	"testing"
+)
+
+// Represents a test of an expression which uses a disabled family of operators, and should fail to parse.
+type DisabledOperatorTest struct {
+	Input    string
+	Disabled OperatorFamily
+	Family   OperatorFamily
+	Text     string
+	Position int
+}
+
+func TestDisabledOperators(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"strlen": func(arguments ...interface{}) (interface{}, error) {
+			return float64(len(arguments[0].(string))), nil
+		},
+	}
+
+	disabledOperatorTests := []DisabledOperatorTest{
+		{Input: "name =~ '^a'", Disabled: RegexOperators, Family: RegexOperators, Text: "=~", Position: 6},
+		{Input: "name !~ '^a'", Disabled: RegexOperators, Family: RegexOperators, Text: "!~", Position: 6},
+		{Input: "1 | 2", Disabled: BitwiseOperators, Family: BitwiseOperators, Text: "|", Position: 3},
+		{Input: "1 + (2 << 1)", Disabled: BitwiseOperators, Family: BitwiseOperators, Text: "<<", Position: 8},
+		{Input: "~1", Disabled: BitwiseOperators, Family: BitwiseOperators, Text: "~", Position: 1},
+		{Input: "true ? 1 : 2", Disabled: TernaryOperators, Family: TernaryOperators, Text: "?", Position: 6},
+		{Input: "foo.Nested.Funk == 'x'", Disabled: AccessorOperators, Family: AccessorOperators, Text: "foo.Nested.Funk", Position: 1},
+		{Input: "'é' + strlen('abc')", Disabled: FunctionCalls, Family: FunctionCalls, Text: "strlen", Position: 7},
+		{Input: "any([1], # > 0)", Disabled: FunctionCalls, Family: FunctionCalls, Text: "any", Position: 1},
+		{Input: "now > '2014-01-02'", Disabled: DateLiterals, Family: DateLiterals, Text: "'2014-01-02'", Position: 7},
+		{Input: "1 ^ 2 =~ 'x'", Disabled: AllOperatorFamilies, Family: BitwiseOperators, Text: "^", Position: 3},
+	}
+
+	for _, disabledTest := range disabledOperatorTests {
+
+		options := ExpressionOptions{Functions: functions, DisabledOperators: disabledTest.Disabled}
+		_, err := NewExpressionWithOptions(disabledTest.Input, options)
+
+		disabledError, ok := err.(*DisabledOperatorError)
+		if !ok {
+			test.Errorf("Expected '%s' to fail with a *DisabledOperatorError, got %v", disabledTest.Input, err)
+			continue
+		}
+
+		if disabledError.Family != disabledTest.Family || disabledError.Text != disabledTest.Text || disabledError.Position != disabledTest.Position {
+			test.Errorf("Expected '%s' to be rejected for %s '%s' at %d, got: %v", disabledTest.Input, disabledTest.Family, disabledTest.Text, disabledTest.Position, err)
+		}
+	}
+
+	expected := "Regex operator '=~' at position 6 is not allowed"
+	_, err := NewExpressionWithOptions("name =~ '^a'", ExpressionOptions{DisabledOperators: RegexOperators})
+	if err == nil || err.Error() != expected {
+		test.Errorf("Expected error '%s', got %v", expected, err)
+	}
+
+	// other families, and operators which merely look alike, remain usable.
+	enabledInputs := map[string]OperatorFamily{
+		"name =~ '^a' && foo ?? true":           BitwiseOperators | TernaryOperators,
+		"1 & 2 == 0 || true ? 1 : 2":            RegexOperators,
+		"(foo ?? 1) > 0 && strlen('abc') == 3":  TernaryOperators | AccessorOperators | DateLiterals,
+		"name == 'abc' && 1 + 2 * 3 ** 2 > 4":   AllOperatorFamilies,
+		"!(1 > 2) && -1 < 0 && name in ['abc']": AllOperatorFamilies,
+	}
+
+	for input, disabled := range enabledInputs {
+
+		_, err := NewExpressionWithOptions(input, ExpressionOptions{Functions: functions, DisabledOperators: disabled})
+		if err != nil {
+			test.Errorf("Expected '%s' to parse, got %v", input, err)
+		}
+	}
+}
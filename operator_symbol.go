@@ -16,9 +16,22 @@ const (
 	req
 	nreq
 	in
+	notIn
+	like
+	notLike
+	ilike
+	notIlike
+	glob
+	notGlob
+	between
+	notBetween
+	betweenBounds
+	is
+	isNot
 
 	and
 	or
+	xor
 
 	plus
 	minus
@@ -43,6 +56,11 @@ const (
 	functional
 	access
 	separate
+	collectionFunctional
+
+	arrayElement
+	mapElement
+	mapEntry
 )
 
 type operatorPrecedence int
@@ -57,11 +75,16 @@ const (
 	bitwisePrecedence
 	bitwiseShiftPrecedence
 	multiplicativePrecedence
+	betweenPrecedence
+	betweenBoundsPrecedence
 	comparatorPrecedence
 	ternaryPrecedence
 	logicalAndPrecedence
+	logicalXorPrecedence
 	logicalOrPrecedence
 	separatePrecedence
+	collectionPrecedence
+	collectionEntryPrecedence
 )
 
 func findOperatorPrecedenceForSymbol(symbol OperatorSymbol) operatorPrecedence {
@@ -87,9 +110,25 @@ func findOperatorPrecedenceForSymbol(symbol OperatorSymbol) operatorPrecedence {
 	case nreq:
 		fallthrough
 	case in:
+		fallthrough
+	case notIn:
+		fallthrough
+	case is:
+		fallthrough
+	case isNot:
+		fallthrough
+	case like, notLike, ilike, notIlike, glob, notGlob:
 		return comparatorPrecedence
+	case between:
+		fallthrough
+	case notBetween:
+		return betweenPrecedence
+	case betweenBounds:
+		return betweenBoundsPrecedence
 	case and:
 		return logicalAndPrecedence
+	case xor:
+		return logicalXorPrecedence
 	case or:
 		return logicalOrPrecedence
 	case bitwiseAnd:
@@ -128,10 +167,18 @@ func findOperatorPrecedenceForSymbol(symbol OperatorSymbol) operatorPrecedence {
 		return ternaryPrecedence
 	case access:
 		fallthrough
+	case collectionFunctional:
+		fallthrough
 	case functional:
 		return functionalPrecedence
 	case separate:
 		return separatePrecedence
+	case arrayElement:
+		fallthrough
+	case mapElement:
+		return collectionPrecedence
+	case mapEntry:
+		return collectionEntryPrecedence
 	default:
 		return valuePrecedence
 	}
@@ -141,20 +188,36 @@ func findOperatorPrecedenceForSymbol(symbol OperatorSymbol) operatorPrecedence {
 // Used during parsing of expressions to determine if a symbol is, in fact, a comparator.
 // Also used during evaluation to determine exactly which comparator is being used.
 var comparatorSymbols = map[string]OperatorSymbol{
-	"==": eq,
-	"!=": neq,
-	">":  gt,
-	">=": gte,
-	"<":  lt,
-	"<=": lte,
-	"=~": req,
-	"!~": nreq,
-	"in": in,
+	"==":        eq,
+	"!=":        neq,
+	">":         gt,
+	">=":        gte,
+	"<":         lt,
+	"<=":        lte,
+	"=~":        req,
+	"!~":        nreq,
+	"in":        in,
+	"not in":    notIn,
+	"like":      like,
+	"not like":  notLike,
+	"ilike":     ilike,
+	"not ilike": notIlike,
+	"glob":      glob,
+	"not glob":  notGlob,
+	"is":        is,
+	"is not":    isNot,
 }
 
 var logicalSymbols = map[string]OperatorSymbol{
-	"&&": and,
-	"||": or,
+	"&&":  and,
+	"||":  or,
+	"xor": xor,
+}
+
+// the comparators which take a pair of bounds, as in "x between 1 and 5". These are only read as words (see ExpressionOptions.WordOperators).
+var betweenSymbols = map[string]OperatorSymbol{
+	"between":     between,
+	"not between": notBetween,
 }
 
 var bitwiseSymbols = map[string]OperatorSymbol{
@@ -195,6 +258,9 @@ var ternarySymbols = map[string]OperatorSymbol{
 	"??": coalesce,
 }
 
+// the arrow separating a lambda's parameter name from its body, as in "any(items, x => x > 1)".
+const lambdaArrow string = "=>"
+
 // this is defined separately from additiveSymbols et al because it's needed for parsing, not stage planning.
 var modifierSymbols = map[string]OperatorSymbol{
 	"+":  plus,
@@ -257,6 +323,32 @@ func (os OperatorSymbol) String() string {
 		return "||"
 	case in:
 		return "in"
+	case notIn:
+		return "not in"
+	case like:
+		return "like"
+	case notLike:
+		return "not like"
+	case ilike:
+		return "ilike"
+	case notIlike:
+		return "not ilike"
+	case glob:
+		return "glob"
+	case notGlob:
+		return "not glob"
+	case is:
+		return "is"
+	case isNot:
+		return "is not"
+	case between:
+		return "between"
+	case notBetween:
+		return "not between"
+	case betweenBounds:
+		return "and"
+	case xor:
+		return "xor"
 	case bitwiseAnd:
 		return "&"
 	case bitwiseOr:
@@ -291,6 +383,12 @@ func (os OperatorSymbol) String() string {
 		return ":"
 	case coalesce:
 		return "??"
+	case arrayElement:
+		return "[]"
+	case mapElement:
+		return "{}"
+	case mapEntry:
+		return ":"
 	default:
 		return ""
 	}
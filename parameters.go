@@ -1,6 +1,7 @@
 package govaluate
 
 import (
+	"context"
 	"errors"
 )
 
@@ -13,6 +14,15 @@ type Parameters interface {
 	Get(name string) (interface{}, error)
 }
 
+// ParametersContext is implemented by Parameters which can make use of the context of an evaluation, such as to cancel a slow lookup.
+// When an expression is evaluated with [Expression.EvalContext], GetContext is used instead of Get.
+type ParametersContext interface {
+	Parameters
+
+	// GetContext is like Get, except that it is given the context of the evaluation.
+	GetContext(ctx context.Context, name string) (interface{}, error)
+}
+
 // MapParameters is an implementation of Parameters interface with map as store for parameters.
 type MapParameters map[string]interface{}
 
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"text/scanner"
@@ -12,15 +11,23 @@ import (
 	"unicode"
 )
 
-func parseTokens(expression string, functions map[string]ExpressionFunction) ([]ExpressionToken, error) {
+// Returns the tokens of the given [expression], along with the name of each function called (keyed by the index of its token),
+// and where each token was read from.
+func parseTokens(expression string, functions map[string]ExpressionFunction, options ExpressionOptions) ([]ExpressionToken, map[int]string, []tokenSpan, error) {
 
 	var ret []ExpressionToken
+	var spans []tokenSpan
+	var functionNames = make(map[int]string)
 	var token ExpressionToken
 	var stream scanner.Scanner
 	var state lexerState
 	var err error
 	var found bool
 
+	// the depths of clauses at which a "between" is still waiting for the "and" which separates its bounds.
+	var betweens []int
+	var depth int
+
 	reader := strings.NewReader(expression)
 	stream.Init(reader)
 	stream.Mode = scanner.ScanIdents | scanner.ScanFloats | scanner.ScanStrings | scanner.ScanRawStrings | scanner.ScanComments | scanner.SkipComments
@@ -29,36 +36,73 @@ func parseTokens(expression string, functions map[string]ExpressionFunction) ([]
 
 	for stream.Peek() != scanner.EOF {
 
-		token, err, found = readToken(&stream, state, functions)
+		offset := stream.Pos().Offset
+		start := len(expression) - len(strings.TrimLeft(expression[offset:], " \t\r\n"))
+
+		if isRegexLiteral(&stream, expression, ret) {
+			token, err = readRegexLiteral(&stream)
+			found = true
+		} else {
+			token, err, found = readToken(&stream, expression, state, functions, options)
+		}
 
 		if err != nil {
-			return ret, err
+			return ret, nil, nil, err
 		}
 
 		if !found {
 			break
 		}
 
+		// the "null" of "is null" is always the literal, even where "null" isn't otherwise a keyword.
+		if token.Kind == variable && isNullPredicate(ret) && strings.EqualFold(token.Value.(string), "null") {
+			token = ExpressionToken{Kind: nullToken}
+		}
+
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
-			return ret, err
+			return ret, nil, nil, err
+		}
+
+		switch token.Kind {
+		case clause, arrayClause, mapClause:
+			depth++
+		case clauseClose, arrayClauseClose, mapClauseClose:
+			depth--
+		case comparator:
+			if token.Value == "between" || token.Value == "not between" {
+				betweens = append(betweens, depth)
+			}
+		case logicalop:
+			// the first "and" (as a word) after a "between" at the same depth separates its bounds, rather than being a logical operator.
+			last := len(betweens) - 1
+			if last >= 0 && betweens[last] == depth && strings.EqualFold(expression[start:stream.Pos().Offset], "and") {
+				token = ExpressionToken{Kind: boundsSeparator, Value: "and"}
+				betweens = betweens[:last]
+			}
+		}
+
+		// functions are read by name, which is kept aside for the planner.
+		if token.Kind == function {
+			functionNames[len(ret)] = token.Value.(string)
+			token.Value = functions[token.Value.(string)]
 		}
 
 		// append this valid token
 		ret = append(ret, token)
+		spans = append(spans, tokenSpan{start: start, end: stream.Pos().Offset})
 	}
 
 	err = checkBalance(ret)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	return ret, nil
+	return ret, functionNames, spans, nil
 }
 
-func readToken(stream *scanner.Scanner, state lexerState, functions map[string]ExpressionFunction) (ExpressionToken, error, bool) {
+func readToken(stream *scanner.Scanner, source string, state lexerState, functions map[string]ExpressionFunction, options ExpressionOptions) (ExpressionToken, error, bool) {
 
-	var fnFunction ExpressionFunction
 	var ret ExpressionToken
 	var tokenValue interface{}
 	var tokenTime time.Time
@@ -97,6 +141,12 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 		tokenValue = ","
 		kind = separator
 	case '[':
+		if isArrayLiteral(stream, source) {
+			tokenValue = '['
+			kind = arrayClause
+			break
+		}
+
 		tokenValue, completed = readUntilFalse(stream, true, isNotClosingBracket)
 		kind = variable
 
@@ -119,6 +169,13 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 			tokenString = tokenString + s
 		}
 
+		if options.WordOperators {
+			ret, found = readWordOperator(stream, source, tokenString, options)
+			if found {
+				return ret, nil, true
+			}
+		}
+
 		tokenValue = tokenString
 		kind = variable
 
@@ -140,48 +197,84 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 
 		default:
 
+			// "is null" and "is not null" are textual operators, but "is" on its own can still be a parameter name.
+			if tokenString == "is" || tokenString == "IS" {
+
+				ret, found = readNullPredicate(stream, source, false)
+				if found {
+					return ret, nil, true
+				}
+			}
+
+			// "not in" (and "not like", and so on) are textual operators, but "not" on its own can still be a parameter name.
+			if tokenString == "not" || tokenString == "NOT" {
+
+				negated := findNegatedWord(stream, source, options)
+				if negated != "" {
+
+					// skip over the negated word
+					stream.Scan()
+					tokenValue = "not " + negated
+					kind = comparator
+					break
+				}
+			}
+
+			// pattern matching comparator? these are only words when enabled, so that they can otherwise be parameter names.
+			if options.PatternOperators && isPatternWord(tokenString, false) {
+				tokenValue = strings.ToLower(tokenString)
+				kind = comparator
+				break
+			}
+
 			// function?
-			fnFunction, found = functions[tokenString]
+			_, found = functions[tokenString]
 			if found {
 				kind = function
-				tokenValue = fnFunction
 				break
 			}
 
-			// accessor?
-			if stream.Peek() == '.' {
-
-				splits := []string{tokenString}
-				for stream.Peek() == '.' {
-					stream.Scan()
-					// check that it doesn't end with a hanging period
-					if stream.Scan() != scanner.Ident {
-						errorMsg := fmt.Sprintf("Hanging accessor on token '%s'", tokenString)
-						return ExpressionToken{}, errors.New(errorMsg), false
-					}
+			// null? it's only a keyword with three-valued logic (or word operators), so it can otherwise be a parameter name.
+			// Functions of the same name take precedence, since they're explicitly given.
+			if (tokenString == "null" || tokenString == "NULL") && options.ThreeValuedLogic {
+				kind = nullToken
+				tokenValue = nil
+				break
+			}
 
-					tokenString = stream.TokenText()
-					//Hack for crazy escapes in variable names
-					if stream.Peek() == '\'' {
-						s, _ := readUntilFalse(stream, true, isVariableName)
-						tokenString = tokenString + s
-					}
+			// built-in collection function?
+			_, found = collectionFunctions[tokenString]
+			if found && isFollowedByClause(stream, source) {
+				kind = collectionFunction
+				break
+			}
 
-					// check that none of them are unexported
-					firstCharacter := getFirstRune(tokenString)
-					if unicode.ToUpper(firstCharacter) != firstCharacter {
-						errorMsg := fmt.Sprintf("Unable to access unexported field '%s' in token '%s'", tokenString, strings.Join(splits, "."))
-						return ExpressionToken{}, errors.New(errorMsg), false
-					}
+			// accessor?
+			if stream.Peek() == '.' || isOptionalAccess(stream, source) {
 
-					splits = append(splits, tokenString)
+				tokenValue, err = readAccessor(stream, source, tokenString)
+				if err != nil {
+					return ExpressionToken{}, err, false
 				}
-
 				kind = accessor
-				tokenValue = splits
 			}
 		}
 
+	case '#':
+		// the placeholder for the current element of a collection function, which may be used as a variable or accessor.
+		tokenValue = "#"
+		kind = variable
+
+		skipSpaceBeforeAccessor(stream, source)
+		if stream.Peek() == '.' || isOptionalAccess(stream, source) {
+
+			tokenValue, err = readAccessor(stream, source, "#")
+			if err != nil {
+				return ExpressionToken{}, err, false
+			}
+			kind = accessor
+		}
+
 	case scanner.String, scanner.RawString, '\'':
 		tokenString = stream.TokenText()
 		if tokenString == "'" {
@@ -224,6 +317,15 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 	case ')':
 		tokenValue = ')'
 		kind = clauseClose
+	case ']':
+		tokenValue = ']'
+		kind = arrayClauseClose
+	case '{':
+		tokenValue = '{'
+		kind = mapClause
+	case '}':
+		tokenValue = '}'
+		kind = mapClauseClose
 
 	default:
 
@@ -269,6 +371,11 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 			break
 		}
 
+		if tokenString == lambdaArrow {
+			kind = lambda
+			break
+		}
+
 		errorMessage := fmt.Sprintf("Invalid token: '%s'", tokenString)
 		return ret, errors.New(errorMessage), false
 	}
@@ -279,6 +386,46 @@ func readToken(stream *scanner.Scanner, state lexerState, functions map[string]E
 	return ret, nil, (kind != unknown)
 }
 
+// Reads the rest of an accessor chain (such as ".Bar?.Baz") following the [first] name, which has already been read.
+func readAccessor(stream *scanner.Scanner, source string, first string) ([]string, error) {
+
+	var tokenString string
+
+	splits := []string{first}
+	tokenString = first
+
+	for stream.Peek() == '.' || isOptionalAccess(stream, source) {
+
+		// optional links ("foo?.Bar") are marked on the name of the member they access.
+		optional := stream.Peek() == '?'
+		if optional {
+			stream.Next()
+		}
+
+		stream.Scan()
+		// check that it doesn't end with a hanging period
+		if stream.Scan() != scanner.Ident {
+			errorMsg := fmt.Sprintf("Hanging accessor on token '%s'", tokenString)
+			return nil, errors.New(errorMsg)
+		}
+
+		tokenString = stream.TokenText()
+		//Hack for crazy escapes in variable names
+		if stream.Peek() == '\'' {
+			s, _ := readUntilFalse(stream, true, isVariableName)
+			tokenString = tokenString + s
+		}
+
+		if optional {
+			tokenString = optionalAccessPrefix + tokenString
+		}
+
+		splits = append(splits, tokenString)
+	}
+
+	return splits, nil
+}
+
 func readTokenUntilFalse(stream *scanner.Scanner, condition func(rune) bool) string {
 
 	var tokenBuffer bytes.Buffer
@@ -340,8 +487,9 @@ func readUntilFalse(stream *scanner.Scanner, allowEscaping bool, condition func(
 /*
 	Checks to see if any optimizations can be performed on the given [tokens], which form a complete, valid expression.
 	The returns slice will represent the optimized (or unmodified) list of tokens to use.
+	Constant regex patterns are compiled here, through [cache] and within the regex [limits].
 */
-func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
+func optimizeTokens(tokens []ExpressionToken, cache *RegexCache, limits Limits) ([]ExpressionToken, error) {
 
 	var token ExpressionToken
 	var symbol OperatorSymbol
@@ -365,7 +513,7 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 		if token.Kind == stringToken {
 
 			token.Kind = pattern
-			token.Value, err = regexp.Compile(token.Value.(string))
+			token.Value, err = compileRegex(token.Value.(string), cache, limits)
 
 			if err != nil {
 				return tokens, err
@@ -378,35 +526,79 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 }
 
 /*
-	Checks the balance of tokens which have multiple parts, such as parenthesis.
+	Checks the balance of tokens which have multiple parts, such as parenthesis, array brackets, and map braces.
 */
 func checkBalance(tokens []ExpressionToken) error {
 
 	var stream *tokenStream
 	var token ExpressionToken
-	var parens int
+	var open []TokenKind
+	var counts = make(map[TokenKind]int)
 
 	stream = newTokenStream(tokens)
 
 	for stream.hasNext() {
 
 		token = stream.next()
-		if token.Kind == clause {
-			parens++
-			continue
-		}
-		if token.Kind == clauseClose {
-			parens--
-			continue
+
+		switch token.Kind {
+		case clause, arrayClause, mapClause:
+			open = append(open, token.Kind)
+			counts[token.Kind]++
+		case clauseClose, arrayClauseClose, mapClauseClose:
+
+			// closing something other than the innermost open clause, such as "(1, [2)]"
+			if len(open) > 0 {
+				if closingKindFor(open[len(open)-1]) != token.Kind {
+					return unbalancedError(token.Kind)
+				}
+				open = open[:len(open)-1]
+			}
+			counts[openingKindFor(token.Kind)]--
 		}
 	}
 
-	if parens != 0 {
-		return errors.New("Unbalanced parenthesis")
+	for _, kind := range []TokenKind{clause, arrayClause, mapClause} {
+		if counts[kind] != 0 {
+			return unbalancedError(kind)
+		}
 	}
 	return nil
 }
 
+func openingKindFor(kind TokenKind) TokenKind {
+
+	switch kind {
+	case arrayClauseClose:
+		return arrayClause
+	case mapClauseClose:
+		return mapClause
+	}
+	return clause
+}
+
+func closingKindFor(kind TokenKind) TokenKind {
+
+	switch kind {
+	case arrayClause:
+		return arrayClauseClose
+	case mapClause:
+		return mapClauseClose
+	}
+	return clauseClose
+}
+
+func unbalancedError(kind TokenKind) error {
+
+	switch kind {
+	case arrayClause, arrayClauseClose:
+		return errors.New("Unbalanced array brackets")
+	case mapClause, mapClauseClose:
+		return errors.New("Unbalanced map braces")
+	}
+	return errors.New("Unbalanced parenthesis")
+}
+
 func isDigit(character rune) bool {
 	return unicode.IsDigit(character)
 }
@@ -427,6 +619,362 @@ func isVariableName(character rune) bool {
 		character == '_'
 }
 
+// the flags that may follow a regex literal, as in "/^a/i". These are the same as Go's own flags, like "(?i)".
+const regexLiteralFlags string = "imsU"
+
+/*
+	Returns true if the stream is positioned at a regex literal (like "/^a/i"), which can only directly follow a regex comparator.
+	Anywhere else, "/" is division.
+*/
+func isRegexLiteral(stream *scanner.Scanner, source string, previous []ExpressionToken) bool {
+
+	if len(previous) == 0 {
+		return false
+	}
+
+	last := previous[len(previous)-1]
+	if last.Kind != comparator {
+		return false
+	}
+
+	symbol := comparatorSymbols[last.Value.(string)]
+	if symbol != req && symbol != nreq {
+		return false
+	}
+
+	remaining := strings.TrimLeft(source[stream.Pos().Offset:], " \t\r\n")
+	return strings.HasPrefix(remaining, "/")
+}
+
+/*
+	Reads a regex literal, like "/^a/i", as a string token holding the equivalent Go pattern (like "(?i)^a").
+	Within the slashes, "\/" is a literal slash; every other character (including other escapes) is part of the pattern as-is.
+*/
+func readRegexLiteral(stream *scanner.Scanner) (ExpressionToken, error) {
+
+	var pattern bytes.Buffer
+	var flags string
+
+	for unicode.IsSpace(stream.Peek()) {
+		stream.Next()
+	}
+
+	// opening slash
+	stream.Next()
+
+	for {
+		character := stream.Next()
+
+		if character == scanner.EOF {
+			return ExpressionToken{}, errors.New("Unclosed regex literal")
+		}
+
+		if character == '/' {
+			break
+		}
+
+		if character == '\\' {
+
+			escaped := stream.Next()
+			if escaped == scanner.EOF {
+				return ExpressionToken{}, errors.New("Unclosed regex literal")
+			}
+
+			if escaped != '/' {
+				pattern.WriteRune(character)
+			}
+			character = escaped
+		}
+
+		pattern.WriteRune(character)
+	}
+
+	for unicode.IsLetter(stream.Peek()) {
+
+		flag := stream.Next()
+		if !strings.ContainsRune(regexLiteralFlags, flag) {
+			errorMsg := fmt.Sprintf("Unknown regex flag '%c', expected any of '%s'", flag, regexLiteralFlags)
+			return ExpressionToken{}, errors.New(errorMsg)
+		}
+
+		if !strings.ContainsRune(flags, flag) {
+			flags += string(flag)
+		}
+	}
+
+	value := pattern.String()
+	if flags != "" {
+		value = "(?" + flags + ")" + value
+	}
+
+	return ExpressionToken{Kind: stringToken, Value: value}, nil
+}
+
+/*
+	Returns true if the stream is positioned at an optional accessor link ("?." followed by a member name).
+	The scanner can only peek one character ahead, so this looks at the [source] directly
+	to tell "foo?.Bar" apart from a ternary like "foo?.5:1".
+*/
+func isOptionalAccess(stream *scanner.Scanner, source string) bool {
+
+	offset := stream.Pos().Offset
+	if !strings.HasPrefix(source[offset:], "?.") {
+		return false
+	}
+
+	next := getFirstRune(source[offset+2:])
+	return unicode.IsLetter(next) || next == '_'
+}
+
+/*
+	Returns true if the "[" just read begins an array literal, rather than an escaped parameter name like "[response-time]".
+	Brackets are an array if they're empty, if they start with another array (so "[[1, 2]]" holds one array),
+	or if they contain a comma (outside of quotes or nested brackets);
+	otherwise they escape a parameter name, whatever else it starts with, as they always have (so "[2xx]" is a parameter).
+	This means that an array of a single element (other than an array) needs a trailing comma, like "[1,]" or "[foo,]".
+*/
+func isArrayLiteral(stream *scanner.Scanner, source string) bool {
+
+	var quote rune
+	var escaped bool
+	var depth int
+
+	contents := source[stream.Pos().Offset:]
+	trimmed := strings.TrimLeftFunc(contents, unicode.IsSpace)
+
+	first := getFirstRune(trimmed)
+	if first == ']' || first == '[' {
+		return true
+	}
+
+	for _, character := range contents {
+
+		if escaped {
+			escaped = false
+			continue
+		}
+
+		switch {
+		case character == '\\':
+			escaped = true
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+		case character == '\'' || character == '"':
+			quote = character
+		case character == '[' || character == '(' || character == '{':
+			depth++
+		case character == ')' || character == '}':
+			depth--
+		case character == ']':
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case character == ',' && depth == 0:
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+	Returns true if the next word in the stream (after at least one space) is [word], in either all lower or upper case (or in any case, if [anyCase]).
+	Used to recognize textual operators which are made of more than one word, like "not in".
+*/
+func isFollowedByWord(stream *scanner.Scanner, source string, word string, anyCase bool) bool {
+	return findWordEnd(source, stream.Pos().Offset, word, anyCase) >= 0
+}
+
+/*
+	Returns the offset in [source] just after [word], if it's the next word after [offset] (following at least one space), or -1 if it isn't.
+*/
+func findWordEnd(source string, offset int, word string, anyCase bool) int {
+
+	remaining := source[offset:]
+	trimmed := strings.TrimLeftFunc(remaining, unicode.IsSpace)
+
+	if len(trimmed) == len(remaining) {
+		return -1
+	}
+
+	if len(trimmed) < len(word) {
+		return -1
+	}
+
+	candidate := trimmed[:len(word)]
+	if candidate != word && candidate != strings.ToUpper(word) && !(anyCase && strings.EqualFold(candidate, word)) {
+		return -1
+	}
+
+	if isVariableName(getFirstRune(trimmed[len(word):])) {
+		return -1
+	}
+	return len(source) - len(trimmed) + len(word)
+}
+
+/*
+	Reads the comparator of "is null" or "is not null", whose "is" has already been read, leaving the "null" to be read as a literal.
+	Returns false if the "is" isn't followed by either.
+*/
+func readNullPredicate(stream *scanner.Scanner, source string, anyCase bool) (ExpressionToken, bool) {
+
+	offset := stream.Pos().Offset
+
+	if findWordEnd(source, offset, "null", anyCase) >= 0 {
+		return ExpressionToken{Kind: comparator, Value: "is"}, true
+	}
+
+	end := findWordEnd(source, offset, "not", anyCase)
+	if end >= 0 && findWordEnd(source, end, "null", anyCase) >= 0 {
+
+		// skip over the "not"
+		stream.Scan()
+		return ExpressionToken{Kind: comparator, Value: "is not"}, true
+	}
+	return ExpressionToken{}, false
+}
+
+/*
+	Returns true if the last of the [tokens] is the comparator of "is null" or "is not null".
+*/
+func isNullPredicate(tokens []ExpressionToken) bool {
+
+	if len(tokens) == 0 {
+		return false
+	}
+
+	last := tokens[len(tokens)-1]
+	return last.Kind == comparator && (last.Value == "is" || last.Value == "is not")
+}
+
+// the pattern matching comparators, which are only words with ExpressionOptions.PatternOperators.
+var patternWords = []string{"like", "ilike", "glob"}
+
+/*
+	Returns true if [word] is a pattern matching comparator, written either all in lower or all in upper case; or in any case, with [anyCase].
+*/
+func isPatternWord(word string, anyCase bool) bool {
+
+	for _, patternWord := range patternWords {
+		if word == patternWord || word == strings.ToUpper(patternWord) || (anyCase && strings.EqualFold(word, patternWord)) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Returns the textual comparator (such as "in") which follows a "not" in the stream, or "" if there isn't one.
+	Pattern matching comparators can only be negated if they're enabled. With word operators, "between" can be negated too,
+	and words are matched in any case.
+*/
+func findNegatedWord(stream *scanner.Scanner, source string, options ExpressionOptions) string {
+
+	words := []string{"in"}
+	if options.PatternOperators {
+		words = append(words, patternWords...)
+	}
+	if options.WordOperators {
+		words = append(words, "between")
+	}
+
+	for _, word := range words {
+		if isFollowedByWord(stream, source, word, options.WordOperators) {
+			return word
+		}
+	}
+	return ""
+}
+
+// the keywords of word operator mode (see ExpressionOptions.WordOperators), which are matched in any case,
+// and the tokens they stand for. In that mode, none of these can be used as parameter names, except in brackets.
+var wordOperatorTokens = map[string]ExpressionToken{
+	"and":     {Kind: logicalop, Value: "&&"},
+	"or":      {Kind: logicalop, Value: "||"},
+	"xor":     {Kind: logicalop, Value: "xor"},
+	"not":     {Kind: prefix, Value: "not"},
+	"true":    {Kind: boolean, Value: true},
+	"false":   {Kind: boolean, Value: false},
+	"null":    {Kind: nullToken, Value: nil},
+	"between": {Kind: comparator, Value: "between"},
+	"in":      {Kind: comparator, Value: "in"},
+	"like":    {Kind: comparator, Value: "like"},
+	"ilike":   {Kind: comparator, Value: "ilike"},
+	"glob":    {Kind: comparator, Value: "glob"},
+}
+
+// Returns true if [word] is a keyword of word operator mode, given the rest of the [options]; which it's assumed to be in lower case.
+func isWordOperator(word string, options ExpressionOptions) bool {
+
+	_, found := wordOperatorTokens[word]
+	return found && (options.PatternOperators || !isPatternWord(word, false))
+}
+
+/*
+	Returns the token for [word] if it's a keyword of word operator mode, such as "AND" or "Not In".
+	A "not" followed by a negatable comparator (like "not between") is read along with it.
+*/
+func readWordOperator(stream *scanner.Scanner, source string, word string, options ExpressionOptions) (ExpressionToken, bool) {
+
+	word = strings.ToLower(word)
+
+	if word == "is" {
+		return readNullPredicate(stream, source, true)
+	}
+
+	if !isWordOperator(word, options) {
+		return ExpressionToken{}, false
+	}
+	token := wordOperatorTokens[word]
+
+	if word == "not" {
+
+		negated := findNegatedWord(stream, source, options)
+		if negated != "" {
+
+			// skip over the negated word
+			stream.Scan()
+			return ExpressionToken{Kind: comparator, Value: "not " + negated}, true
+		}
+	}
+	return token, true
+}
+
+/*
+	Returns true if the next non-space character in the stream opens a clause, as it does for a function call.
+*/
+func isFollowedByClause(stream *scanner.Scanner, source string) bool {
+
+	remaining := strings.TrimLeftFunc(source[stream.Pos().Offset:], unicode.IsSpace)
+	return strings.HasPrefix(remaining, "(")
+}
+
+/*
+	The placeholder "#" is allowed to be separated from its accessor by spaces (as in "# .Price"),
+	which this skips over if they are followed by an accessor.
+*/
+func skipSpaceBeforeAccessor(stream *scanner.Scanner, source string) {
+
+	remaining := source[stream.Pos().Offset:]
+	trimmed := strings.TrimLeftFunc(remaining, unicode.IsSpace)
+
+	if len(trimmed) == len(remaining) || !strings.HasPrefix(trimmed, ".") {
+		return
+	}
+
+	next := getFirstRune(trimmed[1:])
+	if !unicode.IsLetter(next) && next != '_' {
+		return
+	}
+
+	for unicode.IsSpace(stream.Peek()) {
+		stream.Next()
+	}
+}
+
 func isNotClosingBracket(character rune) bool {
 
 	return character != ']'
@@ -14,10 +14,11 @@ const (
 	unclosedQuotes                = "Unclosed string literal"
 	unclosedBrackets              = "Unclosed parameter bracket"
 	unbalancedParenthesis         = "Unbalanced parenthesis"
+	unbalancedArray               = "Unbalanced array brackets"
+	unbalancedMap                 = "Unbalanced map braces"
 	invalidNumeric                = "Unable to parse numeric value"
 	undefinedFunction             = "Undefined function"
 	hangingAccessor               = "Hanging accessor on token"
-	unexportedAccessor            = "Unable to access unexported"
 	invalidHex                    = "Unable to parse hex value"
 )
 
@@ -136,6 +137,31 @@ func TestParsingFailure(test *testing.T) {
 			Input:    "10 > (1 + 50",
 			Expected: unbalancedParenthesis,
 		},
+		{
+			Name:     "Unbalanced array literal",
+			Input:    "1 in [1, 2",
+			Expected: unbalancedArray,
+		},
+		{
+			Name:     "Unbalanced map literal",
+			Input:    "{'a': 1",
+			Expected: unbalancedMap,
+		},
+		{
+			Name:     "Interleaved array and parenthesis",
+			Input:    "([1, 2)]",
+			Expected: unbalancedParenthesis,
+		},
+		{
+			Name:     "Empty array element",
+			Input:    "[1, , 2]",
+			Expected: invalidTokenTransition,
+		},
+		{
+			Name:     "Map literal without key separator",
+			Input:    "{'a', 1}",
+			Expected: "Expected ':' after map key",
+		},
 		{
 			Name:     "Multiple radix",
 			Input:    "127.0.0.1",
@@ -151,12 +177,6 @@ func TestParsingFailure(test *testing.T) {
 			Input:    "foo.Bar.",
 			Expected: hangingAccessor,
 		},
-		{
-			// this is expected to change once there are structtags in place that allow aliasing of fields
-			Name:     "Unexported parameter access",
-			Input:    "foo.bar",
-			Expected: unexportedAccessor,
-		},
 		{
 			Name:     "Incomplete Hex",
 			Input:    "0x",
@@ -207,6 +227,41 @@ func TestParsingFailure(test *testing.T) {
 			Input:    "(amount > '100' &&) == false",
 			Expected: invalidTokenTransition,
 		},
+		{
+			Name:     "Collection function without a predicate",
+			Input:    "any(items)",
+			Expected: "requires a predicate",
+		},
+		{
+			Name:     "Collection function without a collection",
+			Input:    "count()",
+			Expected: "requires a collection",
+		},
+		{
+			Name:     "Lambda without a parameter",
+			Input:    "any(items, => true)",
+			Expected: invalidTokenTransition,
+		},
+		{
+			Name:     "Lambda without a body",
+			Input:    "any(items, x =>)",
+			Expected: invalidTokenTransition,
+		},
+		{
+			Name:     "Unclosed regex literal",
+			Input:    "foo =~ /abc",
+			Expected: "Unclosed regex literal",
+		},
+		{
+			Name:     "Unknown regex flag",
+			Input:    "foo =~ /abc/x",
+			Expected: "Unknown regex flag 'x'",
+		},
+		{
+			Name:     "Invalid regex literal",
+			Input:    "foo =~ /[a/",
+			Expected: "missing closing ]",
+		},
 	}
 
 	runParsingFailureTests(parsingTests, test)
@@ -14,6 +14,7 @@ type TokenParsingTest struct {
 	Name      string
 	Input     string
 	Functions map[string]ExpressionFunction
+	Options   ExpressionOptions
 	Expected  []ExpressionToken
 }
 
@@ -443,6 +444,120 @@ func TestConstantParsing(test *testing.T) {
 				},
 			},
 		},
+		{
+			Name:  "Array literal",
+			Input: "[1, foo]",
+			Expected: []ExpressionToken{
+				{
+					Kind: arrayClause,
+				},
+				{
+					Kind:  numeric,
+					Value: 1.0,
+				},
+				{
+					Kind: separator,
+				},
+				{
+					Kind:  variable,
+					Value: "foo",
+				},
+				{
+					Kind: arrayClauseClose,
+				},
+			},
+		},
+		{
+			Name:  "Array holding only an array",
+			Input: "[[1, 2]]",
+			Expected: []ExpressionToken{
+				{
+					Kind: arrayClause,
+				},
+				{
+					Kind: arrayClause,
+				},
+				{
+					Kind:  numeric,
+					Value: 1.0,
+				},
+				{
+					Kind: separator,
+				},
+				{
+					Kind:  numeric,
+					Value: 2.0,
+				},
+				{
+					Kind: arrayClauseClose,
+				},
+				{
+					Kind: arrayClauseClose,
+				},
+			},
+		},
+		{
+			Name:  "Map literal",
+			Input: "{'a': []}",
+			Expected: []ExpressionToken{
+				{
+					Kind: mapClause,
+				},
+				{
+					Kind:  stringToken,
+					Value: "a",
+				},
+				{
+					Kind:  ternary,
+					Value: ":",
+				},
+				{
+					Kind: arrayClause,
+				},
+				{
+					Kind: arrayClauseClose,
+				},
+				{
+					Kind: mapClauseClose,
+				},
+			},
+		},
+		{
+			Name:  "Optional accessor variable",
+			Input: "foo?.Var.Sub?.Leaf",
+			Expected: []ExpressionToken{
+				{
+					Kind:  accessor,
+					Value: []string{"foo", "?Var", "Sub", "?Leaf"},
+				},
+			},
+		},
+		{
+			Name:  "Ternary with fractional literal",
+			Input: "foo?.5:1",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "foo",
+				},
+				{
+					Kind:  ternary,
+					Value: "?",
+				},
+				{
+					Kind:  numeric,
+					Value: 0.5,
+				},
+				{
+					Kind:  ternary,
+					Value: ":",
+				},
+				{
+					Kind:  numeric,
+					Value: 1.0,
+				},
+			},
+		},
 	}
 
 	tokenParsingTests = combineWhitespaceExpressions(tokenParsingTests)
@@ -941,6 +1056,192 @@ func TestComparatorParsing(test *testing.T) {
 	runTokenParsingTest(tokenParsingTests, test)
 }
 
+// "not in" needs whitespace between its words, so these can't be run through combineWhitespaceExpressions.
+func TestNegatedMembershipParsing(test *testing.T) {
+
+	tokenParsingTests := []TokenParsingTest{
+		{
+
+			Name:  "Negated membership lowercase",
+			Input: "'foo' not in ['bar', 'baz']",
+			Expected: []ExpressionToken{
+				{
+					Kind:  stringToken,
+					Value: "foo",
+				},
+				{
+					Kind:  comparator,
+					Value: "not in",
+				},
+				{
+					Kind: arrayClause,
+				},
+				{
+					Kind:  stringToken,
+					Value: "bar",
+				},
+				{
+					Kind: separator,
+				},
+				{
+					Kind:  stringToken,
+					Value: "baz",
+				},
+				{
+					Kind: arrayClauseClose,
+				},
+			},
+		},
+		{
+
+			Name:  "Negated membership uppercase",
+			Input: "foo NOT IN bar",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "foo",
+				},
+				{
+					Kind:  comparator,
+					Value: "not in",
+				},
+				{
+					Kind:  variable,
+					Value: "bar",
+				},
+			},
+		},
+		{
+
+			Name:  "Parameter named 'not'",
+			Input: "not == inside",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "not",
+				},
+				{
+					Kind:  comparator,
+					Value: "==",
+				},
+				{
+					Kind:  variable,
+					Value: "inside",
+				},
+			},
+		},
+	}
+
+	runTokenParsingTest(tokenParsingTests, test)
+}
+
+func TestCollectionFunctionParsing(test *testing.T) {
+
+	tokenParsingTests := []TokenParsingTest{
+		{
+
+			Name:  "Collection function with placeholder",
+			Input: "any(items, # .Price > 1)",
+			Expected: []ExpressionToken{
+				{
+					Kind:  collectionFunction,
+					Value: "any",
+				},
+				{
+					Kind: clause,
+				},
+				{
+					Kind:  variable,
+					Value: "items",
+				},
+				{
+					Kind: separator,
+				},
+				{
+					Kind:  accessor,
+					Value: []string{"#", "Price"},
+				},
+				{
+					Kind:  comparator,
+					Value: ">",
+				},
+				{
+					Kind:  numeric,
+					Value: 1.0,
+				},
+				{
+					Kind: clauseClose,
+				},
+			},
+		},
+		{
+
+			Name:  "Collection function with lambda",
+			Input: "count(nums, n => n > 1)",
+			Expected: []ExpressionToken{
+				{
+					Kind:  collectionFunction,
+					Value: "count",
+				},
+				{
+					Kind: clause,
+				},
+				{
+					Kind:  variable,
+					Value: "nums",
+				},
+				{
+					Kind: separator,
+				},
+				{
+					Kind:  variable,
+					Value: "n",
+				},
+				{
+					Kind:  lambda,
+					Value: "=>",
+				},
+				{
+					Kind:  variable,
+					Value: "n",
+				},
+				{
+					Kind:  comparator,
+					Value: ">",
+				},
+				{
+					Kind:  numeric,
+					Value: 1.0,
+				},
+				{
+					Kind: clauseClose,
+				},
+			},
+		},
+		{
+
+			Name:  "Collection function name as a parameter",
+			Input: "count > any",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "count",
+				},
+				{
+					Kind:  comparator,
+					Value: ">",
+				},
+				{
+					Kind:  variable,
+					Value: "any",
+				},
+			},
+		},
+	}
+
+	runTokenParsingTest(tokenParsingTests, test)
+}
+
 func TestModifierParsing(test *testing.T) {
 
 	tokenParsingTests := []TokenParsingTest{
@@ -1278,6 +1579,36 @@ func TestEscapedParameters(test *testing.T) {
 				},
 			},
 		},
+		{
+
+			Name:  "Escaped parameter starting with a number",
+			Input: "[2xx] > y",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "2xx",
+				},
+				{
+					Kind:  comparator,
+					Value: ">",
+				},
+				{
+					Kind:  variable,
+					Value: "y",
+				},
+			},
+		},
+		{
+
+			Name:  "Escaped parameter of a number",
+			Input: "[1]",
+			Expected: []ExpressionToken{
+				{
+					Kind:  variable,
+					Value: "1",
+				},
+			},
+		},
 		{
 
 			Name:  "Escaped parameters and unescaped parameters",
@@ -1576,7 +1907,7 @@ func runTokenParsingTest(tokenParsingTests []TokenParsingTest, test *testing.T)
 		if parsingTest.Functions != nil {
 			expression, err = NewExpressionWithFunctions(parsingTest.Input, parsingTest.Functions)
 		} else {
-			expression, err = NewExpression(parsingTest.Input)
+			expression, err = NewExpressionWithOptions(parsingTest.Input, parsingTest.Options)
 		}
 
 		if err != nil {
@@ -1658,3 +1989,16 @@ func runTokenParsingTest(tokenParsingTests []TokenParsingTest, test *testing.T)
 func noop(arguments ...interface{}) (interface{}, error) {
 	return nil, nil
 }
+
+func TestCollectionFunctionVars(test *testing.T) {
+
+	expression, err := NewExpression("any(items, #.Price > limit) && all(nums, n => n < limit)")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	expected := []string{"items", "limit", "nums", "limit"}
+	if !reflect.DeepEqual(expression.Vars(), expected) {
+		test.Errorf("Expected Vars() to return %v, got %v", expected, expression.Vars())
+	}
+}
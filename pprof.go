@@ -0,0 +1,215 @@
+package govaluate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// The fields of the messages in pprof's profile.proto which are written by WriteProfile.
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// WriteProfile writes what the profiler has recorded as a gzipped profile in pprof's format, so that it can be read by `go tool pprof`.
+// Each sample is a stage, with how many times it was evaluated ("calls") and the time spent on it alone ("time", in nanoseconds).
+// Its stack is made of the stages it's within, and the rule they're part of, so that the total time of each is what it contains.
+// The functions of the profile are named after the text of each stage, and their file name is the rule's.
+// pprof shortens names which look like calls, so "len(items) > 2" is shown as "len > 2" unless it's given `-symbolize=none`.
+func (profiler *Profiler) WriteProfile(writer io.Writer) error {
+
+	profiler.mutex.Lock()
+	started := profiler.started
+	profiler.mutex.Unlock()
+
+	strings := &profileStrings{indices: make(map[string]int64)}
+	strings.index("")
+
+	var profile protoBuffer
+	profile.writeMessage(profileSampleType, func(valueType *protoBuffer) {
+		valueType.writeInt(valueTypeType, strings.index("calls"))
+		valueType.writeInt(valueTypeUnit, strings.index("count"))
+	})
+	profile.writeMessage(profileSampleType, func(valueType *protoBuffer) {
+		valueType.writeInt(valueTypeType, strings.index("time"))
+		valueType.writeInt(valueTypeUnit, strings.index("nanoseconds"))
+	})
+
+	// each rule, and each of their stages, has a function and a location of its own, with the same ID.
+	var id uint64
+	writeFunction := func(name string, filename string) uint64 {
+
+		id++
+		profile.writeMessage(profileFunction, func(function *protoBuffer) {
+			function.writeUint(functionID, id)
+			function.writeInt(functionName, strings.index(name))
+			function.writeInt(functionSystemName, strings.index(name))
+			function.writeInt(functionFilename, strings.index(filename))
+		})
+		profile.writeMessage(profileLocation, func(location *protoBuffer) {
+			location.writeUint(locationID, id)
+			location.writeMessage(locationLine, func(line *protoBuffer) {
+				line.writeUint(lineFunctionID, id)
+			})
+		})
+		return id
+	}
+
+	for _, expressionProfile := range profiler.attached() {
+
+		ruleID := writeFunction(expressionProfile.rule, expressionProfile.rule)
+
+		stages := expressionProfile.stageProfiles()
+		ids := make(map[*evaluationStage]uint64)
+
+		for i, stage := range expressionProfile.stages {
+			ids[stage] = writeFunction(stages[i].Expression, expressionProfile.rule)
+		}
+
+		for i, stage := range expressionProfile.stages {
+
+			if stages[i].Calls == 0 {
+				continue
+			}
+
+			// stacks start from the innermost stage.
+			var stack []uint64
+			for within := stage; within != nil; within = expressionProfile.parents[within] {
+				stack = append(stack, ids[within])
+			}
+			stack = append(stack, ruleID)
+
+			profile.writeMessage(profileSample, func(sample *protoBuffer) {
+				sample.writePacked(sampleLocationID, stack)
+				sample.writePacked(sampleValue, []uint64{uint64(stages[i].Calls), uint64(stages[i].Self)})
+			})
+		}
+	}
+
+	for _, str := range strings.table {
+		profile.writeString(profileStringTable, str)
+	}
+
+	profile.writeInt(profileTimeNanos, started.UnixNano())
+	profile.writeInt(profileDurationNanos, int64(time.Since(started)))
+	profile.writeInt(profileDefaultSampleType, strings.index("time"))
+
+	compressed := gzip.NewWriter(writer)
+
+	_, err := compressed.Write(profile.Bytes())
+	if err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// The string table of a profile, which every other string in it refers to by index.
+type profileStrings struct {
+	table   []string
+	indices map[string]int64
+}
+
+// Returns the index of [str] in the table, adding it if it isn't there yet.
+func (strings *profileStrings) index(str string) int64 {
+
+	index, found := strings.indices[str]
+	if !found {
+		index = int64(len(strings.table))
+		strings.indices[str] = index
+		strings.table = append(strings.table, str)
+	}
+	return index
+}
+
+// Writes messages in protocol buffers' wire format, which is all that's needed to write a profile.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+const (
+	protoVarintType    = 0
+	protoDelimitedType = 2
+)
+
+func (buffer *protoBuffer) writeVarint(value uint64) {
+
+	for value >= 0x80 {
+		buffer.WriteByte(byte(value) | 0x80)
+		value >>= 7
+	}
+	buffer.WriteByte(byte(value))
+}
+
+func (buffer *protoBuffer) writeKey(field int, wireType int) {
+	buffer.writeVarint(uint64(field)<<3 | uint64(wireType))
+}
+
+// Writes [value] as the given [field], unless it's zero, which is the default.
+func (buffer *protoBuffer) writeUint(field int, value uint64) {
+
+	if value == 0 {
+		return
+	}
+
+	buffer.writeKey(field, protoVarintType)
+	buffer.writeVarint(value)
+}
+
+func (buffer *protoBuffer) writeInt(field int, value int64) {
+	buffer.writeUint(field, uint64(value))
+}
+
+// Writes [str] as the given [field], even if it's empty; since it may be an element of a repeated field, like the string table.
+func (buffer *protoBuffer) writeString(field int, str string) {
+
+	buffer.writeKey(field, protoDelimitedType)
+	buffer.writeVarint(uint64(len(str)))
+	buffer.WriteString(str)
+}
+
+func (buffer *protoBuffer) writePacked(field int, values []uint64) {
+
+	var packed protoBuffer
+	for _, value := range values {
+		packed.writeVarint(value)
+	}
+
+	buffer.writeKey(field, protoDelimitedType)
+	buffer.writeVarint(uint64(packed.Len()))
+	buffer.Write(packed.Bytes())
+}
+
+// Writes the message written by [write] as the given [field].
+func (buffer *protoBuffer) writeMessage(field int, write func(*protoBuffer)) {
+
+	var message protoBuffer
+	write(&message)
+
+	buffer.writeKey(field, protoDelimitedType)
+	buffer.writeVarint(uint64(message.Len()))
+	buffer.Write(message.Bytes())
+}
@@ -0,0 +1,324 @@
+package govaluate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Profiler records how long each stage of the expressions it's attached to takes to evaluate, and how often it's evaluated,
+// across every evaluation of them; so that the parts of rules which are slow can be found. It's safe for concurrent use,
+// and can be attached to any number of expressions with [Expression.CollectProfile].
+//
+// The results can be read with Stages and Functions, or written as a table with WriteTable, or as a profile for pprof with WriteProfile.
+type Profiler struct {
+	mutex sync.Mutex
+
+	// when the profiler was made, or last reset.
+	started time.Time
+
+	profiles []*expressionProfile
+}
+
+// StageProfile is the time spent evaluating one stage of an expression, such as "len(items) > 2".
+type StageProfile struct {
+
+	// Rule is the name the expression was given when the profiler was attached to it.
+	Rule string
+
+	// Expression is the text of the stage, and Offset is where it starts within the text of the whole expression, in bytes.
+	Expression string
+	Offset     int
+
+	// Calls is how many times the stage was evaluated.
+	Calls int64
+
+	// Total is the time spent evaluating the stage, including the stages within it, and Self is the time spent on the stage alone.
+	Total time.Duration
+	Self  time.Duration
+}
+
+// FunctionProfile is the time spent in calls to one function, or one accessor, across every expression the profiler is attached to.
+type FunctionProfile struct {
+
+	// Name is the name of the function, or the path of the accessor, such as "foo.Bar".
+	Name string
+
+	// Accessor is true if this is an accessor, rather than a function.
+	Accessor bool
+
+	// Calls is how many times it was evaluated, and Total is the time spent doing so, including evaluating its arguments.
+	Calls int64
+	Total time.Duration
+}
+
+// What a profiler records about one expression.
+type expressionProfile struct {
+	rule   string
+	source string
+	spans  map[*evaluationStage]stageSpan
+
+	// the stages which are recorded, in the order they're written out.
+	stages   []*evaluationStage
+	counters map[*evaluationStage]*profileCounters
+
+	// the innermost stage which is recorded that each recorded stage is within, if any.
+	parents map[*evaluationStage]*evaluationStage
+}
+
+// The counts of a stage, which are changed atomically.
+type profileCounters struct {
+	calls, nanoseconds int64
+}
+
+// NewProfiler returns a new Profiler, which isn't yet attached to any expressions.
+func NewProfiler() *Profiler {
+	return &Profiler{started: time.Now()}
+}
+
+// CollectProfile attaches [profiler] to this expression, as the rule [name], so that it records each evaluation of it by Eval (and its variants)
+// from then on. If [name] is empty, the expression's text is used. Attaching another profiler replaces this one.
+// Parenthesis aren't recorded, and neither are literals within the expression.
+//
+// This should be called before the expression is evaluated concurrently, since attaching it isn't synchronized with evaluation.
+// Copies of the expression made before it's attached aren't profiled, and those made after share the profiler.
+// Fails if the expression can't be written out, which is how its stages are named.
+func (expr *Expression) CollectProfile(profiler *Profiler, name string) error {
+
+	source, spans, err := formatStageWithSpans(expr.evaluationStages, expr.options)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = source
+	}
+
+	profile := &expressionProfile{
+		rule:     name,
+		source:   source,
+		spans:    spans,
+		counters: make(map[*evaluationStage]*profileCounters),
+		parents:  make(map[*evaluationStage]*evaluationStage),
+	}
+	profile.addStages(expr.evaluationStages, nil, true)
+
+	profiler.mutex.Lock()
+	profiler.profiles = append(profiler.profiles, profile)
+	profiler.mutex.Unlock()
+
+	expr.profile = profile
+	return nil
+}
+
+// Adds [stage], and those within it, to the stages which are recorded; in the order they're written out, as with coverage.
+// [parent] is the innermost stage which is recorded that [stage] is within.
+func (profile *expressionProfile) addStages(stage *evaluationStage, parent *evaluationStage, root bool) {
+
+	if stage == nil {
+		return
+	}
+
+	_, written := profile.spans[stage]
+	if written && stage.symbol != noopSymbol && stage.symbol != separate && stage.symbol != betweenBounds && (stage.symbol != literal || root) {
+
+		profile.stages = append(profile.stages, stage)
+		profile.counters[stage] = &profileCounters{}
+
+		if parent != nil {
+			profile.parents[stage] = parent
+		}
+		parent = stage
+	}
+
+	profile.addStages(stage.leftStage, parent, false)
+	profile.addStages(stage.rightStage, parent, false)
+}
+
+// Records that [stage] was evaluated, taking [elapsed].
+func (profile *expressionProfile) record(stage *evaluationStage, elapsed time.Duration) {
+
+	counters := profile.counters[stage]
+	if counters == nil {
+		return
+	}
+
+	atomic.AddInt64(&counters.calls, 1)
+	atomic.AddInt64(&counters.nanoseconds, int64(elapsed))
+}
+
+// Returns the profile of each stage, in the order they're written out; along with the time spent on each, alone.
+func (profile *expressionProfile) stageProfiles() []StageProfile {
+
+	ret := make([]StageProfile, len(profile.stages))
+	indices := make(map[*evaluationStage]int)
+
+	for i, stage := range profile.stages {
+
+		span := profile.spans[stage]
+		counters := profile.counters[stage]
+		indices[stage] = i
+
+		total := time.Duration(atomic.LoadInt64(&counters.nanoseconds))
+
+		ret[i] = StageProfile{
+			Rule:       profile.rule,
+			Expression: profile.source[span.start:span.end],
+			Offset:     span.start,
+			Calls:      atomic.LoadInt64(&counters.calls),
+			Total:      total,
+			Self:       total,
+		}
+	}
+
+	// each stage's time includes the stages within it.
+	for _, stage := range profile.stages {
+
+		parent, found := profile.parents[stage]
+		if found {
+			ret[indices[parent]].Self -= ret[indices[stage]].Total
+		}
+	}
+
+	// counts read while evaluation is adding to them may not quite add up.
+	for i := range ret {
+		if ret[i].Self < 0 {
+			ret[i].Self = 0
+		}
+	}
+	return ret
+}
+
+// Stages returns the profile of each stage of each expression the profiler is attached to, in the order they were attached,
+// and then in the order their stages are written out.
+// The counts are read while evaluation may still be adding to them, so they're only consistent with each other if it isn't.
+func (profiler *Profiler) Stages() []StageProfile {
+
+	var ret []StageProfile
+
+	for _, profile := range profiler.attached() {
+		ret = append(ret, profile.stageProfiles()...)
+	}
+	return ret
+}
+
+// Functions returns the profile of each function and accessor called by the expressions the profiler is attached to,
+// from the most time spent to the least.
+func (profiler *Profiler) Functions() []FunctionProfile {
+
+	type functionKey struct {
+		name     string
+		accessor bool
+	}
+
+	var ret []FunctionProfile
+	indices := make(map[functionKey]int)
+
+	for _, profile := range profiler.attached() {
+		for _, stage := range profile.stages {
+
+			var key functionKey
+
+			switch stage.symbol {
+			case functional:
+				key = functionKey{name: stage.name}
+			case collectionFunctional:
+				key = functionKey{name: stage.collection.name}
+			case access:
+				key = functionKey{name: stage.name, accessor: true}
+			default:
+				continue
+			}
+
+			index, found := indices[key]
+			if !found {
+				index = len(ret)
+				indices[key] = index
+				ret = append(ret, FunctionProfile{Name: key.name, Accessor: key.accessor})
+			}
+
+			counters := profile.counters[stage]
+			ret[index].Calls += atomic.LoadInt64(&counters.calls)
+			ret[index].Total += time.Duration(atomic.LoadInt64(&counters.nanoseconds))
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Total > ret[j].Total
+	})
+	return ret
+}
+
+// Reset sets every count back to zero, and starts the profile's duration again.
+func (profiler *Profiler) Reset() {
+
+	profiler.mutex.Lock()
+	profiler.started = time.Now()
+	profiler.mutex.Unlock()
+
+	for _, profile := range profiler.attached() {
+		for _, counters := range profile.counters {
+			atomic.StoreInt64(&counters.calls, 0)
+			atomic.StoreInt64(&counters.nanoseconds, 0)
+		}
+	}
+}
+
+// WriteTable writes the profile of each stage as a table, from the most time spent on it alone to the least;
+// followed by the profile of each function and accessor. Those which were never evaluated aren't included.
+func (profiler *Profiler) WriteTable(writer io.Writer) error {
+
+	var stages []StageProfile
+	for _, stage := range profiler.Stages() {
+		if stage.Calls > 0 {
+			stages = append(stages, stage)
+		}
+	}
+
+	sort.SliceStable(stages, func(i, j int) bool {
+		return stages[i].Self > stages[j].Self
+	})
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "Rule\tStage\tCalls\tTotal\tSelf")
+	for _, stage := range stages {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%v\t%v\n", stage.Rule, stage.Expression, stage.Calls, stage.Total, stage.Self)
+	}
+
+	var functions []FunctionProfile
+	for _, function := range profiler.Functions() {
+		if function.Calls > 0 {
+			functions = append(functions, function)
+		}
+	}
+
+	if len(functions) > 0 {
+
+		fmt.Fprintln(table)
+		fmt.Fprintln(table, "Function\tCalls\tTotal")
+
+		for _, function := range functions {
+
+			name := function.Name + "()"
+			if function.Accessor {
+				name = function.Name
+			}
+			fmt.Fprintf(table, "%s\t%d\t%v\n", name, function.Calls, function.Total)
+		}
+	}
+	return table.Flush()
+}
+
+// Returns the profiles of the expressions the profiler is attached to.
+func (profiler *Profiler) attached() []*expressionProfile {
+
+	profiler.mutex.Lock()
+	defer profiler.mutex.Unlock()
+
+	return append([]*expressionProfile(nil), profiler.profiles...)
+}
@@ -0,0 +1,328 @@
+package govaluate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const profiledDelay = time.Millisecond
+
+// Returns a profiler attached to two rules, each evaluated [times] times; one of which calls a slow function.
+func profiledRules(test *testing.T, times int) (*Profiler, *Expression, *Expression) {
+
+	options := ExpressionOptions{
+		FunctionDescriptors: StandardFunctionDescriptors(),
+		Functions: map[string]ExpressionFunction{
+			"slow": func(arguments ...interface{}) (interface{}, error) {
+				time.Sleep(profiledDelay)
+				return true, nil
+			},
+		},
+	}
+
+	eligibility, err := NewExpressionWithOptions("len(items) > 2 && slow() || foo.Int > 100", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	matches, err := NewExpressionWithOptions("any(items, # > 2)", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	profiler := NewProfiler()
+
+	err = eligibility.CollectProfile(profiler, "eligibility")
+	if err != nil {
+		test.Fatalf("Unable to collect a profile: %v", err)
+	}
+
+	err = matches.CollectProfile(profiler, "")
+	if err != nil {
+		test.Fatalf("Unable to collect a profile: %v", err)
+	}
+
+	for i := 0; i < times; i++ {
+
+		// the slow function is only called when there are enough items.
+		items := []interface{}{1.0, 2.0, 3.0}
+		if i%2 == 1 {
+			items = items[:1]
+		}
+
+		eligibility.Evaluate(map[string]interface{}{"items": items, "foo": dummyParameterInstance})
+		matches.Evaluate(map[string]interface{}{"items": items})
+	}
+	return profiler, eligibility, matches
+}
+
+func TestProfiler(test *testing.T) {
+
+	profiler, _, _ := profiledRules(test, 4)
+
+	expected := []StageProfile{
+		{Rule: "eligibility", Expression: "len(items) > 2 && slow() || foo.Int > 100", Offset: 0, Calls: 4},
+		{Rule: "eligibility", Expression: "len(items) > 2 && slow()", Offset: 0, Calls: 4},
+		{Rule: "eligibility", Expression: "len(items) > 2", Offset: 0, Calls: 4},
+		{Rule: "eligibility", Expression: "len(items)", Offset: 0, Calls: 4},
+		{Rule: "eligibility", Expression: "items", Offset: 4, Calls: 4},
+		{Rule: "eligibility", Expression: "slow()", Offset: 18, Calls: 2},
+		{Rule: "eligibility", Expression: "foo.Int > 100", Offset: 28, Calls: 2},
+		{Rule: "eligibility", Expression: "foo.Int", Offset: 28, Calls: 2},
+		{Rule: "any(items, # > 2)", Expression: "any(items, # > 2)", Offset: 0, Calls: 4},
+		{Rule: "any(items, # > 2)", Expression: "items", Offset: 4, Calls: 4},
+		{Rule: "any(items, # > 2)", Expression: "# > 2", Offset: 11, Calls: 8},
+		{Rule: "any(items, # > 2)", Expression: "#", Offset: 11, Calls: 8},
+	}
+
+	stages := profiler.Stages()
+	if len(stages) != len(expected) {
+		test.Fatalf("Expected %d stages, got %v", len(expected), stages)
+	}
+
+	for i, stage := range stages {
+
+		actual := stage
+		actual.Total = 0
+		actual.Self = 0
+
+		if actual != expected[i] {
+			test.Errorf("Expected %v, got %v", expected[i], actual)
+		}
+
+		if stage.Self > stage.Total || (stage.Calls > 0 && stage.Total <= 0) {
+			test.Errorf("Expected the time spent on '%s' alone to be within its total, got %v and %v", stage.Expression, stage.Self, stage.Total)
+		}
+	}
+
+	// the slow function is where the time is spent, and is included in the time of the stages around it.
+	slow := stages[5]
+	if slow.Self < 2*profiledDelay {
+		test.Errorf("Expected the slow function to take at least %v, got %v", 2*profiledDelay, slow.Self)
+	}
+
+	for _, stage := range stages[:2] {
+		if stage.Total < slow.Total {
+			test.Errorf("Expected '%s' to include the time of the slow function, got %v", stage.Expression, stage.Total)
+		}
+	}
+
+	functions := profiler.Functions()
+	if len(functions) != 4 || functions[0].Name != "slow" || functions[0].Calls != 2 || functions[0].Total != slow.Total {
+		test.Errorf("Expected the slow function to take the most time, got %v", functions)
+	}
+
+	for _, function := range functions {
+		if function.Name == "foo.Int" && (!function.Accessor || function.Calls != 2) {
+			test.Errorf("Expected the accessor to be called twice, got %v", function)
+		}
+	}
+}
+
+func TestProfilerTable(test *testing.T) {
+
+	// the accessor isn't evaluated, since the slow function is true.
+	profiler, _, _ := profiledRules(test, 1)
+
+	var buffer bytes.Buffer
+
+	err := profiler.WriteTable(&buffer)
+	if err != nil {
+		test.Fatalf("Unable to write the table: %v", err)
+	}
+
+	lines := strings.Split(buffer.String(), "\n")
+	if strings.Join(strings.Fields(lines[0]), " ") != "Rule Stage Calls Total Self" {
+		test.Errorf("Expected a heading, got '%s'", lines[0])
+	}
+
+	// stages are ordered by the time spent on them alone.
+	if !strings.HasPrefix(lines[1], "eligibility ") || !strings.Contains(lines[1], " slow() ") {
+		test.Errorf("Expected the slow function first, got '%s'", lines[1])
+	}
+
+	// those which weren't evaluated are left out.
+	for _, line := range lines {
+		if strings.HasPrefix(line, "foo.Int ") || strings.Contains(line, "  foo.Int  ") {
+			test.Errorf("Expected the accessor not to be included, got\n%s", buffer.String())
+		}
+	}
+
+	if !strings.Contains(buffer.String(), "\nFunction  Calls  Total\nslow()    1      ") {
+		test.Errorf("Expected a table of functions, got\n%s", buffer.String())
+	}
+}
+
+func TestWriteProfile(test *testing.T) {
+
+	profiler, _, _ := profiledRules(test, 4)
+
+	var buffer bytes.Buffer
+
+	err := profiler.WriteProfile(&buffer)
+	if err != nil {
+		test.Fatalf("Unable to write the profile: %v", err)
+	}
+
+	reader, err := gzip.NewReader(&buffer)
+	if err != nil {
+		test.Fatalf("Expected a gzipped profile: %v", err)
+	}
+
+	profile, err := ioutil.ReadAll(reader)
+	if err != nil {
+		test.Fatalf("Expected a gzipped profile: %v", err)
+	}
+
+	var stringTable []string
+	var samples [][]byte
+	sampleTypes := 0
+
+	for _, field := range readProtoFields(test, profile) {
+		switch field.number {
+		case profileSampleType:
+			sampleTypes++
+		case profileSample:
+			samples = append(samples, field.bytes)
+		case profileStringTable:
+			stringTable = append(stringTable, string(field.bytes))
+		}
+	}
+
+	if sampleTypes != 2 || len(stringTable) == 0 || stringTable[0] != "" {
+		test.Fatalf("Expected two sample types and a string table, got %d and %v", sampleTypes, stringTable)
+	}
+
+	for _, expected := range []string{"calls", "count", "time", "nanoseconds", "eligibility", "slow()", "any(items, # > 2)"} {
+		if !containsString(stringTable, expected) {
+			test.Errorf("Expected '%s' in the string table, got %v", expected, stringTable)
+		}
+	}
+
+	// each stage which was evaluated is a sample, including the slow function; within two stages, and its rule.
+	if len(samples) != 12 {
+		test.Fatalf("Expected 12 samples, got %d", len(samples))
+	}
+
+	slow := readProtoFields(test, samples[5])
+	stack := readPackedVarints(test, slow[0].bytes)
+	values := readPackedVarints(test, slow[1].bytes)
+
+	if len(stack) != 4 || len(values) != 2 || values[0] != 2 || time.Duration(values[1]) < 2*profiledDelay {
+		test.Errorf("Expected the slow function to be called twice, within three stages, got %v and %v", stack, values)
+	}
+}
+
+func TestConcurrentProfiling(test *testing.T) {
+
+	expression, _ := NewExpression("a && b")
+	profiler := NewProfiler()
+
+	err := expression.CollectProfile(profiler, "rule")
+	if err != nil {
+		test.Fatalf("Unable to collect a profile: %v", err)
+	}
+
+	var group sync.WaitGroup
+	for i := 0; i < 8; i++ {
+
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			for j := 0; j < 100; j++ {
+				expression.EvalContext(context.Background(), MapParameters{"a": true, "b": false})
+			}
+		}()
+	}
+	group.Wait()
+
+	for _, stage := range profiler.Stages() {
+		if stage.Calls != 800 {
+			test.Errorf("Expected '%s' to be evaluated 800 times, got %d", stage.Expression, stage.Calls)
+		}
+	}
+
+	profiler.Reset()
+	for _, stage := range profiler.Stages() {
+		if stage.Calls != 0 || stage.Total != 0 {
+			test.Errorf("Expected the profile to be reset, got %v", stage)
+		}
+	}
+}
+
+type protoField struct {
+	number int
+	value  uint64
+	bytes  []byte
+}
+
+// Reads the fields of a message in protocol buffers' wire format, which are either varints or delimited.
+func readProtoFields(test *testing.T, message []byte) []protoField {
+
+	var ret []protoField
+
+	for len(message) > 0 {
+
+		var key uint64
+		key, message = readVarint(test, message)
+
+		field := protoField{number: int(key >> 3)}
+
+		switch key & 7 {
+		case protoVarintType:
+			field.value, message = readVarint(test, message)
+		case protoDelimitedType:
+			var length uint64
+			length, message = readVarint(test, message)
+			field.bytes, message = message[:length], message[length:]
+		default:
+			test.Fatalf("Unexpected wire type %d", key&7)
+		}
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+func readPackedVarints(test *testing.T, packed []byte) []uint64 {
+
+	var ret []uint64
+	for len(packed) > 0 {
+
+		var value uint64
+		value, packed = readVarint(test, packed)
+		ret = append(ret, value)
+	}
+	return ret
+}
+
+func readVarint(test *testing.T, data []byte) (uint64, []byte) {
+
+	var ret uint64
+	for i, b := range data {
+
+		ret |= uint64(b&0x7f) << (7 * uint(i))
+		if b < 0x80 {
+			return ret, data[i+1:]
+		}
+	}
+
+	test.Fatalf("Unterminated varint")
+	return 0, nil
+}
+
+func containsString(strs []string, str string) bool {
+
+	for _, candidate := range strs {
+		if candidate == str {
+			return true
+		}
+	}
+	return false
+}
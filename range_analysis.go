@@ -0,0 +1,1025 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+)
+
+// Interval is the range of numbers from Min to Max, inclusive. Either may be infinite, for a range without that bound.
+type Interval struct {
+	Min float64
+	Max float64
+}
+
+func (interval Interval) String() string {
+	return fmt.Sprintf("[%v, %v]", interval.Min, interval.Max)
+}
+
+// Contains returns true if [value] is within the interval.
+func (interval Interval) Contains(value float64) bool {
+	return interval.Min <= value && value <= interval.Max
+}
+
+// HazardKind is a kind of problem which evaluating an expression may run into, as found by [Expression.AnalyzeRange].
+type HazardKind int
+
+const (
+	// DivisionByZero means that a divisor ("/" or "%"), or the base of a negative exponent, may be zero.
+	DivisionByZero HazardKind = iota
+
+	// NotANumber means that a result may be NaN, even when none of the operands are; as with "0 / 0", or "-1 ** 0.5".
+	NotANumber
+)
+
+func (kind HazardKind) String() string {
+
+	switch kind {
+	case DivisionByZero:
+		return "division by zero"
+	case NotANumber:
+		return "NaN"
+	}
+	return fmt.Sprintf("HazardKind(%d)", int(kind))
+}
+
+// RangeHazard is a part of an expression which may run into a problem when evaluated.
+type RangeHazard struct {
+	Kind HazardKind
+
+	// Expression is the text of the part of the expression which may run into the problem, such as "a / b".
+	Expression string
+}
+
+// RangeAnalysis describes the values that an expression may produce, as found by [Expression.AnalyzeRange].
+type RangeAnalysis struct {
+
+	// Range holds every number the expression may produce, or is nil if it can't produce a number.
+	Range *Interval
+
+	// MayBeNaN is true if the expression may produce NaN.
+	MayBeNaN bool
+
+	// MayBeTrue and MayBeFalse are true if the expression may produce either bool.
+	MayBeTrue  bool
+	MayBeFalse bool
+
+	// MayBeNull is true if the expression may produce null.
+	MayBeNull bool
+
+	// MayBeOther is true if the expression may produce anything else, such as a string or an array, or something which isn't known.
+	MayBeOther bool
+
+	// Hazards are the parts of the expression which may divide by zero, or produce NaN, in the order they're found.
+	Hazards []RangeHazard
+}
+
+// AnalyzeRange finds the values that the expression may produce, given the values that each parameter may have.
+// Those are given by [constraints], keyed by the parameter's name; a number's Minimum and Maximum give its range.
+// Parameters without a constraint may be anything.
+//
+// Ranges of numbers are propagated through arithmetic, ternaries, comparisons, and "&&" and "||",
+// as well as the standard math functions (see [StandardFunctionDescriptors]); functions named "min", "max", "abs",
+// "floor", "ceil", "trunc", "round", "sqrt", and "pow" are assumed to be them, whether or not they're given a descriptor.
+// Comparisons of a parameter with a literal narrow the parameter's range where they apply,
+// so in "x > 0 ? 100 / x : 0", "x" is known to be positive when it's divided by.
+// Other functions are assumed to produce anything of their ReturnType (see [FunctionDescriptor]).
+// Numbers given by a constraint, or by a function, are assumed to be finite, and not NaN.
+//
+// The results hold every value that the expression may produce, but may also hold some that it can't;
+// for instance, "x - x" is found to be anywhere within [-10, 10], for "x" within [0, 10].
+// Likewise, hazards are possible problems, which may not actually occur.
+//
+// Fails if any of the [constraints] are invalid (see [Expression.CheckSatisfiability]).
+func (expr Expression) AnalyzeRange(constraints map[string]VariableConstraint) (RangeAnalysis, error) {
+
+	var ret RangeAnalysis
+
+	for name, constraint := range constraints {
+		err := constraint.validate(name)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	analyzer := &rangeAnalyzer{
+		expr:        expr,
+		constraints: constraints,
+		found:       make(map[RangeHazard]bool),
+	}
+
+	result := analyzer.analyze(expr.evaluationStages, nil)
+	if expr.evaluationStages == nil {
+		result = abstractValue{null: true}
+	}
+
+	ret.Range = result.numbers
+	ret.MayBeNaN = result.nan
+	ret.MayBeTrue = result.canBeTrue
+	ret.MayBeFalse = result.canBeFalse
+	ret.MayBeNull = result.null
+	ret.MayBeOther = result.other
+	ret.Hazards = analyzer.hazards
+	return ret, nil
+}
+
+// What's known about the values a stage may produce.
+type abstractValue struct {
+
+	// the numbers it may produce, if any.
+	numbers *Interval
+
+	// true if those numbers can't be zero, even if the interval includes it; as for "x" once "x != 0".
+	nonZero bool
+
+	// true if those numbers may be infinite, rather than only unbounded; as for "1 / x", where "x" may be zero.
+	infinite bool
+
+	nan        bool
+	canBeTrue  bool
+	canBeFalse bool
+	null       bool
+
+	// true if it may produce anything else, like a string.
+	other bool
+}
+
+// The values of parameters which are narrowed by the conditions that apply, such as "x" in "x > 0 && 1 / x > 2".
+type rangeEnvironment map[string]abstractValue
+
+// Propagates ranges through a tree of stages.
+type rangeAnalyzer struct {
+	expr        Expression
+	constraints map[string]VariableConstraint
+
+	hazards []RangeHazard
+	found   map[RangeHazard]bool
+}
+
+// Returns what's known about the values that [stage] produces, when its parameters are within [environment].
+func (analyzer *rangeAnalyzer) analyze(stage *evaluationStage, environment rangeEnvironment) abstractValue {
+
+	if stage == nil {
+		return abstractValue{}
+	}
+
+	switch stage.symbol {
+
+	case literal:
+		return abstractValueOf(literalValueOf(stage))
+
+	case value:
+		return analyzer.parameterValue(stage.name, environment)
+
+	case noopSymbol:
+		return analyzer.analyze(stage.rightStage, environment)
+
+	case and, or:
+		return analyzer.analyzeLogical(stage, environment)
+
+	case ternaryTrue:
+		return analyzer.analyzeTernaryTrue(stage, environment)
+
+	case ternaryFalse, coalesce:
+		return analyzer.analyzeCoalesce(stage, environment)
+
+	case functional:
+		return analyzer.analyzeFunction(stage, environment)
+
+	case collectionFunctional:
+		analyzer.analyze(stage.leftStage, environment)
+		analyzer.analyze(stage.rightStage, environment.with(stage.collection.parameterName, unknownAbstractValue()))
+		return unknownAbstractValue()
+	}
+
+	left := analyzer.analyze(stage.leftStage, environment)
+	right := analyzer.analyze(stage.rightStage, environment)
+
+	switch stage.symbol {
+
+	case negate:
+		ret := abstractValue{nan: right.nan, nonZero: right.nonZero, infinite: right.infinite}
+		if right.numbers != nil {
+			ret.numbers = &Interval{-right.numbers.Max, -right.numbers.Min}
+		}
+		return ret
+
+	case invert:
+		return abstractValue{canBeTrue: right.canBeFalse, canBeFalse: right.canBeTrue, null: right.null && analyzer.expr.options.ThreeValuedLogic}
+
+	case plus, minus, multiply, divide, modulus, exponent:
+		return analyzer.analyzeArithmetic(stage, left, right)
+
+	case bitwiseAnd, bitwiseOr, bitwiseXor, bitwiseLshift, bitwiseRshift, bitwiseNot:
+		return analyzer.numericResult(left, right, stage.symbol == bitwiseNot, func(Interval, Interval) Interval {
+			return Interval{math.Inf(-1), math.Inf(1)}
+		})
+
+	case gt, lt, gte, lte, eq, neq:
+		return analyzer.analyzeComparison(stage.symbol, left, right)
+
+	case between, notBetween:
+		return analyzer.analyzeBetween(stage, left, environment)
+
+	case is:
+		return abstractValue{canBeTrue: left.null, canBeFalse: left.hasNonNull()}
+
+	case isNot:
+		return abstractValue{canBeTrue: left.hasNonNull(), canBeFalse: left.null}
+
+	case in, notIn, req, nreq, like, notLike, ilike, notIlike, glob, notGlob, xor:
+		return analyzer.boolResult(left, right)
+	}
+
+	// arrays, maps, accessors, and anything else which isn't a number or bool.
+	return unknownAbstractValue()
+}
+
+// Returns the value of the named parameter; as narrowed by the [environment], or as constrained.
+func (analyzer *rangeAnalyzer) parameterValue(name string, environment rangeEnvironment) abstractValue {
+
+	narrowed, found := environment[name]
+	if found {
+		return narrowed
+	}
+
+	bound, isBound := analyzer.expr.boundParameters[name]
+	if isBound {
+		return abstractValueOf(bound)
+	}
+
+	constraint, isConstrained := analyzer.constraints[name]
+	if !isConstrained {
+		return unknownAbstractValue()
+	}
+
+	ret := abstractValue{null: constraint.Nullable}
+
+	switch constraint.Type {
+	case NumberType:
+		interval := Interval{math.Inf(-1), math.Inf(1)}
+		if constraint.Minimum != nil {
+			interval.Min = *constraint.Minimum
+		}
+		if constraint.Maximum != nil {
+			interval.Max = *constraint.Maximum
+		}
+		ret.numbers = &interval
+		ret.nonZero = !interval.Contains(0)
+	case StringType:
+		ret.other = true
+	case BoolType:
+		ret.canBeTrue, ret.canBeFalse = true, true
+	default:
+		ret = unknownAbstractValue()
+	}
+	return ret
+}
+
+func (analyzer *rangeAnalyzer) analyzeLogical(stage *evaluationStage, environment rangeEnvironment) abstractValue {
+
+	left := analyzer.analyze(stage.leftStage, environment)
+
+	// the right side is only evaluated if the left doesn't decide the result; "false" for "&&", or "true" for "||".
+	continuing := stage.symbol == and
+	right := abstractValue{}
+
+	if (continuing && (left.canBeTrue || left.null)) || (!continuing && (left.canBeFalse || left.null)) {
+
+		narrowed, reachable := analyzer.narrow(stage.leftStage, environment, continuing)
+		if reachable {
+			right = analyzer.analyze(stage.rightStage, narrowed)
+		}
+	}
+
+	ret := abstractValue{}
+	if continuing {
+		ret.canBeFalse = left.canBeFalse || right.canBeFalse
+		ret.canBeTrue = left.canBeTrue && right.canBeTrue
+	} else {
+		ret.canBeTrue = left.canBeTrue || right.canBeTrue
+		ret.canBeFalse = left.canBeFalse && right.canBeFalse
+	}
+	ret.null = analyzer.expr.options.ThreeValuedLogic && (left.null || right.null)
+	return ret
+}
+
+// A ternary's true stage produces its value if the condition is true, and null otherwise.
+func (analyzer *rangeAnalyzer) analyzeTernaryTrue(stage *evaluationStage, environment rangeEnvironment) abstractValue {
+
+	ret, _, _ := analyzer.analyzeTernary(stage, environment)
+	return ret
+}
+
+// Returns what a ternary's true stage produces, along with what its condition and its value do.
+func (analyzer *rangeAnalyzer) analyzeTernary(stage *evaluationStage, environment rangeEnvironment) (abstractValue, abstractValue, abstractValue) {
+
+	condition := analyzer.analyze(stage.leftStage, environment)
+	trueValue := abstractValue{}
+
+	if condition.canBeTrue {
+
+		narrowed, reachable := analyzer.narrow(stage.leftStage, environment, true)
+		if reachable {
+			trueValue = analyzer.analyze(stage.rightStage, narrowed)
+		}
+	}
+
+	ret := trueValue
+	ret.null = ret.null || condition.canBeFalse || condition.null
+	return ret, condition, trueValue
+}
+
+// A ternary's false stage produces whatever its true stage does, unless that's null; as does "??".
+func (analyzer *rangeAnalyzer) analyzeCoalesce(stage *evaluationStage, environment rangeEnvironment) abstractValue {
+
+	var left abstractValue
+
+	if stage.symbol == ternaryFalse && stage.leftStage != nil && stage.leftStage.symbol == ternaryTrue {
+
+		var condition, trueValue abstractValue
+		left, condition, trueValue = analyzer.analyzeTernary(stage.leftStage, environment)
+
+		// the false value is used when the condition is false; unless the true value may be null, in which case it's used then too.
+		if !trueValue.null {
+
+			if !condition.canBeFalse && !condition.null {
+				left.null = false
+			}
+
+			narrowed, reachable := analyzer.narrow(stage.leftStage.leftStage, environment, false)
+			if !reachable {
+				left.null = false
+			}
+			environment = narrowed
+		}
+	} else {
+		left = analyzer.analyze(stage.leftStage, environment)
+	}
+
+	ret := left
+	ret.null = false
+
+	if !left.null {
+		return ret
+	}
+	return ret.union(analyzer.analyze(stage.rightStage, environment))
+}
+
+func (analyzer *rangeAnalyzer) analyzeArithmetic(stage *evaluationStage, left abstractValue, right abstractValue) abstractValue {
+
+	// "+" concatenates strings.
+	if stage.symbol == plus && (left.other || right.other) {
+
+		ret := analyzer.numericResult(left, right, false, addIntervals)
+		ret.other = true
+		return ret
+	}
+
+	if left.numbers == nil || right.numbers == nil {
+		return abstractValue{null: analyzer.expr.options.ThreeValuedLogic && (left.null || right.null)}
+	}
+
+	leftMayBeZero := left.numbers.Contains(0) && !left.nonZero
+	rightMayBeZero := right.numbers.Contains(0) && !right.nonZero
+
+	switch stage.symbol {
+
+	case plus, minus:
+		operation := addIntervals
+		if stage.symbol == minus {
+			operation = subtractIntervals
+		}
+
+		// opposite infinities cancel out to NaN.
+		ret := analyzer.numericResult(left, right, false, operation)
+		if left.infinite && right.infinite {
+			analyzer.reportNaN(stage, &ret)
+		}
+		return ret
+
+	case multiply:
+		ret := analyzer.numericResult(left, right, false, multiplyIntervals)
+		ret.nonZero = left.nonZero && right.nonZero
+
+		if (left.infinite && rightMayBeZero) || (right.infinite && leftMayBeZero) {
+			analyzer.reportNaN(stage, &ret)
+		}
+		return ret
+
+	case divide:
+		ret := analyzer.numericResult(left, right, false, func(dividend Interval, divisor Interval) Interval {
+			return multiplyIntervals(dividend, reciprocalInterval(divisor))
+		})
+		ret.nonZero = left.nonZero
+
+		if rightMayBeZero {
+			analyzer.report(DivisionByZero, stage)
+			ret.infinite = true
+		}
+
+		if (rightMayBeZero && leftMayBeZero) || (left.infinite && right.infinite) {
+			analyzer.reportNaN(stage, &ret)
+		}
+		return ret
+
+	case modulus:
+		ret := analyzer.numericResult(left, right, false, modulusInterval)
+		ret.infinite = false
+
+		if rightMayBeZero {
+			analyzer.report(DivisionByZero, stage)
+		}
+
+		if rightMayBeZero || left.infinite {
+			analyzer.reportNaN(stage, &ret)
+		}
+		return ret
+
+	case exponent:
+		return analyzer.analyzeExponent(stage, left, right)
+	}
+	return unknownAbstractValue()
+}
+
+func (analyzer *rangeAnalyzer) analyzeExponent(stage *evaluationStage, base abstractValue, power abstractValue) abstractValue {
+
+	ret := analyzer.numericResult(base, power, false, exponentInterval)
+
+	// a negative base can only be raised to a whole number.
+	isWhole := power.numbers.Min == power.numbers.Max && power.numbers.Min == math.Trunc(power.numbers.Min)
+	if base.numbers.Min < 0 && !isWhole {
+		analyzer.reportNaN(stage, &ret)
+	}
+
+	if base.numbers.Contains(0) && !base.nonZero && power.numbers.Min < 0 {
+		analyzer.report(DivisionByZero, stage)
+		ret.infinite = true
+	}
+	return ret
+}
+
+// Returns the result of an operator on numbers, given the interval of its result; or of a unary operator (on only the right side), if [unary].
+func (analyzer *rangeAnalyzer) numericResult(left abstractValue, right abstractValue, unary bool, operation func(Interval, Interval) Interval) abstractValue {
+
+	ret := abstractValue{
+		nan:      right.nan || (!unary && left.nan),
+		infinite: right.infinite || (!unary && left.infinite),
+		null:     analyzer.expr.options.ThreeValuedLogic && (right.null || (!unary && left.null)),
+	}
+
+	if right.numbers == nil || (!unary && left.numbers == nil) {
+		return ret
+	}
+
+	leftNumbers := Interval{}
+	if !unary {
+		leftNumbers = *left.numbers
+	}
+
+	interval := operation(leftNumbers, *right.numbers)
+	ret.numbers = &interval
+	return ret
+}
+
+func (analyzer *rangeAnalyzer) analyzeComparison(symbol OperatorSymbol, left abstractValue, right abstractValue) abstractValue {
+
+	ret := analyzer.boolResult(left, right)
+
+	// only numbers can be compared by their intervals.
+	if left.numbers == nil || right.numbers == nil || left.hasOtherThanNumbers() || right.hasOtherThanNumbers() || left.nan || right.nan {
+		return ret
+	}
+
+	l, r := *left.numbers, *right.numbers
+
+	switch symbol {
+	case gt:
+		ret.canBeTrue, ret.canBeFalse = l.Max > r.Min, l.Min <= r.Max
+	case lt:
+		ret.canBeTrue, ret.canBeFalse = l.Min < r.Max, l.Max >= r.Min
+	case gte:
+		ret.canBeTrue, ret.canBeFalse = l.Max >= r.Min, l.Min < r.Max
+	case lte:
+		ret.canBeTrue, ret.canBeFalse = l.Min <= r.Max, l.Max > r.Min
+	case eq, neq:
+		overlaps := l.Min <= r.Max && r.Min <= l.Max
+		same := l.Min == l.Max && r.Min == r.Max && l.Min == r.Min
+
+		ret.canBeTrue, ret.canBeFalse = overlaps, !same
+		if symbol == neq {
+			ret.canBeTrue, ret.canBeFalse = ret.canBeFalse, ret.canBeTrue
+		}
+	}
+	return ret
+}
+
+func (analyzer *rangeAnalyzer) analyzeBetween(stage *evaluationStage, left abstractValue, environment rangeEnvironment) abstractValue {
+
+	lower := analyzer.analyze(stage.rightStage.leftStage, environment)
+	upper := analyzer.analyze(stage.rightStage.rightStage, environment)
+
+	ret := analyzer.boolResult(left, lower)
+	ret.null = ret.null || (analyzer.expr.options.ThreeValuedLogic && upper.null)
+
+	if left.numbers == nil || lower.numbers == nil || upper.numbers == nil ||
+		left.hasOtherThanNumbers() || lower.hasOtherThanNumbers() || upper.hasOtherThanNumbers() || left.nan || lower.nan || upper.nan {
+		return ret
+	}
+
+	value := *left.numbers
+	ret.canBeTrue = value.Max >= lower.numbers.Min && value.Min <= upper.numbers.Max && lower.numbers.Min <= upper.numbers.Max
+	ret.canBeFalse = value.Min < lower.numbers.Max || value.Max > upper.numbers.Min
+
+	if stage.symbol == notBetween {
+		ret.canBeTrue, ret.canBeFalse = ret.canBeFalse, ret.canBeTrue
+	}
+	return ret
+}
+
+// Returns the result of an operator which produces either bool; or null, if either operand is null with three-valued logic.
+func (analyzer *rangeAnalyzer) boolResult(left abstractValue, right abstractValue) abstractValue {
+
+	return abstractValue{
+		canBeTrue:  true,
+		canBeFalse: true,
+		null:       analyzer.expr.options.ThreeValuedLogic && (left.null || right.null),
+	}
+}
+
+func (analyzer *rangeAnalyzer) analyzeFunction(stage *evaluationStage, environment rangeEnvironment) abstractValue {
+
+	var arguments []abstractValue
+	for _, argument := range argumentStages(stage.rightStage) {
+		arguments = append(arguments, analyzer.analyze(argument, environment))
+	}
+
+	// functions without a descriptor are only known by name; those named like the standard math functions are assumed to be them.
+	returnType := AnyType
+	if stage.function != nil {
+		returnType = stage.function.returnType
+	} else if rangeFunctionNames[stage.name] {
+		returnType = NumberType
+	}
+
+	numeric := len(arguments) > 0
+	for _, argument := range arguments {
+		numeric = numeric && argument.numbers != nil
+	}
+
+	if numeric && returnType == NumberType {
+
+		first := *arguments[0].numbers
+		ret := abstractValue{numbers: &Interval{}}
+
+		for _, argument := range arguments {
+			ret.nan = ret.nan || argument.nan
+			ret.infinite = ret.infinite || argument.infinite
+		}
+
+		switch stage.name {
+
+		case "min", "max":
+			if len(arguments) == 1 {
+				break
+			}
+
+			*ret.numbers = first
+
+			// NaN is never less (or greater) than anything, so the result may be any of the others.
+			if ret.nan {
+				for _, argument := range arguments[1:] {
+					ret.numbers = ret.union(argument).numbers
+				}
+				return ret
+			}
+
+			for _, argument := range arguments[1:] {
+				if stage.name == "min" {
+					*ret.numbers = Interval{math.Min(ret.numbers.Min, argument.numbers.Min), math.Min(ret.numbers.Max, argument.numbers.Max)}
+				} else {
+					*ret.numbers = Interval{math.Max(ret.numbers.Min, argument.numbers.Min), math.Max(ret.numbers.Max, argument.numbers.Max)}
+				}
+			}
+			return ret
+
+		case "abs":
+			*ret.numbers = absInterval(first)
+			return ret
+
+		case "floor", "ceil", "trunc", "round":
+			// these only ever increase with their argument; except for rounding to a given number of digits, which isn't known.
+			if len(arguments) == 1 {
+				rounding := map[string]func(float64) float64{"floor": math.Floor, "ceil": math.Ceil, "trunc": math.Trunc, "round": math.Round}[stage.name]
+				*ret.numbers = Interval{rounding(first.Min), rounding(first.Max)}
+				return ret
+			}
+
+		case "sqrt":
+			// negative numbers fail, rather than producing NaN.
+			*ret.numbers = Interval{math.Sqrt(math.Max(first.Min, 0)), math.Sqrt(math.Max(first.Max, 0))}
+			return ret
+
+		case "pow":
+			if len(arguments) == 2 {
+				return analyzer.analyzeExponent(stage, arguments[0], arguments[1])
+			}
+		}
+	}
+
+	switch returnType {
+	case NumberType:
+		return abstractValue{numbers: &Interval{math.Inf(-1), math.Inf(1)}}
+	case BoolType:
+		return abstractValue{canBeTrue: true, canBeFalse: true}
+	case StringType, ArrayType, MapType:
+		return abstractValue{other: true}
+	}
+	return unknownAbstractValue()
+}
+
+// the math functions whose ranges are known, which functions of the same names are assumed to be.
+var rangeFunctionNames = map[string]bool{
+	"min":   true,
+	"max":   true,
+	"abs":   true,
+	"floor": true,
+	"ceil":  true,
+	"trunc": true,
+	"round": true,
+	"sqrt":  true,
+	"pow":   true,
+}
+
+// Returns the stages of each argument given to a function, from the stage of its arguments.
+func argumentStages(stage *evaluationStage) []*evaluationStage {
+
+	if stage == nil {
+		return nil
+	}
+
+	switch stage.symbol {
+	case noopSymbol:
+		return argumentStages(stage.rightStage)
+	case separate:
+		return append(argumentStages(stage.leftStage), argumentStages(stage.rightStage)...)
+	}
+	return []*evaluationStage{stage}
+}
+
+// Returns the [environment] in which [condition] has the given [outcome], as far as it's known;
+// along with false if it can't have that outcome.
+func (analyzer *rangeAnalyzer) narrow(condition *evaluationStage, environment rangeEnvironment, outcome bool) (rangeEnvironment, bool) {
+
+	if condition == nil {
+		return environment, true
+	}
+
+	switch condition.symbol {
+
+	case noopSymbol:
+		return analyzer.narrow(condition.rightStage, environment, outcome)
+
+	case invert:
+		return analyzer.narrow(condition.rightStage, environment, !outcome)
+
+	case and, or:
+		// both sides are true for "&&" to be true, and both false for "||" to be false.
+		if outcome != (condition.symbol == and) {
+			return environment, true
+		}
+
+		narrowed, reachable := analyzer.narrow(condition.leftStage, environment, outcome)
+		if !reachable {
+			return nil, false
+		}
+		return analyzer.narrow(condition.rightStage, narrowed, outcome)
+
+	case is, isNot:
+		if condition.leftStage == nil || condition.leftStage.symbol != value {
+			return environment, true
+		}
+
+		name := condition.leftStage.name
+		current := analyzer.parameterValue(name, environment)
+
+		if outcome == (condition.symbol == is) {
+			if !current.null {
+				return nil, false
+			}
+			return environment.with(name, abstractValue{null: true}), true
+		}
+
+		current.null = false
+		return environment.with(name, current), true
+
+	case gt, lt, gte, lte, eq, neq:
+		return analyzer.narrowComparison(condition, environment, outcome)
+	}
+	return environment, true
+}
+
+// Narrows the parameter of a comparison with a number, such as "x > 0".
+func (analyzer *rangeAnalyzer) narrowComparison(condition *evaluationStage, environment rangeEnvironment, outcome bool) (rangeEnvironment, bool) {
+
+	symbol := condition.symbol
+	parameter, constant := condition.leftStage, condition.rightStage
+
+	// "0 < x" is the same as "x > 0".
+	if parameter != nil && parameter.symbol == literal {
+		parameter, constant = constant, parameter
+		symbol = mirroredComparators[symbol]
+	}
+
+	if parameter == nil || constant == nil || parameter.symbol != value || constant.symbol != literal {
+		return environment, true
+	}
+
+	limit, isNumber := literalValueOf(constant).(float64)
+	if !isNumber || math.IsNaN(limit) {
+		return environment, true
+	}
+
+	if !outcome {
+		symbol = complementaryComparators[symbol]
+	}
+
+	current := analyzer.parameterValue(parameter.name, environment)
+	if current.numbers == nil {
+		return nil, false
+	}
+
+	// the comparison only has an outcome if the parameter is a number.
+	narrowed := abstractValue{numbers: &Interval{current.numbers.Min, current.numbers.Max}, nonZero: current.nonZero}
+	interval := narrowed.numbers
+
+	switch symbol {
+	case gt, gte:
+		interval.Min = math.Max(interval.Min, limit)
+		narrowed.nonZero = narrowed.nonZero || limit > 0 || (symbol == gt && limit == 0)
+		if symbol == gt && interval.Max <= limit {
+			return nil, false
+		}
+	case lt, lte:
+		interval.Max = math.Min(interval.Max, limit)
+		narrowed.nonZero = narrowed.nonZero || limit < 0 || (symbol == lt && limit == 0)
+		if symbol == lt && interval.Min >= limit {
+			return nil, false
+		}
+	case eq:
+		if !interval.Contains(limit) || (limit == 0 && current.nonZero) {
+			return nil, false
+		}
+		*interval = Interval{limit, limit}
+		narrowed.nonZero = limit != 0
+	case neq:
+		if interval.Min == limit && interval.Max == limit {
+			return nil, false
+		}
+		narrowed.nonZero = narrowed.nonZero || limit == 0
+	}
+
+	if interval.Min > interval.Max {
+		return nil, false
+	}
+	return environment.with(parameter.name, narrowed), true
+}
+
+// the comparators which give the same result with their operands swapped; "a < b" is "b > a".
+var mirroredComparators = map[OperatorSymbol]OperatorSymbol{
+	gt:  lt,
+	lt:  gt,
+	gte: lte,
+	lte: gte,
+	eq:  eq,
+	neq: neq,
+}
+
+// the comparators which give the opposite result (for numbers other than NaN); "a > b" is "!(a <= b)".
+var complementaryComparators = map[OperatorSymbol]OperatorSymbol{
+	gt:  lte,
+	lte: gt,
+	lt:  gte,
+	gte: lt,
+	eq:  neq,
+	neq: eq,
+}
+
+// Records that [stage] may produce NaN, as [result] does.
+func (analyzer *rangeAnalyzer) reportNaN(stage *evaluationStage, result *abstractValue) {
+
+	analyzer.report(NotANumber, stage)
+	result.nan = true
+}
+
+// Records that [stage] may run into the given kind of problem.
+func (analyzer *rangeAnalyzer) report(kind HazardKind, stage *evaluationStage) {
+
+	text, err := formatStage(stage, analyzer.expr.options)
+	if err != nil {
+		text = stage.symbol.String()
+	}
+
+	hazard := RangeHazard{Kind: kind, Expression: text}
+	if !analyzer.found[hazard] {
+		analyzer.found[hazard] = true
+		analyzer.hazards = append(analyzer.hazards, hazard)
+	}
+}
+
+// Returns a copy of [environment] in which [name] has the given [value].
+func (environment rangeEnvironment) with(name string, value abstractValue) rangeEnvironment {
+
+	ret := rangeEnvironment{name: value}
+	for other, otherValue := range environment {
+		if other != name {
+			ret[other] = otherValue
+		}
+	}
+	return ret
+}
+
+func abstractValueOf(value interface{}) abstractValue {
+
+	switch typed := castToFloat64(value).(type) {
+	case float64:
+		if math.IsNaN(typed) {
+			return abstractValue{nan: true}
+		}
+		return abstractValue{numbers: &Interval{typed, typed}, nonZero: typed != 0, infinite: math.IsInf(typed, 0)}
+	case bool:
+		return abstractValue{canBeTrue: typed, canBeFalse: !typed}
+	case nil:
+		return abstractValue{null: true}
+	}
+	return abstractValue{other: true}
+}
+
+// Returns a value which may be anything.
+func unknownAbstractValue() abstractValue {
+
+	return abstractValue{
+		numbers:    &Interval{math.Inf(-1), math.Inf(1)},
+		infinite:   true,
+		nan:        true,
+		canBeTrue:  true,
+		canBeFalse: true,
+		null:       true,
+		other:      true,
+	}
+}
+
+// Returns the value which may be anything that either [value] or [other] may be.
+func (value abstractValue) union(other abstractValue) abstractValue {
+
+	ret := abstractValue{
+		numbers:    value.numbers,
+		nonZero:    value.nonZero || value.numbers == nil,
+		infinite:   value.infinite || other.infinite,
+		nan:        value.nan || other.nan,
+		canBeTrue:  value.canBeTrue || other.canBeTrue,
+		canBeFalse: value.canBeFalse || other.canBeFalse,
+		null:       value.null || other.null,
+		other:      value.other || other.other,
+	}
+
+	if other.numbers != nil {
+		ret.nonZero = ret.nonZero && other.nonZero
+
+		if ret.numbers == nil {
+			ret.numbers = other.numbers
+		} else {
+			ret.numbers = &Interval{math.Min(ret.numbers.Min, other.numbers.Min), math.Max(ret.numbers.Max, other.numbers.Max)}
+		}
+	}
+	return ret
+}
+
+func (value abstractValue) hasNonNull() bool {
+	return value.numbers != nil || value.nan || value.canBeTrue || value.canBeFalse || value.other
+}
+
+func (value abstractValue) hasOtherThanNumbers() bool {
+	return value.canBeTrue || value.canBeFalse || value.null || value.other
+}
+
+func addIntervals(left Interval, right Interval) Interval {
+	return Interval{lowerBound(left.Min + right.Min), upperBound(left.Max + right.Max)}
+}
+
+func subtractIntervals(left Interval, right Interval) Interval {
+	return Interval{lowerBound(left.Min - right.Max), upperBound(left.Max - right.Min)}
+}
+
+func multiplyIntervals(left Interval, right Interval) Interval {
+
+	products := []float64{
+		boundProduct(left.Min, right.Min),
+		boundProduct(left.Min, right.Max),
+		boundProduct(left.Max, right.Min),
+		boundProduct(left.Max, right.Max),
+	}
+
+	ret := Interval{products[0], products[0]}
+	for _, product := range products[1:] {
+		ret.Min = math.Min(ret.Min, product)
+		ret.Max = math.Max(ret.Max, product)
+	}
+	return ret
+}
+
+// Returns the interval of 1 divided by any number in [interval].
+// Dividing by zero gives an infinity, so intervals which include zero have an infinite bound.
+func reciprocalInterval(interval Interval) Interval {
+
+	switch {
+	case interval.Min > 0 || interval.Max < 0:
+		return Interval{1 / interval.Max, 1 / interval.Min}
+	case interval.Min == 0 && interval.Max > 0:
+		return Interval{1 / interval.Max, math.Inf(1)}
+	case interval.Max == 0 && interval.Min < 0:
+		return Interval{math.Inf(-1), 1 / interval.Min}
+	}
+	return Interval{math.Inf(-1), math.Inf(1)}
+}
+
+// The remainder has the sign of the dividend, and is smaller than both the dividend and the divisor.
+func modulusInterval(dividend Interval, divisor Interval) Interval {
+
+	largest := math.Max(math.Abs(divisor.Min), math.Abs(divisor.Max))
+
+	ret := Interval{0, 0}
+	if dividend.Min < 0 {
+		ret.Min = math.Max(dividend.Min, -largest)
+	}
+	if dividend.Max > 0 {
+		ret.Max = math.Min(dividend.Max, largest)
+	}
+	return ret
+}
+
+func exponentInterval(base Interval, power Interval) Interval {
+
+	// for a base which isn't negative, the result only grows (or only shrinks) with either operand, so its bounds are at the corners.
+	if base.Min >= 0 {
+
+		ret := Interval{math.Inf(1), math.Inf(-1)}
+		for _, b := range []float64{base.Min, base.Max} {
+			for _, p := range []float64{power.Min, power.Max} {
+
+				result := math.Pow(b, p)
+				ret.Min = math.Min(ret.Min, lowerBound(result))
+				ret.Max = math.Max(ret.Max, upperBound(result))
+			}
+		}
+		return ret
+	}
+
+	// a negative base is raised to a whole number.
+	if power.Min == power.Max && power.Min >= 0 && power.Min == math.Trunc(power.Min) {
+
+		if math.Mod(power.Min, 2) == 1 {
+			return Interval{math.Pow(base.Min, power.Min), math.Pow(base.Max, power.Min)}
+		}
+
+		magnitude := absInterval(base)
+		return Interval{math.Pow(magnitude.Min, power.Min), math.Pow(magnitude.Max, power.Min)}
+	}
+	return Interval{math.Inf(-1), math.Inf(1)}
+}
+
+func absInterval(interval Interval) Interval {
+
+	switch {
+	case interval.Min >= 0:
+		return interval
+	case interval.Max <= 0:
+		return Interval{-interval.Max, -interval.Min}
+	}
+	return Interval{0, math.Max(-interval.Min, interval.Max)}
+}
+
+// Multiplies the bounds of intervals, where zero times an infinite bound is zero.
+func boundProduct(left float64, right float64) float64 {
+
+	if left == 0 || right == 0 {
+		return 0
+	}
+	return left * right
+}
+
+// Returns [bound] as the lower bound of an interval; where NaN (as from adding opposite infinities) is unbounded.
+func lowerBound(bound float64) float64 {
+
+	if math.IsNaN(bound) {
+		return math.Inf(-1)
+	}
+	return bound
+}
+
+func upperBound(bound float64) float64 {
+
+	if math.IsNaN(bound) {
+		return math.Inf(1)
+	}
+	return bound
+}
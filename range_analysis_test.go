@@ -0,0 +1,333 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type RangeAnalysisTest struct {
+	Name        string
+	Input       string
+	Options     ExpressionOptions
+	Constraints map[string]VariableConstraint
+	Range       *Interval
+	MayBeTrue   bool
+	MayBeFalse  bool
+	MayBeNull   bool
+	Hazards     []RangeHazard
+}
+
+func TestAnalyzeRange(test *testing.T) {
+
+	within := func(minimum float64, maximum float64) VariableConstraint {
+		return VariableConstraint{Type: NumberType, Minimum: &minimum, Maximum: &maximum}
+	}
+	nullable := within(1, 2)
+	nullable.Nullable = true
+	unbounded := VariableConstraint{Type: NumberType}
+	standard := ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()}
+
+	rangeTests := []RangeAnalysisTest{
+		{
+			Name:        "Arithmetic",
+			Input:       "requests_made * requests_succeeded / 100",
+			Constraints: map[string]VariableConstraint{"requests_made": within(0, 1000), "requests_succeeded": within(0, 1000)},
+			Range:       &Interval{0, 10000},
+		},
+		{
+			Name:        "Subtraction and negation",
+			Input:       "-(a - b) + 1",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(5, 6)},
+			Range:       &Interval{-4, 7},
+		},
+		{
+			Name:        "Division by a range including zero",
+			Input:       "a / b",
+			Constraints: map[string]VariableConstraint{"a": within(1, 10), "b": within(0, 10)},
+			Range:       &Interval{0.1, math.Inf(1)},
+			Hazards:     []RangeHazard{{DivisionByZero, "a / b"}},
+		},
+		{
+			Name:        "Division of zero by zero",
+			Input:       "1 + a / b",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(-1, 1)},
+			Range:       &Interval{math.Inf(-1), math.Inf(1)},
+			Hazards:     []RangeHazard{{DivisionByZero, "a / b"}, {NotANumber, "a / b"}},
+		},
+		{
+			Name:        "Division guarded by a ternary",
+			Input:       "b > 0 ? a / b : 0",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(0, 10)},
+			Range:       &Interval{0, math.Inf(1)},
+		},
+		{
+			Name:        "Division guarded by the false branch of a ternary",
+			Input:       "b == 0 ? -1 : a / b",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(0, 10)},
+			Range:       &Interval{-1, math.Inf(1)},
+		},
+		{
+			Name:        "Division guarded by a logical operator",
+			Input:       "b != 0 && a / b > 1",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(-5, 5)},
+			MayBeTrue:   true,
+			MayBeFalse:  true,
+		},
+		{
+			Name:        "Division guarded by a negated condition",
+			Input:       "!(b <= 0) ? a / b : 0",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10), "b": within(0, 10)},
+			Range:       &Interval{0, math.Inf(1)},
+		},
+		{
+			Name:        "Modulus",
+			Input:       "a % b",
+			Constraints: map[string]VariableConstraint{"a": within(-50, 50), "b": within(0, 7)},
+			Range:       &Interval{-7, 7},
+			Hazards:     []RangeHazard{{DivisionByZero, "a % b"}, {NotANumber, "a % b"}},
+		},
+		{
+			Name:        "Exponents",
+			Input:       "a ** 2 + b ** 3",
+			Constraints: map[string]VariableConstraint{"a": within(-3, 2), "b": within(-1, 2)},
+			Range:       &Interval{-1, 17},
+		},
+		{
+			Name:        "Fractional exponent of a negative number",
+			Input:       "a ** 0.5",
+			Constraints: map[string]VariableConstraint{"a": within(-1, 4)},
+			Range:       &Interval{math.Inf(-1), math.Inf(1)},
+			Hazards:     []RangeHazard{{NotANumber, "a ** 0.5"}},
+		},
+		{
+			Name:        "Negative exponent of zero",
+			Input:       "a ** -1",
+			Constraints: map[string]VariableConstraint{"a": within(0, 4)},
+			Range:       &Interval{0.25, math.Inf(1)},
+			Hazards:     []RangeHazard{{DivisionByZero, "a ** -1"}},
+		},
+		{
+			Name:        "Clamping",
+			Input:       "max(0, min(100, score * 2))",
+			Options:     standard,
+			Constraints: map[string]VariableConstraint{"score": unbounded},
+			Range:       &Interval{0, 100},
+		},
+		{
+			Name:        "Clamping without descriptors",
+			Input:       "max(0, min(100, score * 2))",
+			Options:     ExpressionOptions{Functions: StandardFunctions()},
+			Constraints: map[string]VariableConstraint{"score": unbounded},
+			Range:       &Interval{0, 100},
+		},
+		{
+			Name:        "Math functions",
+			Input:       "sqrt(a) + abs(b) + floor(c)",
+			Options:     standard,
+			Constraints: map[string]VariableConstraint{"a": within(-4, 16), "b": within(-3, 2), "c": within(0.5, 1.5)},
+			Range:       &Interval{0, 8},
+		},
+		{
+			Name:        "Other functions",
+			Input:       "len(name) + 1",
+			Options:     standard,
+			Constraints: map[string]VariableConstraint{},
+			Range:       &Interval{math.Inf(-1), math.Inf(1)},
+		},
+		{
+			Name:        "Comparison which is always false",
+			Input:       "a > 100",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10)},
+			MayBeFalse:  true,
+		},
+		{
+			Name:        "Unreachable branch",
+			Input:       "a < 100 ? a * 2 : a / 0",
+			Constraints: map[string]VariableConstraint{"a": within(0, 10)},
+			Range:       &Interval{0, 20},
+		},
+		{
+			Name:        "Nested ternaries",
+			Input:       "a < 3 ? 1 : a < 6 ? 2 : 3",
+			Constraints: map[string]VariableConstraint{"a": within(4, 10)},
+			Range:       &Interval{2, 3},
+		},
+		{
+			Name:        "Between",
+			Input:       "a between 1 and 3",
+			Options:     ExpressionOptions{WordOperators: true},
+			Constraints: map[string]VariableConstraint{"a": within(5, 10)},
+			MayBeFalse:  true,
+		},
+		{
+			Name:        "Coalesce",
+			Input:       "a ?? 5",
+			Constraints: map[string]VariableConstraint{"a": nullable},
+			Range:       &Interval{1, 5},
+		},
+		{
+			Name:        "Null",
+			Input:       "a > 1 ? a : null",
+			Options:     ExpressionOptions{WordOperators: true},
+			Constraints: map[string]VariableConstraint{"a": within(0, 10)},
+			Range:       &Interval{1, 10},
+			MayBeNull:   true,
+		},
+		{
+			Name:        "Three-valued logic",
+			Input:       "a + 1",
+			Options:     ExpressionOptions{ThreeValuedLogic: true},
+			Constraints: map[string]VariableConstraint{"a": nullable},
+			Range:       &Interval{2, 3},
+			MayBeNull:   true,
+		},
+	}
+
+	for _, rangeTest := range rangeTests {
+
+		expression, err := NewExpressionWithOptions(rangeTest.Input, rangeTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", rangeTest.Name, err)
+			continue
+		}
+
+		analysis, err := expression.AnalyzeRange(rangeTest.Constraints)
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", rangeTest.Name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(analysis.Range, rangeTest.Range) {
+			test.Errorf("Test '%s' failed: expected the range %v, got %v", rangeTest.Name, rangeTest.Range, analysis.Range)
+		}
+
+		if analysis.MayBeTrue != rangeTest.MayBeTrue || analysis.MayBeFalse != rangeTest.MayBeFalse || analysis.MayBeNull != rangeTest.MayBeNull {
+			test.Errorf("Test '%s' failed: expected true %v, false %v, and null %v; got %v, %v, and %v", rangeTest.Name,
+				rangeTest.MayBeTrue, rangeTest.MayBeFalse, rangeTest.MayBeNull, analysis.MayBeTrue, analysis.MayBeFalse, analysis.MayBeNull)
+		}
+
+		if !reflect.DeepEqual(analysis.Hazards, rangeTest.Hazards) {
+			test.Errorf("Test '%s' failed: expected the hazards %v, got %v", rangeTest.Name, rangeTest.Hazards, analysis.Hazards)
+		}
+	}
+}
+
+func TestAnalyzeRangeOfUnknownParameters(test *testing.T) {
+
+	expression, err := NewExpression("x + 'suffix'")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	analysis, err := expression.AnalyzeRange(nil)
+	if err != nil {
+		test.Fatalf("Unable to analyze: %v", err)
+	}
+
+	if !analysis.MayBeOther || analysis.MayBeTrue || analysis.MayBeNull {
+		test.Errorf("Expected the concatenation of an unknown parameter to be a string or number, got %+v", analysis)
+	}
+}
+
+func TestAnalyzeRangeFailure(test *testing.T) {
+
+	expression, err := NewExpression("x + 1")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, err = expression.AnalyzeRange(map[string]VariableConstraint{"x": {Type: MapType}})
+	if err == nil || !strings.Contains(err.Error(), "Parameter 'x'") {
+		test.Errorf("Expected an invalid constraint to fail, got %v", err)
+	}
+}
+
+// Tests that the range found for randomly generated expressions holds the result of evaluating them over a grid of parameters.
+func TestAnalyzeRangeOfRandomExpressions(test *testing.T) {
+
+	random := rand.New(rand.NewSource(45))
+
+	minimum, maximum := -3.0, 5.0
+	constraints := map[string]VariableConstraint{
+		"x": {Type: NumberType, Minimum: &minimum, Maximum: &maximum},
+		"y": {Type: NumberType, Minimum: &minimum, Maximum: &maximum},
+	}
+	options := ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()}
+
+	var grid []float64
+	for value := minimum; value <= maximum; value += 0.5 {
+		grid = append(grid, value)
+	}
+
+	for i := 0; i < 300; i++ {
+
+		input := randomArithmetic(random, 3)
+
+		expression, err := NewExpressionWithOptions(input, options)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		analysis, err := expression.AnalyzeRange(constraints)
+		if err != nil {
+			test.Fatalf("Unable to analyze '%s': %v", input, err)
+		}
+
+	grid:
+		for _, x := range grid {
+			for _, y := range grid {
+
+				result, err := expression.Evaluate(map[string]interface{}{"x": x, "y": y})
+				if err != nil {
+					continue
+				}
+
+				number := result.(float64)
+				if math.IsNaN(number) && analysis.MayBeNaN {
+					continue
+				}
+
+				if analysis.Range == nil || !analysis.Range.Contains(number) {
+					test.Errorf("'%s' is %v for x = %v and y = %v, which is outside of its range %v (NaN: %v)", input, number, x, y, analysis.Range, analysis.MayBeNaN)
+					break grid
+				}
+			}
+		}
+	}
+}
+
+// Returns a random arithmetic expression over "x" and "y", nested at most [depth] times.
+func randomArithmetic(random *rand.Rand, depth int) string {
+
+	operands := []string{"x", "y", "0", "1", "2", "-1.5"}
+
+	choice := random.Intn(6)
+	if depth == 0 || choice == 0 {
+		return operands[random.Intn(len(operands))]
+	}
+
+	left := randomArithmetic(random, depth-1)
+	right := randomArithmetic(random, depth-1)
+
+	switch choice {
+	case 1:
+		operators := []string{"+", "-", "*", "/", "%"}
+		return fmt.Sprintf("(%s %s %s)", left, operators[random.Intn(len(operators))], right)
+	case 2:
+		exponents := []string{"2", "3", "0.5", "-1", "y"}
+		return fmt.Sprintf("(%s ** %s)", left, exponents[random.Intn(len(exponents))])
+	case 3:
+		functions := []string{"min", "max"}
+		return fmt.Sprintf("%s(%s, %s)", functions[random.Intn(len(functions))], left, right)
+	case 4:
+		functions := []string{"abs", "floor", "ceil", "round", "sqrt", "-"}
+		return fmt.Sprintf("%s(%s)", functions[random.Intn(len(functions))], left)
+	}
+
+	comparators := []string{">", "<", ">=", "<=", "==", "!="}
+	return fmt.Sprintf("(%s %s %s ? %s : %s)", operands[random.Intn(2)], comparators[random.Intn(len(comparators))], operands[2+random.Intn(4)], left, right)
+}
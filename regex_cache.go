@@ -0,0 +1,174 @@
+package govaluate
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+const defaultRegexCacheCapacity int = 256
+
+// DefaultRegexCache holds the compiled regex patterns of every expression which isn't given its own [ExpressionOptions].RegexCache.
+var DefaultRegexCache = NewRegexCache(defaultRegexCacheCapacity)
+
+// RegexCache is a bounded, least-recently-used cache of compiled regex patterns, which is safe for concurrent use.
+// Patterns which come from parameters (as in "name =~ pattern") are compiled when evaluated, so caching them saves compiling
+// the same pattern over and over. Constant patterns are compiled through the cache too, when the expression is parsed.
+type RegexCache struct {
+	mutex    sync.Mutex
+	capacity int
+
+	// entries are kept in order of use, most recent first.
+	entries map[string]*list.Element
+	order   *list.List
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// RegexCacheStats describe how a RegexCache has been used.
+type RegexCacheStats struct {
+
+	// Hits and Misses count how many patterns were found in the cache, or had to be compiled.
+	Hits   uint64
+	Misses uint64
+
+	// Evictions counts how many patterns were dropped to make room for others.
+	Evictions uint64
+
+	// Size is how many patterns are currently cached, out of at most Capacity.
+	Size     int
+	Capacity int
+}
+
+type regexCacheEntry struct {
+	pattern string
+	regex   *regexp.Regexp
+
+	// the number of instructions the pattern compiles to, or -1 if that hasn't been needed yet.
+	programSize int
+}
+
+// NewRegexCache returns an empty cache which holds up to [capacity] compiled patterns. A capacity of zero (or less) disables caching.
+func NewRegexCache(capacity int) *RegexCache {
+
+	return &RegexCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Stats returns how the cache has been used so far.
+func (cache *RegexCache) Stats() RegexCacheStats {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return RegexCacheStats{
+		Hits:      cache.hits,
+		Misses:    cache.misses,
+		Evictions: cache.evictions,
+		Size:      cache.order.Len(),
+		Capacity:  cache.capacity,
+	}
+}
+
+// SetCapacity changes how many patterns the cache can hold, evicting the least recently used patterns if it now holds too many.
+func (cache *RegexCache) SetCapacity(capacity int) {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.capacity = capacity
+	cache.evict()
+}
+
+// Returns the compiled [pattern], compiling and caching it if it isn't already cached.
+// If [maxSize] is positive, patterns which compile to more instructions than that fail with a *LimitError.
+func (cache *RegexCache) compile(pattern string, maxSize int) (*regexp.Regexp, error) {
+
+	cache.mutex.Lock()
+
+	element, found := cache.entries[pattern]
+	if found {
+
+		cache.hits++
+		cache.order.MoveToFront(element)
+		entry := element.Value.(*regexCacheEntry)
+
+		if maxSize > 0 && entry.programSize < 0 {
+			entry.programSize = regexProgramSize(pattern)
+		}
+		programSize := entry.programSize
+		cache.mutex.Unlock()
+
+		if maxSize > 0 {
+			err := checkLimit("MaxRegexSize", maxSize, programSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entry.regex, nil
+	}
+
+	cache.misses++
+	cache.mutex.Unlock()
+
+	// patterns are compiled outside the lock, so that a slow pattern doesn't hold up others.
+	entry := &regexCacheEntry{pattern: pattern, programSize: -1}
+
+	if maxSize > 0 {
+
+		entry.programSize = regexProgramSize(pattern)
+
+		err := checkLimit("MaxRegexSize", maxSize, entry.programSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	entry.regex = regex
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// another evaluation may have compiled the same pattern in the meantime.
+	_, found = cache.entries[pattern]
+	if !found && cache.capacity > 0 {
+		cache.entries[pattern] = cache.order.PushFront(entry)
+		cache.evict()
+	}
+	return regex, nil
+}
+
+// Drops the least recently used patterns until the cache is within its capacity. Must be called with the mutex held.
+func (cache *RegexCache) evict() {
+
+	for cache.order.Len() > 0 && cache.order.Len() > cache.capacity {
+
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*regexCacheEntry).pattern)
+		cache.evictions++
+	}
+}
+
+// Compiles [pattern] through [cache] (or DefaultRegexCache, if nil), failing with a *LimitError if it exceeds the regex [limits].
+func compileRegex(pattern string, cache *RegexCache, limits Limits) (*regexp.Regexp, error) {
+
+	err := checkLimit("MaxRegexLength", limits.MaxRegexLength, len(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	if cache == nil {
+		cache = DefaultRegexCache
+	}
+	return cache.compile(pattern, limits.MaxRegexSize)
+}
@@ -0,0 +1,137 @@
+package govaluate
+
+import (
+	"testing"
+)
+
+func TestRegexCache(test *testing.T) {
+
+	cache := NewRegexCache(2)
+
+	for _, pattern := range []string{"a", "b", "a", "c", "a"} {
+
+		regex, err := cache.compile(pattern, 0)
+		if err != nil || regex.String() != pattern {
+			test.Fatalf("Unable to compile '%s': %v", pattern, err)
+		}
+	}
+
+	// "b" was least recently used when "c" was added.
+	expected := RegexCacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 2, Capacity: 2}
+	if stats := cache.Stats(); stats != expected {
+		test.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	cache.compile("b", 0)
+	if stats := cache.Stats(); stats.Misses != 4 || stats.Evictions != 2 {
+		test.Errorf("Expected evicted pattern to be compiled again, got %+v", stats)
+	}
+
+	cache.SetCapacity(0)
+	if stats := cache.Stats(); stats.Size != 0 || stats.Evictions != 4 {
+		test.Errorf("Expected shrinking the cache to evict every pattern, got %+v", stats)
+	}
+
+	_, err := cache.compile("[a", 0)
+	if err == nil {
+		test.Errorf("Expected invalid pattern to fail to compile")
+	}
+}
+
+func TestRegexCacheEvaluation(test *testing.T) {
+
+	cache := NewRegexCache(10)
+	options := ExpressionOptions{RegexCache: cache}
+
+	expression, err := NewExpressionWithOptions("name =~ pattern && name =~ '^f'", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	// the constant pattern is compiled while parsing.
+	if stats := cache.Stats(); stats.Misses != 1 || stats.Size != 1 {
+		test.Errorf("Expected the constant pattern to be cached while parsing, got %+v", stats)
+	}
+
+	for i := 0; i < 3; i++ {
+
+		result, err := expression.Evaluate(map[string]interface{}{"name": "foo", "pattern": "o+$"})
+		if err != nil || result != true {
+			test.Errorf("Expected true, got %v (%v)", result, err)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Misses != 2 || stats.Hits != 2 {
+		test.Errorf("Expected the pattern from a parameter to be compiled once, got %+v", stats)
+	}
+
+	// the same pattern in a different expression with the same cache is already compiled.
+	other, _ := NewExpressionWithOptions("'foo' =~ 'o+$'", options)
+	other.Evaluate(nil)
+
+	if stats := cache.Stats(); stats.Misses != 2 || stats.Hits != 3 {
+		test.Errorf("Expected patterns to be shared across expressions, got %+v", stats)
+	}
+
+	_, err = expression.Evaluate(map[string]interface{}{"name": "foo", "pattern": "[o"})
+	if err == nil {
+		test.Errorf("Expected invalid pattern from a parameter to fail")
+	}
+}
+
+func TestRegexLengthLimit(test *testing.T) {
+
+	options := ExpressionOptions{Limits: Limits{MaxRegexLength: 5}, RegexCache: NewRegexCache(10)}
+	expected := "Regex pattern is 6 bytes long, which exceeds the limit of 5"
+
+	_, err := NewExpressionWithOptions("name =~ 'abcdef'", options)
+	if err == nil || err.Error() != expected {
+		test.Errorf("Expected long constant pattern to fail with '%s', got %v", expected, err)
+	}
+
+	expression, _ := NewExpressionWithOptions("name =~ pattern", options)
+
+	_, err = expression.Evaluate(map[string]interface{}{"name": "abc", "pattern": "abcdef"})
+	if _, ok := err.(*LimitError); !ok || err.Error() != expected {
+		test.Errorf("Expected long pattern from a parameter to fail with '%s', got %v", expected, err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"name": "abc", "pattern": "^a"})
+	if err != nil || result != true {
+		test.Errorf("Expected short pattern to match, got %v (%v)", result, err)
+	}
+}
+
+func TestRegexLiterals(test *testing.T) {
+
+	cases := map[string]interface{}{
+		"name =~ /^foo/":            true,
+		"name =~ /^FOO/":            false,
+		"name =~ /^FOO/i":           true,
+		"name !~ /^FOO/i":           false,
+		"name =~ /^FOO.BAR$/is":     true,
+		"path =~ /^a\\/b$/":         true,
+		"path =~ /\\w\\/\\w/":       true,
+		"name =~ /'/":               false,
+		"(name =~ /o/) && 4/2 == 2": true,
+	}
+
+	parameters := map[string]interface{}{
+		"name": "foo\nbar",
+		"path": "a/b",
+	}
+
+	for input, expected := range cases {
+
+		expression, err := NewExpression(input)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+		if err != nil || result != expected {
+			test.Errorf("Expected '%s' to evaluate to %v, got %v (%v)", input, expected, result, err)
+		}
+	}
+}
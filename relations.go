@@ -0,0 +1,267 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Relationship is whether a relationship between two expressions holds, as found by [Implies], [Overlaps], and [Equivalent].
+type Relationship int
+
+const (
+	// UnknownRelationship means that it couldn't be shown whether the relationship holds;
+	// for the same reasons that a satisfiability can be unknown (see [UnknownSatisfiability]).
+	UnknownRelationship Relationship = iota
+
+	// RelationshipHolds means that the relationship holds for every parameter.
+	RelationshipHolds
+
+	// RelationshipFails means that the relationship doesn't hold, as shown by a counterexample.
+	RelationshipFails
+)
+
+func (relationship Relationship) String() string {
+
+	switch relationship {
+	case RelationshipHolds:
+		return "holds"
+	case RelationshipFails:
+		return "fails"
+	}
+	return "unknown"
+}
+
+// RelationResult is what [Implies], [Overlaps], and [Equivalent] find about two expressions.
+type RelationResult struct {
+	Relationship Relationship
+
+	// Example holds parameters for which both expressions are true, if any were found; only given by Overlaps.
+	Example map[string]interface{}
+
+	// Counterexample holds parameters which show that the relationship doesn't hold, if it doesn't.
+	// For Implies and Equivalent, one expression is true for them, and the other isn't.
+	// For Overlaps, they're not true for both; where possible, one of them is true for them.
+	Counterexample map[string]interface{}
+}
+
+// Implies finds whether [b] is true for every parameter which makes [a] true; such as when a rule is subsumed by another.
+// "age > 30" implies "age > 18", for instance; but "age > 18" doesn't imply "age > 30", as shown by a counterexample where age is 19.
+//
+// Expressions which are null, or fail, aren't true. The values that each parameter can have may be given by [constraints],
+// as with [Expression.CheckSatisfiability], whose analysis this uses; so the same kinds of expressions can be compared exactly.
+//
+// Fails if any of the [constraints] are invalid, if only one of the expressions uses three-valued logic,
+// or if one of them still uses a parameter given to [Expression.Bind] which the other uses differently.
+func Implies(a *Expression, b *Expression, constraints map[string]VariableConstraint) (RelationResult, error) {
+
+	var ret RelationResult
+
+	result, err := findDisagreement(a, b, constraints)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Relationship = relationshipOf(result)
+	ret.Counterexample = result.Example
+	return ret, nil
+}
+
+// Overlaps finds whether there are any parameters which make both [a] and [b] true;
+// if there aren't, the expressions are mutually exclusive. "age > 30" overlaps with "age < 40", but not with "age < 20".
+// Otherwise, this is the same as [Implies].
+func Overlaps(a *Expression, b *Expression, constraints map[string]VariableConstraint) (RelationResult, error) {
+
+	var ret RelationResult
+
+	combined, err := combineExpressions(a, b)
+	if err != nil {
+		return ret, err
+	}
+
+	term, goals, err := relationTermOf(combined, a, true, b, true)
+	if err != nil {
+		return ret, err
+	}
+
+	result, err := combined.checkSatisfiability(term, goals, constraints)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Example = result.Example
+
+	switch {
+	case result.Example != nil:
+		ret.Relationship = RelationshipHolds
+		return ret, nil
+	case result.Satisfiability == UnknownSatisfiability:
+		ret.Relationship = UnknownRelationship
+		return ret, nil
+	}
+
+	ret.Relationship = RelationshipFails
+	ret.Counterexample = result.Counterexample
+
+	// parameters for which one is true (and so the other isn't) say more than those for which neither is.
+	for _, ordered := range [][]*Expression{{a, b}, {b, a}} {
+
+		disagreement, err := findDisagreement(ordered[0], ordered[1], constraints)
+		if err != nil {
+			return ret, err
+		}
+
+		if disagreement.Example != nil {
+			ret.Counterexample = disagreement.Example
+			break
+		}
+	}
+	return ret, nil
+}
+
+// Equivalent finds whether [a] and [b] are true for exactly the same parameters; that is, whether each implies the other.
+// "age > 30 || age == 30" is equivalent to "age >= 30", for instance.
+// Otherwise, this is the same as [Implies].
+func Equivalent(a *Expression, b *Expression, constraints map[string]VariableConstraint) (RelationResult, error) {
+
+	var ret RelationResult
+
+	forward, err := findDisagreement(a, b, constraints)
+	if err != nil {
+		return ret, err
+	}
+
+	if forward.Example != nil {
+		ret.Relationship = RelationshipFails
+		ret.Counterexample = forward.Example
+		return ret, nil
+	}
+
+	backward, err := findDisagreement(b, a, constraints)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Relationship = relationshipOf(backward)
+	ret.Counterexample = backward.Example
+
+	if forward.Satisfiability == UnknownSatisfiability && ret.Relationship == RelationshipHolds {
+		ret.Relationship = UnknownRelationship
+	}
+	return ret, nil
+}
+
+// Searches for parameters which make [a] true, but not [b].
+func findDisagreement(a *Expression, b *Expression, constraints map[string]VariableConstraint) (SatisfiabilityResult, error) {
+
+	combined, err := combineExpressions(a, b)
+	if err != nil {
+		return SatisfiabilityResult{}, err
+	}
+
+	term, goals, err := relationTermOf(combined, a, true, b, false)
+	if err != nil {
+		return SatisfiabilityResult{}, err
+	}
+
+	return combined.checkSatisfiability(term, goals, constraints)
+}
+
+// Returns whether a relationship holds, given the result of searching for parameters for which it doesn't.
+func relationshipOf(disagreement SatisfiabilityResult) Relationship {
+
+	switch {
+	case disagreement.Example != nil:
+		return RelationshipFails
+	case disagreement.Satisfiability == Unsatisfiable:
+		return RelationshipHolds
+	}
+	return UnknownRelationship
+}
+
+// Returns the term (and goals) for the satisfiability checker, which is true wherever [a] is true (or if not [aIsTrue], isn't true),
+// as well as [b] (or not, as given by [bIsTrue]).
+func relationTermOf(combined Expression, a *Expression, aIsTrue bool, b *Expression, bIsTrue bool) (*boolTerm, []satisfiabilityGoal, error) {
+
+	aTerm, err := combined.satisfiabilityTermOf(a.evaluationStages, aIsTrue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bTerm, err := combined.satisfiabilityTermOf(b.evaluationStages, bIsTrue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	goals := []satisfiabilityGoal{
+		{stage: a.evaluationStages, isTrue: aIsTrue},
+		{stage: b.evaluationStages, isTrue: bIsTrue},
+	}
+	return newCompoundTerm(andTerm, []*boolTerm{aTerm, bTerm}), goals, nil
+}
+
+// Returns an expression (without stages of its own) in which the stages of both [a] and [b] can be evaluated.
+func combineExpressions(a *Expression, b *Expression) (Expression, error) {
+
+	var ret Expression
+
+	if a.options.ThreeValuedLogic != b.options.ThreeValuedLogic {
+		return ret, errors.New("Unable to compare an expression which uses three-valued logic with one which doesn't")
+	}
+
+	ret.options = a.options
+
+	// bound parameters apply to both expressions' stages, so those which are still used can't differ between them.
+	for _, pair := range [][]*Expression{{a, b}, {b, a}} {
+
+		bound, other := pair[0], pair[1]
+
+		for name, value := range bound.boundParameters {
+
+			if !refersToParameter(bound.evaluationStages, name, nil) {
+				continue
+			}
+
+			if refersToParameter(other.evaluationStages, name, nil) {
+
+				otherValue, isBound := other.boundParameters[name]
+				if !isBound {
+					errorMsg := fmt.Sprintf("Parameter '%s' is bound in only one of the expressions being compared", name)
+					return ret, errors.New(errorMsg)
+				}
+
+				if !reflect.DeepEqual(value, otherValue) {
+					errorMsg := fmt.Sprintf("Parameter '%s' is bound to different values in the expressions being compared", name)
+					return ret, errors.New(errorMsg)
+				}
+			}
+
+			if ret.boundParameters == nil {
+				ret.boundParameters = make(map[string]interface{})
+			}
+			ret.boundParameters[name] = value
+		}
+	}
+	return ret, nil
+}
+
+// Returns true if [stage] refers to the parameter [name] (or an accessor of it), other than where it's [shadowed] by a collection function.
+func refersToParameter(stage *evaluationStage, name string, shadowed map[string]bool) bool {
+
+	if stage == nil {
+		return false
+	}
+
+	switch stage.symbol {
+	case value:
+		return stage.name == name && !shadowed[name]
+	case access:
+		if accessorRoot(stage.name) == name && !shadowed[name] {
+			return true
+		}
+	case collectionFunctional:
+		return refersToParameter(stage.leftStage, name, shadowed) ||
+			refersToParameter(stage.rightStage, name, shadowWith(shadowed, stage.collection.parameterName))
+	}
+	return refersToParameter(stage.leftStage, name, shadowed) || refersToParameter(stage.rightStage, name, shadowed)
+}
@@ -0,0 +1,350 @@
+package govaluate
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+type RelationTest struct {
+	Name        string
+	A           string
+	B           string
+	Options     ExpressionOptions
+	Constraints map[string]VariableConstraint
+	Implies     Relationship
+	Overlaps    Relationship
+	Equivalent  Relationship
+}
+
+func TestRelations(test *testing.T) {
+
+	relationTests := []RelationTest{
+		{
+			Name:       "Subsumed range",
+			A:          "age > 30",
+			B:          "age > 18",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Wider range",
+			A:          "age > 18",
+			B:          "age > 30",
+			Implies:    RelationshipFails,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Subsumed range of large numbers",
+			A:          "x > 1e17",
+			B:          "x >= 1e17",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Mutually exclusive",
+			A:          "age > 30",
+			B:          "age < 20",
+			Implies:    RelationshipFails,
+			Overlaps:   RelationshipFails,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Equivalent",
+			A:          "age > 30 || age == 30",
+			B:          "age >= 30",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipHolds,
+		},
+		{
+			Name:       "Membership",
+			A:          "status in ['open', 'pending']",
+			B:          "status != 'closed'",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Membership and equality",
+			A:          "status in ['open', 'pending']",
+			B:          "status == 'open' || status == 'pending'",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipHolds,
+		},
+		{
+			Name:       "Different parameters",
+			A:          "country == 'NZ' && age >= 18",
+			B:          "country == 'NZ'",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipHolds,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:        "Within constraints",
+			A:           "score >= 0",
+			B:           "score <= 100",
+			Constraints: map[string]VariableConstraint{"score": {Type: NumberType, Minimum: new(float64)}},
+			Implies:     RelationshipFails,
+			Overlaps:    RelationshipHolds,
+			Equivalent:  RelationshipFails,
+		},
+		{
+			Name:        "Integers",
+			A:           "age > 30",
+			B:           "age >= 31",
+			Constraints: map[string]VariableConstraint{"age": {Type: NumberType, Integer: true}},
+			Implies:     RelationshipHolds,
+			Overlaps:    RelationshipHolds,
+			Equivalent:  RelationshipHolds,
+		},
+		{
+			Name:        "Null isn't true",
+			A:           "x > 1 || x <= 1",
+			B:           "true",
+			Options:     ExpressionOptions{ThreeValuedLogic: true},
+			Constraints: map[string]VariableConstraint{"x": {Type: NumberType, Nullable: true}},
+			Implies:     RelationshipHolds,
+			Overlaps:    RelationshipHolds,
+			Equivalent:  RelationshipFails,
+		},
+		{
+			Name:       "Never true",
+			A:          "x > 2 && x < 1",
+			B:          "y == 'a'",
+			Implies:    RelationshipHolds,
+			Overlaps:   RelationshipFails,
+			Equivalent: RelationshipFails,
+		},
+		{
+			Name:       "Comparison of parameters",
+			A:          "x > y",
+			B:          "y < x",
+			Implies:    UnknownRelationship,
+			Overlaps:   RelationshipHolds,
+			Equivalent: UnknownRelationship,
+		},
+	}
+
+	for _, relationTest := range relationTests {
+
+		a, err := NewExpressionWithOptions(relationTest.A, relationTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", relationTest.Name, err)
+			continue
+		}
+
+		b, err := NewExpressionWithOptions(relationTest.B, relationTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", relationTest.Name, err)
+			continue
+		}
+
+		relations := []struct {
+			name     string
+			check    func(*Expression, *Expression, map[string]VariableConstraint) (RelationResult, error)
+			expected Relationship
+		}{
+			{"Implies", Implies, relationTest.Implies},
+			{"Overlaps", Overlaps, relationTest.Overlaps},
+			{"Equivalent", Equivalent, relationTest.Equivalent},
+		}
+
+		for _, relation := range relations {
+
+			result, err := relation.check(a, b, relationTest.Constraints)
+			if err != nil {
+				test.Errorf("Test '%s' failed %s: %v", relationTest.Name, relation.name, err)
+				continue
+			}
+
+			if result.Relationship != relation.expected {
+				test.Errorf("Test '%s' failed %s: expected %v, got %v", relationTest.Name, relation.name, relation.expected, result.Relationship)
+			}
+
+			// counterexamples must be genuine, and given whenever the relationship fails.
+			if (result.Counterexample != nil) != (result.Relationship == RelationshipFails) {
+				test.Errorf("Test '%s' failed %s: unexpected counterexample %v", relationTest.Name, relation.name, result.Counterexample)
+				continue
+			}
+
+			if result.Counterexample == nil {
+				continue
+			}
+
+			aIsTrue := isTrueFor(a, result.Counterexample)
+			bIsTrue := isTrueFor(b, result.Counterexample)
+
+			genuine := aIsTrue && !bIsTrue
+			switch relation.name {
+			case "Overlaps":
+				genuine = !(aIsTrue && bIsTrue)
+			case "Equivalent":
+				genuine = aIsTrue != bIsTrue
+			}
+
+			if !genuine {
+				test.Errorf("Test '%s' failed %s: the counterexample %v doesn't show that it fails", relationTest.Name, relation.name, result.Counterexample)
+			}
+		}
+	}
+}
+
+func TestOverlapsExample(test *testing.T) {
+
+	a, _ := NewExpression("age > 30 && country == 'NZ'")
+	b, _ := NewExpression("age < 40")
+
+	result, err := Overlaps(a, b, nil)
+	if err != nil {
+		test.Fatalf("Unable to check for an overlap: %v", err)
+	}
+
+	if result.Example["age"] != 35.0 || result.Example["country"] != "NZ" {
+		test.Errorf("Expected an example of age 35 and country 'NZ', got %v", result.Example)
+	}
+
+	// a counterexample for which one of them is true is preferred.
+	b, _ = NewExpression("age < 20")
+
+	result, err = Overlaps(a, b, nil)
+	if err != nil {
+		test.Fatalf("Unable to check for an overlap: %v", err)
+	}
+
+	if !isTrueFor(a, result.Counterexample) {
+		test.Errorf("Expected a counterexample for which '%s' is true, got %v", a.String(), result.Counterexample)
+	}
+}
+
+func TestRelationFailure(test *testing.T) {
+
+	plain, _ := NewExpression("x > 1")
+	threeValued, _ := NewExpressionWithOptions("x > 1", ExpressionOptions{ThreeValuedLogic: true})
+
+	// times can't be written as literals, so they're kept by the expressions they're bound to.
+	bindable, _ := NewExpression("x == y")
+	bound, _ := bindable.Bind(map[string]interface{}{"y": time.Unix(1, 0)})
+	boundDifferently, _ := bindable.Bind(map[string]interface{}{"y": time.Unix(2, 0)})
+	free, _ := NewExpression("y > 1")
+
+	pairs := []struct {
+		a, b     *Expression
+		expected string
+	}{
+		{plain, threeValued, "three-valued logic"},
+		{bound, free, "Parameter 'y'"},
+		{free, bound, "Parameter 'y'"},
+		{bound, boundDifferently, "different values"},
+	}
+
+	for _, pair := range pairs {
+
+		_, err := Implies(pair.a, pair.b, nil)
+		if err == nil || !strings.Contains(err.Error(), pair.expected) {
+			test.Errorf("Expected comparing '%s' with '%s' to fail with '%s', got %v", pair.a.String(), pair.b.String(), pair.expected, err)
+		}
+	}
+
+	// those that can be are no longer used by the expressions at all.
+	bindable, _ = NewExpression("x > y")
+	boundAsLiteral, _ := bindable.Bind(map[string]interface{}{"y": 1.0})
+	boundDifferently, _ = bindable.Bind(map[string]interface{}{"y": 2.0})
+
+	result, err := Implies(boundDifferently, boundAsLiteral, nil)
+	if err != nil || result.Relationship != RelationshipHolds {
+		test.Errorf("Expected '%s' to imply '%s', got %v (%v)", boundDifferently.String(), boundAsLiteral.String(), result.Relationship, err)
+	}
+
+	result, err = Implies(boundAsLiteral, free, nil)
+	if err != nil || result.Relationship != RelationshipFails {
+		test.Errorf("Expected '%s' not to imply '%s', got %v (%v)", boundAsLiteral.String(), free.String(), result.Relationship, err)
+	}
+
+	_, err = Overlaps(plain, plain, map[string]VariableConstraint{"x": {Type: ArrayType}})
+	if err == nil || !strings.Contains(err.Error(), "Parameter 'x'") {
+		test.Errorf("Expected an invalid constraint to fail, got %v", err)
+	}
+}
+
+// Tests that the relationships of randomly generated expressions agree with evaluating them for every combination of parameters.
+func TestRelationsOfRandomExpressions(test *testing.T) {
+
+	random := rand.New(rand.NewSource(46))
+
+	zero := 0.0
+	three := 3.0
+
+	constraints := map[string]VariableConstraint{
+		"a": {Type: BoolType},
+		"x": {Type: NumberType, Integer: true, Minimum: &zero, Maximum: &three},
+		"s": {Type: StringType},
+	}
+	conditions := []string{"a", "x == 1", "x != 2", "x > 1", "x <= 0", "x in [1, 3]", "s == 'p'", "s > 'p'", "true"}
+
+	var assignments []map[string]interface{}
+	for _, a := range []interface{}{true, false} {
+		for _, x := range []interface{}{0.0, 1.0, 2.0, 3.0} {
+			for _, s := range []interface{}{"", "p", "pa", "q"} {
+				assignments = append(assignments, map[string]interface{}{"a": a, "x": x, "s": s})
+			}
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+
+		a, _ := NewExpression(randomLogic(random, conditions, 3))
+		b, _ := NewExpression(randomLogic(random, conditions, 3))
+
+		implies, overlaps, equivalent := RelationshipHolds, RelationshipFails, RelationshipHolds
+
+		for _, assignment := range assignments {
+
+			aIsTrue := isTrueFor(a, assignment)
+			bIsTrue := isTrueFor(b, assignment)
+
+			if aIsTrue && !bIsTrue {
+				implies = RelationshipFails
+			}
+			if aIsTrue && bIsTrue {
+				overlaps = RelationshipHolds
+			}
+			if aIsTrue != bIsTrue {
+				equivalent = RelationshipFails
+			}
+		}
+
+		relations := []struct {
+			name     string
+			check    func(*Expression, *Expression, map[string]VariableConstraint) (RelationResult, error)
+			expected Relationship
+		}{
+			{"Implies", Implies, implies},
+			{"Overlaps", Overlaps, overlaps},
+			{"Equivalent", Equivalent, equivalent},
+		}
+
+		for _, relation := range relations {
+
+			result, err := relation.check(a, b, constraints)
+			if err != nil {
+				test.Fatalf("%s failed on '%s' and '%s': %v", relation.name, a.String(), b.String(), err)
+			}
+
+			if result.Relationship != relation.expected {
+				test.Errorf("Expected %s of '%s' and '%s' to be %v, got %v", relation.name, a.String(), b.String(), relation.expected, result.Relationship)
+			}
+		}
+	}
+}
+
+func isTrueFor(expression *Expression, parameters map[string]interface{}) bool {
+
+	value, err := expression.Evaluate(parameters)
+	return err == nil && value == true
+}
@@ -1,13 +1,30 @@
 package govaluate
 
+import (
+	"context"
+)
+
 // sanitizedParameters is a wrapper for Parameters that does sanitization as
 // parameters are accessed.
 type sanitizedParameters struct {
 	orig Parameters
+
+	// the state of the evaluation these parameters are used for, or nil if there's nothing to keep track of.
+	evaluation *evaluationState
 }
 
 func (p sanitizedParameters) Get(key string) (interface{}, error) {
-	value, err := p.orig.Get(key)
+
+	var value interface{}
+	var err error
+
+	contextual, isContextual := p.orig.(ParametersContext)
+	if isContextual && p.evaluation != nil && p.evaluation.ctx != nil {
+		value, err = contextual.GetContext(p.evaluation.ctx, key)
+	} else {
+		value, err = p.orig.Get(key)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -15,6 +32,72 @@ func (p sanitizedParameters) Get(key string) (interface{}, error) {
 	return castToFloat64(value), nil
 }
 
+func (p sanitizedParameters) evaluationState() *evaluationState {
+	return p.evaluation
+}
+
+// evaluationState is shared by every stage evaluated during a single evaluation of an expression.
+type evaluationState struct {
+
+	// the context given to EvalContext, or nil if evaluation wasn't given one.
+	ctx context.Context
+
+	limits     Limits
+	operations int
+
+	// records each stage that's evaluated, if not nil; for EvalWithTrace.
+	tracer *evaluationTracer
+
+	// the coverage collected for the expression, if any; see CollectCoverage.
+	coverage *Coverage
+
+	// the profile recorded for the expression, if any; see CollectProfile.
+	profile *expressionProfile
+}
+
+// step is called before each stage is evaluated. Returns an error if evaluation should stop.
+func (state *evaluationState) step() error {
+
+	if state.ctx != nil {
+		select {
+		case <-state.ctx.Done():
+			return state.ctx.Err()
+		default:
+		}
+	}
+
+	if state.limits.MaxOperations > 0 {
+		state.operations++
+		return checkLimit("MaxOperations", state.limits.MaxOperations, state.operations)
+	}
+	return nil
+}
+
+// evaluationParameters are Parameters which know the state of the evaluation they're used in.
+type evaluationParameters interface {
+	evaluationState() *evaluationState
+}
+
+// Returns the state of the evaluation that the given parameters are used in, or nil if there is none.
+func stateOf(parameters Parameters) *evaluationState {
+
+	carrier, ok := parameters.(evaluationParameters)
+	if !ok {
+		return nil
+	}
+	return carrier.evaluationState()
+}
+
+// Returns the context of the evaluation that the given parameters are used in, or nil if it was not given one.
+func contextOf(parameters Parameters) context.Context {
+
+	state := stateOf(parameters)
+	if state == nil {
+		return nil
+	}
+	return state.ctx
+}
+
 func castToFloat64(value interface{}) interface{} {
 	switch value.(type) {
 	case uint8:
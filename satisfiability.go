@@ -0,0 +1,795 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// the most assignments of parameters that CheckSatisfiability will try, before giving up with UnknownSatisfiability.
+const maxSatisfiabilitySteps int = 100000
+
+// Satisfiability describes whether there are parameters for which an expression is true, as found by [Expression.CheckSatisfiability].
+type Satisfiability int
+
+const (
+	// UnknownSatisfiability means that no parameters were found for which the expression is true,
+	// but it couldn't be shown that there are none; because the expression uses something other than comparisons of parameters
+	// with literals (such as "x > y", or a function call), or because there were too many combinations of parameters to try.
+	UnknownSatisfiability Satisfiability = iota
+
+	// Unsatisfiable means that the expression is never true, as with "age > 30 && age < 20".
+	Unsatisfiable
+
+	// Satisfiable means that the expression is true for some parameters.
+	// It may not be true for others; or it may be true for every parameter, but couldn't be shown to be.
+	Satisfiable
+
+	// Tautology means that the expression is true for every parameter, as with "age > 30 || age <= 30".
+	Tautology
+)
+
+func (satisfiability Satisfiability) String() string {
+
+	switch satisfiability {
+	case Unsatisfiable:
+		return "unsatisfiable"
+	case Satisfiable:
+		return "satisfiable"
+	case Tautology:
+		return "tautology"
+	}
+	return "unknown"
+}
+
+// SatisfiabilityResult is what [Expression.CheckSatisfiability] finds about an expression.
+type SatisfiabilityResult struct {
+	Satisfiability Satisfiability
+
+	// Example holds parameters for which the expression is true, if any were found.
+	Example map[string]interface{}
+
+	// Counterexample holds parameters for which the expression isn't true (because it's false, null, or fails), if any were found.
+	Counterexample map[string]interface{}
+}
+
+// VariableConstraint describes the values that a parameter can have, for [Expression.CheckSatisfiability].
+type VariableConstraint struct {
+
+	// Type is the type of the parameter; NumberType, StringType, or BoolType.
+	// If AnyType, the parameter is assumed to have the types of the literals it's compared with,
+	// or to be a bool if it's used as a condition on its own (as in "x && y").
+	Type ValueType
+
+	// Integer means that a number can only be a whole number.
+	Integer bool
+
+	// Minimum and Maximum are the (inclusive) bounds of a number, if not nil.
+	Minimum *float64
+	Maximum *float64
+
+	// Nullable means that the parameter can also be null.
+	// Parameters without a Type are nullable if they're compared with null.
+	Nullable bool
+}
+
+// CheckSatisfiability finds whether there are any parameters for which the expression is true, and whether it's true for every parameter.
+// The values that each parameter can have may be given by [constraints], keyed by the parameter's name.
+//
+// The analysis is exact for expressions of "&&", "||", and "!" over comparisons of parameters with literals ("==", "!=", "<", "<=", ">", ">="),
+// as well as "in", "between", "is null", and bool parameters on their own.
+// Anything else (like "x > y", or a function call) is still evaluated, so examples found are always genuine;
+// but if none are, the result is UnknownSatisfiability rather than Unsatisfiable (and likewise, Satisfiable rather than Tautology).
+// Accessors, and parameters that were bound with [Expression.Bind], can't be given values.
+//
+// Fails if any of the [constraints] are invalid, such as a Minimum above the Maximum.
+func (expr Expression) CheckSatisfiability(constraints map[string]VariableConstraint) (SatisfiabilityResult, error) {
+
+	term, err := expr.satisfiabilityTermOf(expr.evaluationStages, true)
+	if err != nil {
+		return SatisfiabilityResult{}, err
+	}
+
+	return expr.checkSatisfiability(term, []satisfiabilityGoal{{stage: expr.evaluationStages, isTrue: true}}, constraints)
+}
+
+// Returns the term of [stage] for the satisfiability checker, which is true wherever the stage is true;
+// or if not [isTrue], wherever the stage isn't true.
+func (expr Expression) satisfiabilityTermOf(stage *evaluationStage, isTrue bool) (*boolTerm, error) {
+
+	if stage == nil {
+		return constantBoolTerm(!isTrue), nil
+	}
+
+	simplifier := &stageSimplifier{options: expr.options}
+	return simplifier.termOf(stage, !isTrue)
+}
+
+// Searches for parameters which meet every one of the [goals], and for parameters which don't;
+// where [term] is the logic of the goals, and is true if they're met.
+func (expr Expression) checkSatisfiability(term *boolTerm, goals []satisfiabilityGoal, constraints map[string]VariableConstraint) (SatisfiabilityResult, error) {
+
+	var ret SatisfiabilityResult
+
+	checker, err := expr.newSatisfiabilityChecker(term, goals, constraints, nil)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Example = checker.search(make(map[string]interface{}), true)
+	ret.Counterexample = checker.search(make(map[string]interface{}), false)
+
+	exact := checker.complete && checker.steps <= maxSatisfiabilitySteps
+
+	switch {
+	case ret.Example == nil && exact:
+		ret.Satisfiability = Unsatisfiable
+	case ret.Example == nil:
+		ret.Satisfiability = UnknownSatisfiability
+	case ret.Counterexample == nil && exact:
+		ret.Satisfiability = Tautology
+	default:
+		ret.Satisfiability = Satisfiable
+	}
+	return ret, nil
+}
+
+// Returns a checker which searches for parameters that meet the [goals] (whose logic is [term]),
+// and which only tries the given values for any parameters that are [pinned].
+func (expr Expression) newSatisfiabilityChecker(term *boolTerm, goals []satisfiabilityGoal, constraints map[string]VariableConstraint, pinned map[string]interface{}) (*satisfiabilityChecker, error) {
+
+	for name, constraint := range constraints {
+		err := constraint.validate(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	checker := &satisfiabilityChecker{
+		expr:        expr,
+		constraints: constraints,
+		term:        term,
+		goals:       goals,
+		evaluator:   Expression{ChecksTypes: true, options: expr.options},
+		complete:    true,
+		variables:   make(map[string]*satisfiabilityVariable),
+		conditions:  make(map[*boolTerm]*satisfiabilityCondition),
+	}
+
+	checker.analyze(checker.term)
+	checker.shareConstants()
+
+	for _, variable := range checker.ordered {
+
+		value, isPinned := pinned[variable.name]
+		if isPinned {
+			variable.candidates = []interface{}{value}
+			continue
+		}
+		variable.candidates = checker.candidatesOf(variable)
+	}
+	return checker, nil
+}
+
+func (constraint VariableConstraint) validate(name string) error {
+
+	switch constraint.Type {
+	case AnyType, NumberType, StringType, BoolType:
+	default:
+		errorMsg := fmt.Sprintf("Parameter '%s' can't be constrained to be of type %v", name, constraint.Type)
+		return errors.New(errorMsg)
+	}
+
+	bounded := constraint.Integer || constraint.Minimum != nil || constraint.Maximum != nil
+	if bounded && constraint.Type != NumberType {
+		errorMsg := fmt.Sprintf("Parameter '%s' can only be constrained to integers, or to a range, if it's of type number", name)
+		return errors.New(errorMsg)
+	}
+
+	if constraint.Minimum != nil && constraint.Maximum != nil && *constraint.Minimum > *constraint.Maximum {
+		errorMsg := fmt.Sprintf("Parameter '%s' has a minimum (%v) above its maximum (%v)", name, *constraint.Minimum, *constraint.Maximum)
+		return errors.New(errorMsg)
+	}
+	return nil
+}
+
+// The truth of a term, given the values of some of its parameters.
+type truth int
+
+const (
+	unknownTruth truth = iota
+	trueTruth
+	falseTruth
+)
+
+// A parameter of an expression whose satisfiability is being checked.
+type satisfiabilityVariable struct {
+	name string
+
+	// the literals it's compared with.
+	constants []interface{}
+
+	// true if it's used as a condition on its own.
+	condition bool
+
+	// the values which are tried for it; one from each range of values that every condition treats the same way.
+	candidates []interface{}
+}
+
+// A condition of an expression whose satisfiability is being checked.
+type satisfiabilityCondition struct {
+
+	// the parameters it depends on.
+	names []string
+
+	// true if it can't be evaluated, because it uses something which can't be given a value (like an accessor).
+	opaque bool
+}
+
+// A stage which must be true (or, if not [isTrue], must not be true) for parameters to satisfy what's being checked.
+type satisfiabilityGoal struct {
+	stage *evaluationStage
+
+	// if not nil, a term whose truth is found instead of evaluating a stage; it isn't true if its truth is unknown.
+	// Its conditions must be within the checker's term.
+	term *boolTerm
+
+	isTrue bool
+}
+
+// Searches for parameters which make an expression true (or not true).
+type satisfiabilityChecker struct {
+	expr        Expression
+	constraints map[string]VariableConstraint
+	term        *boolTerm
+
+	// what parameters have to do to satisfy the expression; usually, make it true.
+	goals []satisfiabilityGoal
+
+	// evaluates conditions.
+	evaluator Expression
+
+	variables  map[string]*satisfiabilityVariable
+	ordered    []*satisfiabilityVariable
+	conditions map[*boolTerm]*satisfiabilityCondition
+
+	// the conditions which aren't comparisons of a parameter with literals.
+	guessed []*satisfiabilityCondition
+
+	// true if every condition is a comparison of a parameter with literals,
+	// so that trying the candidates of each parameter is sure to find parameters for which the expression is true, if there are any.
+	complete bool
+
+	steps int
+}
+
+// Finds the parameters of every condition of [term], and the literals they're compared with.
+func (checker *satisfiabilityChecker) analyze(term *boolTerm) {
+
+	switch term.kind {
+
+	case andTerm, orTerm:
+		for _, operand := range term.operands {
+			checker.analyze(operand)
+		}
+
+	case conditionTerm:
+		condition := &satisfiabilityCondition{}
+		checker.conditions[term] = condition
+
+		if !checker.analyzeComparison(term.condition, condition) {
+
+			checker.complete = false
+
+			var constants []interface{}
+			checker.analyzeStage(term.condition, condition, &constants, nil)
+
+			// the literals of a condition which isn't understood are only a guess at the values which matter to its parameters.
+			for _, name := range condition.names {
+				variable := checker.variables[name]
+				variable.constants = append(variable.constants, constants...)
+			}
+			checker.guessed = append(checker.guessed, condition)
+		}
+	}
+}
+
+// Guesses that the parameters of each condition which isn't understood (like "x > y") may be compared with each other's literals.
+func (checker *satisfiabilityChecker) shareConstants() {
+
+	for _, condition := range checker.guessed {
+
+		var constants []interface{}
+		for _, name := range condition.names {
+			constants = append(constants, checker.variables[name].constants...)
+		}
+
+		for _, name := range condition.names {
+			checker.variables[name].constants = constants
+		}
+	}
+}
+
+// Analyzes [stage], if it's a comparison of a parameter with literals (or a parameter on its own), and returns true.
+// Returns false for any other stage.
+func (checker *satisfiabilityChecker) analyzeComparison(stage *evaluationStage, condition *satisfiabilityCondition) bool {
+
+	for stage.symbol == noopSymbol && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+
+	if stage.symbol == value {
+
+		variable := checker.variableOf(stage, condition)
+		if variable == nil {
+			return false
+		}
+		variable.condition = true
+		return true
+	}
+
+	left, right := stage.leftStage, stage.rightStage
+	var constants []interface{}
+
+	switch stage.symbol {
+
+	case eq, neq, gt, lt, gte, lte:
+		if left != nil && left.symbol == literal {
+			left, right = right, left
+		}
+		if right == nil || right.symbol != literal {
+			return false
+		}
+		constants = []interface{}{literalValueOf(right)}
+
+	case in, notIn:
+		elements, isList := constantElementsOf(right)
+		if !isList {
+			return false
+		}
+		constants = elements
+
+	case between, notBetween:
+		if right == nil || right.leftStage == nil || right.rightStage == nil ||
+			right.leftStage.symbol != literal || right.rightStage.symbol != literal {
+			return false
+		}
+		constants = []interface{}{literalValueOf(right.leftStage), literalValueOf(right.rightStage)}
+
+	case is, isNot:
+		constants = []interface{}{nil}
+
+	default:
+		return false
+	}
+
+	if left == nil || left.symbol != value {
+		return false
+	}
+
+	variable := checker.variableOf(left, condition)
+	if variable == nil {
+		return false
+	}
+
+	variable.constants = append(variable.constants, constants...)
+	return true
+}
+
+// Finds the parameters that [stage] depends on (other than those [shadowed] by collection functions), and the literals within it.
+func (checker *satisfiabilityChecker) analyzeStage(stage *evaluationStage, condition *satisfiabilityCondition, constants *[]interface{}, shadowed map[string]bool) {
+
+	if stage == nil {
+		return
+	}
+
+	switch stage.symbol {
+
+	case value:
+		if !shadowed[stage.name] && checker.variableOf(stage, condition) == nil {
+			condition.opaque = true
+		}
+		return
+
+	case access:
+		if !shadowed[accessorRoot(stage.name)] {
+			condition.opaque = true
+		}
+
+	case literal:
+		switch value := literalValueOf(stage).(type) {
+		case []interface{}:
+			*constants = append(*constants, value...)
+		case float64, string, bool:
+			*constants = append(*constants, value)
+		}
+	}
+
+	checker.analyzeStage(stage.leftStage, condition, constants, shadowed)
+
+	if stage.collection != nil {
+		shadowed = shadowWith(shadowed, stage.collection.parameterName)
+	}
+	checker.analyzeStage(stage.rightStage, condition, constants, shadowed)
+}
+
+// Returns the elements of [stage], if it's a list of literals that "in" can be given;
+// either an array (like "[1, 2]"), or a list in parentheses (like "(1, 2)").
+func constantElementsOf(stage *evaluationStage) ([]interface{}, bool) {
+
+	if stage == nil {
+		return nil, false
+	}
+
+	switch stage.symbol {
+
+	case literal:
+		elements, isArray := literalValueOf(stage).([]interface{})
+		return elements, isArray
+
+	case noopSymbol:
+		if stage.rightStage == nil || stage.rightStage.symbol != separate {
+			return nil, false
+		}
+		return separatedLiteralsOf(stage.rightStage)
+
+	case arrayElement:
+		// each element is added to the array built by the stages before it.
+		elements, isList := constantElementsOf(stage.leftStage)
+		if !isList || stage.rightStage == nil || stage.rightStage.symbol != literal {
+			return nil, false
+		}
+		return append(append([]interface{}{}, elements...), literalValueOf(stage.rightStage)), true
+	}
+	return nil, false
+}
+
+// Returns the value of each literal separated by commas in [stage], such as "1, 2, 3"; or false if any of them isn't a literal.
+func separatedLiteralsOf(stage *evaluationStage) ([]interface{}, bool) {
+
+	if stage == nil {
+		return nil, false
+	}
+
+	switch stage.symbol {
+
+	case literal:
+		return []interface{}{literalValueOf(stage)}, true
+
+	case separate:
+		left, isLeftList := separatedLiteralsOf(stage.leftStage)
+		right, isRightList := separatedLiteralsOf(stage.rightStage)
+		return append(left, right...), isLeftList && isRightList
+	}
+	return nil, false
+}
+
+// Returns the variable for the parameter that [stage] refers to, which [condition] depends on.
+// Returns nil if it's a parameter which can't be given a value, because it was bound.
+func (checker *satisfiabilityChecker) variableOf(stage *evaluationStage, condition *satisfiabilityCondition) *satisfiabilityVariable {
+
+	name := stage.name
+
+	if _, isBound := checker.expr.boundParameters[name]; isBound || name == placeholderParameter {
+		return nil
+	}
+
+	variable, found := checker.variables[name]
+	if !found {
+		variable = &satisfiabilityVariable{name: name}
+		checker.variables[name] = variable
+		checker.ordered = append(checker.ordered, variable)
+	}
+
+	condition.names = append(condition.names, name)
+	return variable
+}
+
+// Returns the values to try for [variable]; one from each range of values which its conditions treat the same way,
+// such as 4, 5, and 6 for a parameter which is only compared with 5.
+func (checker *satisfiabilityChecker) candidatesOf(variable *satisfiabilityVariable) []interface{} {
+
+	constraint := checker.constraints[variable.name]
+
+	var numbers []float64
+	var stringConstants []string
+	var ret []interface{}
+
+	isNumber := constraint.Type == NumberType
+	isString := constraint.Type == StringType
+	isBool := constraint.Type == BoolType || (constraint.Type == AnyType && variable.condition)
+	nullable := constraint.Nullable
+
+	for _, constant := range variable.constants {
+
+		switch value := constant.(type) {
+		case float64:
+			if !math.IsNaN(value) && !math.IsInf(value, 0) {
+				numbers = append(numbers, value)
+			}
+			isNumber = isNumber || constraint.Type == AnyType
+		case string:
+			stringConstants = append(stringConstants, value)
+			isString = isString || constraint.Type == AnyType
+		case bool:
+			isBool = isBool || constraint.Type == AnyType
+		case nil:
+			nullable = nullable || constraint.Type == AnyType
+		}
+	}
+
+	// a parameter that's used in no way which suggests its type could be anything.
+	if constraint.Type == AnyType && !isNumber && !isString && !isBool {
+		isNumber, isString, isBool = true, true, true
+	}
+
+	if isNumber {
+		candidates, exact := numberCandidates(numbers, constraint)
+		for _, number := range candidates {
+			ret = append(ret, number)
+		}
+		checker.complete = checker.complete && exact
+	}
+
+	if isString {
+		for _, candidate := range stringCandidates(stringConstants) {
+			ret = append(ret, candidate)
+		}
+	}
+
+	if isBool {
+		ret = append(ret, true, false)
+	}
+
+	if nullable {
+		ret = append(ret, nil)
+	}
+	return ret
+}
+
+// Returns a number from each range of numbers which compare the same way with each of [constants]; every constant,
+// as well as a number between each of them, one below the least, and one above the greatest (within the bounds of the [constraint]).
+// Those are the closest numbers to the constants where a step of 1 (or half the gap between them) would round back onto a constant,
+// as it does past 2^53. Returns false if a candidate still fell back onto a constant, so that some range may have gone untried.
+func numberCandidates(constants []float64, constraint VariableConstraint) ([]float64, bool) {
+
+	var ret []float64
+	exact := true
+
+	if constraint.Minimum != nil {
+		constants = append(constants, *constraint.Minimum)
+	}
+	if constraint.Maximum != nil {
+		constants = append(constants, *constraint.Maximum)
+	}
+	if len(constants) == 0 {
+		constants = []float64{0}
+	}
+
+	sort.Float64s(constants)
+
+	// the candidate past [constant] (toward [target]); or the closest number to it, if [candidate] rounded back onto it.
+	beyond := func(constant float64, candidate float64, target float64) float64 {
+
+		if candidate == constant && !math.IsInf(constant, 0) {
+			candidate = math.Nextafter(constant, target)
+		}
+		if candidate == constant && !math.IsInf(constant, 0) {
+			exact = false
+		}
+		return candidate
+	}
+
+	if constraint.Integer {
+
+		// the greatest whole number below each constant, the least above it, and the constant itself (if whole).
+		// Past 2^53, every number is whole, so the closest ones are the next whole numbers.
+		for _, constant := range constants {
+
+			below := beyond(constant, math.Min(math.Ceil(constant)-1, constant), math.Inf(-1))
+			above := beyond(constant, math.Max(math.Floor(constant)+1, constant), math.Inf(1))
+			ret = append(ret, below, math.Floor(constant), above)
+		}
+		sort.Float64s(ret)
+	} else {
+
+		ret = append(ret, beyond(constants[0], constants[0]-1, math.Inf(-1)))
+		for i, constant := range constants {
+
+			// halving each first keeps the midpoint from overflowing. There's nothing between numbers which are next to each other.
+			if i > 0 && math.Nextafter(constants[i-1], constant) < constant {
+
+				between := constants[i-1]/2 + constant/2
+				if between == constant {
+					between = constants[i-1]
+				}
+				ret = append(ret, beyond(constants[i-1], between, constant))
+			}
+			ret = append(ret, constant)
+		}
+		last := constants[len(constants)-1]
+		ret = append(ret, beyond(last, last+1, math.Inf(1)))
+	}
+
+	// remove duplicates, and anything out of bounds.
+	filtered := ret[:0]
+	for i, candidate := range ret {
+
+		if i > 0 && candidate == ret[i-1] {
+			continue
+		}
+		if (constraint.Minimum != nil && candidate < *constraint.Minimum) || (constraint.Maximum != nil && candidate > *constraint.Maximum) {
+			continue
+		}
+		filtered = append(filtered, candidate)
+	}
+	return filtered, exact
+}
+
+// Returns a string from each range of strings which compare the same way with each of [constants];
+// every constant, the empty string (which is less than any other), and the least string greater than each constant.
+func stringCandidates(constants []string) []string {
+
+	var ret []string
+	seen := make(map[string]bool)
+
+	add := func(candidate string) {
+		if !seen[candidate] {
+			seen[candidate] = true
+			ret = append(ret, candidate)
+		}
+	}
+
+	for _, constant := range constants {
+		add(constant)
+	}
+
+	add("")
+
+	for _, constant := range constants {
+		add(constant + "\x00")
+	}
+	return ret
+}
+
+// Searches for values of the remaining variables (after those in [assignment]) which make the expression true,
+// if [goal] is true; or not true, if it's false. Returns nil if there aren't any.
+func (checker *satisfiabilityChecker) search(assignment map[string]interface{}, goal bool) map[string]interface{} {
+
+	checker.steps++
+	if checker.steps > maxSatisfiabilitySteps {
+		return nil
+	}
+
+	// no parameters that follow from these can meet the goal.
+	truth := checker.truthOf(checker.term, assignment)
+	if (goal && truth == falseTruth) || (!goal && truth == trueTruth) {
+		return nil
+	}
+
+	depth := len(assignment)
+
+	if depth == len(checker.ordered) {
+
+		if checker.isTrue(assignment) != goal {
+			return nil
+		}
+
+		ret := make(map[string]interface{}, len(assignment))
+		for name, value := range assignment {
+			ret[name] = value
+		}
+		return ret
+	}
+
+	variable := checker.ordered[depth]
+
+	for _, candidate := range variable.candidates {
+
+		assignment[variable.name] = candidate
+
+		ret := checker.search(assignment, goal)
+		if ret != nil {
+			delete(assignment, variable.name)
+			return ret
+		}
+	}
+
+	delete(assignment, variable.name)
+	return nil
+}
+
+// Returns the truth of [term] given [assignment], which may not include every parameter.
+func (checker *satisfiabilityChecker) truthOf(term *boolTerm, assignment map[string]interface{}) truth {
+
+	switch term.kind {
+
+	case constantTerm:
+		if term.value {
+			return trueTruth
+		}
+		return falseTruth
+
+	case andTerm, orTerm:
+		// "false" decides the result of "&&", and "true" that of "||".
+		deciding := falseTruth
+		if term.kind == orTerm {
+			deciding = trueTruth
+		}
+
+		ret := trueTruth
+		if term.kind == orTerm {
+			ret = falseTruth
+		}
+
+		for _, operand := range term.operands {
+
+			operandTruth := checker.truthOf(operand, assignment)
+			if operandTruth == deciding {
+				return deciding
+			}
+			if operandTruth == unknownTruth {
+				ret = unknownTruth
+			}
+		}
+		return ret
+	}
+
+	condition := checker.conditions[term]
+	if condition.opaque {
+		return unknownTruth
+	}
+
+	for _, name := range condition.names {
+		if _, found := assignment[name]; !found {
+			return unknownTruth
+		}
+	}
+
+	result, err := checker.evaluate(term.condition, assignment)
+	if err != nil || !isBool(result) {
+		return unknownTruth
+	}
+
+	if result.(bool) != term.negated {
+		return trueTruth
+	}
+	return falseTruth
+}
+
+// Returns true if the given parameters meet every goal, by evaluating each of them.
+func (checker *satisfiabilityChecker) isTrue(assignment map[string]interface{}) bool {
+
+	for _, goal := range checker.goals {
+
+		if goal.term != nil {
+			if (checker.truthOf(goal.term, assignment) == trueTruth) != goal.isTrue {
+				return false
+			}
+			continue
+		}
+
+		result, err := checker.evaluate(goal.stage, assignment)
+		if (err == nil && result == true) != goal.isTrue {
+			return false
+		}
+	}
+	return true
+}
+
+func (checker *satisfiabilityChecker) evaluate(stage *evaluationStage, assignment map[string]interface{}) (ret interface{}, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	if stage == nil {
+		return nil, nil
+	}
+
+	parameters := checker.expr.withBoundParameters(MapParameters(assignment))
+	return checker.evaluator.evaluateStage(stage, &sanitizedParameters{orig: parameters})
+}
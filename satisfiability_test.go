@@ -0,0 +1,362 @@
+package govaluate
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type SatisfiabilityTest struct {
+	Name           string
+	Input          string
+	Options        ExpressionOptions
+	Constraints    map[string]VariableConstraint
+	Satisfiability Satisfiability
+}
+
+func TestCheckSatisfiability(test *testing.T) {
+
+	zero := 0.0
+	hundred := 100.0
+
+	satisfiabilityTests := []SatisfiabilityTest{
+		{
+			Name:           "Contradictory range",
+			Input:          "age > 30 && age < 20",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Range",
+			Input:          "age > 30 && age < 40",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Open range",
+			Input:          "age > 30 && age < 31",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Open range of integers",
+			Input:          "age > 30 && age < 31",
+			Constraints:    map[string]VariableConstraint{"age": {Type: NumberType, Integer: true}},
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Closed range of integers",
+			Input:          "age >= 30.5 && age <= 31",
+			Constraints:    map[string]VariableConstraint{"age": {Type: NumberType, Integer: true}},
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Below a large number",
+			Input:          "x < 1e20",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Above a large number",
+			Input:          "x > 1e17",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Between large numbers",
+			Input:          "x > 9007199254740992 && x < 9007199254740996",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Between large numbers next to each other",
+			Input:          "x > 9007199254740992 && x < 9007199254740994",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Above a large integer",
+			Input:          "x > 1e17 || x < -1e17",
+			Constraints:    map[string]VariableConstraint{"x": {Type: NumberType, Integer: true}},
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Large numbers in general",
+			Input:          "x > 1e300 || x <= 1e300",
+			Satisfiability: Tautology,
+		},
+		{
+			Name:           "Complementary comparisons",
+			Input:          "age > 30 || age <= 30",
+			Satisfiability: Tautology,
+		},
+		{
+			Name:           "Outside of constraints",
+			Input:          "score < 0 || score > 100",
+			Constraints:    map[string]VariableConstraint{"score": {Type: NumberType, Minimum: &zero, Maximum: &hundred}},
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Within constraints",
+			Input:          "score between 0 and 100",
+			Options:        ExpressionOptions{WordOperators: true},
+			Constraints:    map[string]VariableConstraint{"score": {Type: NumberType, Minimum: &zero, Maximum: &hundred}},
+			Satisfiability: Tautology,
+		},
+		{
+			Name:           "Equality",
+			Input:          "name == 'bob' && name != 'bob'",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Equality with a literal on the left",
+			Input:          "'bob' == name && name == 'alice'",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Lexicographic comparison",
+			Input:          "name > 'a' && name < 'b' && name != 'ab'",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Lexicographic contradiction",
+			Input:          "name > 'b' && name < 'a'",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Membership",
+			Input:          "status in ['open', 'closed'] && status not in ['open', 'closed']",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Membership of a list in parentheses",
+			Input:          "status in ('open', 'closed') && status not in ('open', 'closed')",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Membership of a range",
+			Input:          "x in [1, 2, 3] && x > 2",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Membership outside a range",
+			Input:          "x in [1, 2, 3] && x > 3",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Bools",
+			Input:          "(a || b) && !a && !b",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Excluded middle",
+			Input:          "flag || !flag",
+			Satisfiability: Tautology,
+		},
+		{
+			Name:           "Bool comparison",
+			Input:          "flag == true && !flag",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Mixed",
+			Input:          "(age >= 18 && country == 'NZ') || (vip && age >= 16)",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Unsatisfiable across several parameters",
+			Input:          "(x > 1 || y == 'a') && (x <= 1 || y == 'b') && y != 'a' && y != 'b'",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Nulls",
+			Input:          "x is null || x == 1",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Not nullable",
+			Input:          "x is null",
+			Constraints:    map[string]VariableConstraint{"x": {Type: NumberType}},
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Three-valued logic",
+			Input:          "x > 1 || x <= 1",
+			Options:        ExpressionOptions{ThreeValuedLogic: true},
+			Constraints:    map[string]VariableConstraint{"x": {Type: NumberType, Nullable: true}},
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Constant",
+			Input:          "1 > 2",
+			Satisfiability: Unsatisfiable,
+		},
+		{
+			Name:           "Comparison of parameters",
+			Input:          "x > y && y > x",
+			Satisfiability: UnknownSatisfiability,
+		},
+		{
+			Name:           "Comparison of parameters which is satisfied",
+			Input:          "x > y && y == 1",
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Function",
+			Input:          "len(name) > 3 || name == 'x'",
+			Options:        ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Satisfiability: Satisfiable,
+		},
+		{
+			Name:           "Function which isn't understood",
+			Input:          "len(name) > 3 && len(name) < 2",
+			Options:        ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Satisfiability: UnknownSatisfiability,
+		},
+	}
+
+	for _, satisfiabilityTest := range satisfiabilityTests {
+
+		expression, err := NewExpressionWithOptions(satisfiabilityTest.Input, satisfiabilityTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", satisfiabilityTest.Name, err)
+			continue
+		}
+
+		result, err := expression.CheckSatisfiability(satisfiabilityTest.Constraints)
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", satisfiabilityTest.Name, err)
+			continue
+		}
+
+		if result.Satisfiability != satisfiabilityTest.Satisfiability {
+			test.Errorf("Test '%s' failed: expected %v, got %v", satisfiabilityTest.Name, satisfiabilityTest.Satisfiability, result.Satisfiability)
+		}
+
+		// examples must be genuine, and given for every satisfiable expression.
+		expectsExample := result.Satisfiability == Satisfiable || result.Satisfiability == Tautology
+		if (result.Example != nil) != expectsExample {
+			test.Errorf("Test '%s' failed: unexpected example %v", satisfiabilityTest.Name, result.Example)
+		} else if result.Example != nil {
+
+			value, err := expression.Evaluate(result.Example)
+			if err != nil || value != true {
+				test.Errorf("Test '%s' failed: expected the example %v to be true, got %v (%v)", satisfiabilityTest.Name, result.Example, value, err)
+			}
+		}
+
+		expectsCounterexample := result.Satisfiability != Tautology
+		if (result.Counterexample != nil) != expectsCounterexample {
+			test.Errorf("Test '%s' failed: unexpected counterexample %v", satisfiabilityTest.Name, result.Counterexample)
+		} else if result.Counterexample != nil {
+
+			value, err := expression.Evaluate(result.Counterexample)
+			if err == nil && value == true {
+				test.Errorf("Test '%s' failed: expected the counterexample %v not to be true", satisfiabilityTest.Name, result.Counterexample)
+			}
+		}
+	}
+}
+
+func TestSatisfiabilityExample(test *testing.T) {
+
+	expression, err := NewExpression("age > 30 && age < 40 && name == 'bob'")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	result, err := expression.CheckSatisfiability(nil)
+	if err != nil {
+		test.Fatalf("Unable to check satisfiability: %v", err)
+	}
+
+	if result.Example["age"] != 35.0 || result.Example["name"] != "bob" {
+		test.Errorf("Expected an example of age 35 and name 'bob', got %v", result.Example)
+	}
+}
+
+func TestSatisfiabilityFailure(test *testing.T) {
+
+	one := 1.0
+	zero := 0.0
+
+	constraints := []map[string]VariableConstraint{
+		{"x": {Type: ArrayType}},
+		{"x": {Type: StringType, Minimum: &one}},
+		{"x": {Integer: true}},
+		{"x": {Type: NumberType, Minimum: &one, Maximum: &zero}},
+	}
+
+	expression, err := NewExpression("x > 1")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	for _, constraint := range constraints {
+
+		_, err = expression.CheckSatisfiability(constraint)
+		if err == nil || !strings.Contains(err.Error(), "Parameter 'x'") {
+			test.Errorf("Expected the constraint %v to be invalid, got %v", constraint, err)
+		}
+	}
+}
+
+// Tests that the satisfiability of randomly generated expressions agrees with evaluating them for every combination of parameters.
+func TestSatisfiabilityOfRandomExpressions(test *testing.T) {
+
+	random := rand.New(rand.NewSource(44))
+
+	zero := 0.0
+	three := 3.0
+
+	constraints := map[string]VariableConstraint{
+		"a": {Type: BoolType},
+		"b": {Type: BoolType},
+		"x": {Type: NumberType, Integer: true, Minimum: &zero, Maximum: &three},
+		"s": {Type: StringType},
+	}
+	conditions := []string{"a", "b", "x == 1", "x != 2", "x > 1", "x <= 0", "x in [1, 3]", "s == 'p'", "s > 'p'", "s in ['p', 'q']", "true"}
+
+	var assignments []map[string]interface{}
+	for _, a := range []interface{}{true, false} {
+		for _, b := range []interface{}{true, false} {
+			for _, x := range []interface{}{0.0, 1.0, 2.0, 3.0} {
+				for _, s := range []interface{}{"", "p", "pa", "q", "r"} {
+					assignments = append(assignments, map[string]interface{}{"a": a, "b": b, "x": x, "s": s})
+				}
+			}
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+
+		input := randomLogic(random, conditions, 4)
+
+		expression, err := NewExpression(input)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		result, err := expression.CheckSatisfiability(constraints)
+		if err != nil {
+			test.Fatalf("Unable to check the satisfiability of '%s': %v", input, err)
+		}
+
+		matches := 0
+		for _, assignment := range assignments {
+
+			value, err := expression.Evaluate(assignment)
+			if err != nil {
+				test.Fatalf("Unable to evaluate '%s' with %v: %v", input, assignment, err)
+			}
+			if value == true {
+				matches++
+			}
+		}
+
+		expected := Satisfiable
+		switch matches {
+		case 0:
+			expected = Unsatisfiable
+		case len(assignments):
+			expected = Tautology
+		}
+
+		if result.Satisfiability != expected {
+			test.Errorf("Expected '%s' to be %v, got %v", input, expected, result.Satisfiability)
+		}
+	}
+}
@@ -0,0 +1,556 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the most clauses (or terms) that ToCNF (or ToDNF) will produce, since converting to a normal form can grow an expression exponentially.
+const maxNormalFormClauses int = 4096
+
+// Simplify returns an equivalent expression with redundant logic removed, such as "(a && true) || (a && b)" simplified to "a".
+// It applies the identity and annihilator laws ("a && true" is "a", and "a || true" is "true"), absorption ("a || (a && b)" is "a"),
+// and De Morgan's laws (so negations are moved in to what they negate, where "!(a == b)" becomes "a != b");
+// removes double negations and duplicates; and flattens nested "&&" and "||". Constant parts of the expression are evaluated.
+// This applies anywhere in the expression, including within the arguments of functions and the branches of ternaries.
+// The order in which operands are evaluated is kept, so "x != null && x > 1" stays that way.
+//
+// The simplified expression gives the same result as the original for any parameters which the original can be evaluated with.
+// Since it may evaluate less, it can also succeed where the original would fail; "foo > 1 || true" is simply "true".
+func (expr Expression) Simplify() (*Expression, error) {
+
+	simplifier := &stageSimplifier{options: expr.options}
+
+	stage, err := simplifier.simplifyStage(expr.evaluationStages)
+	if err != nil {
+		return nil, err
+	}
+	return expr.rewrite(stage)
+}
+
+// ToCNF returns an equivalent expression in conjunctive normal form; a series of clauses joined by "&&",
+// each of which is a series of (possibly negated) conditions joined by "||", as in "(a || !b) && (c || d)".
+// Anything other than "&&", "||", "!", and boolean literals is a condition, which is simplified (see [Expression.Simplify]) but not broken up.
+//
+// The result gives the same result as the original for any parameters with which every one of its conditions can be evaluated.
+// Unlike with Simplify, it may need to evaluate conditions which the original would have skipped;
+// for instance, "(x != null && x > 1) || y" becomes "(x != null || y) && (x > 1 || y)", which fails to compare a null "x" if "y" is true.
+// Fails if the result would have more than 4096 clauses.
+func (expr Expression) ToCNF() (*Expression, error) {
+	return expr.toNormalForm(andTerm)
+}
+
+// ToDNF returns an equivalent expression in disjunctive normal form; a series of terms joined by "||",
+// each of which is a series of (possibly negated) conditions joined by "&&", as in "(a && !b) || (c && d)".
+// This is otherwise the same as [Expression.ToCNF].
+func (expr Expression) ToDNF() (*Expression, error) {
+	return expr.toNormalForm(orTerm)
+}
+
+func (expr Expression) toNormalForm(outer boolTermKind) (*Expression, error) {
+
+	simplifier := &stageSimplifier{options: expr.options}
+
+	if expr.evaluationStages == nil {
+		return expr.rewrite(nil)
+	}
+
+	term, err := simplifier.termOf(expr.evaluationStages, false)
+	if err != nil {
+		return nil, err
+	}
+
+	term, err = simplifier.normalize(simplifier.simplify(term), outer)
+	if err != nil {
+		return nil, err
+	}
+	return expr.rewrite(term.stage())
+}
+
+type boolTermKind int
+
+const (
+	conditionTerm boolTermKind = iota
+	constantTerm
+	andTerm
+	orTerm
+)
+
+// A boolTerm is the logic of an expression (its "&&", "||", "!", and boolean literals) taken apart from the conditions it operates on,
+// with negations moved in to the conditions themselves.
+type boolTerm struct {
+	kind boolTermKind
+
+	// the operands of "&&" and "||", in the order they're evaluated.
+	operands []*boolTerm
+
+	// for conditions, the stage which is evaluated; and whether it's negated.
+	condition *evaluationStage
+	negated   bool
+
+	// for constants.
+	value bool
+
+	// identifies terms which are the same, so that duplicates can be found.
+	key string
+
+	// for conditions, the key of their negation; so that "a && !a", or "x == 1 && x != 1", is known to be false.
+	complementKey string
+}
+
+// Simplifies the logic of stages, which are otherwise left as they are.
+type stageSimplifier struct {
+	options ExpressionOptions
+}
+
+// Returns a simplified copy of [stage], whose own logic (and that of every stage within it) is simplified.
+func (simplifier *stageSimplifier) simplifyStage(stage *evaluationStage) (*evaluationStage, error) {
+
+	if stage == nil {
+		return nil, nil
+	}
+
+	if isLogicalStage(stage) {
+
+		term, err := simplifier.termOf(stage, false)
+		if err != nil {
+			return nil, err
+		}
+		return simplifier.simplify(term).stage(), nil
+	}
+
+	return simplifier.simplifyOperands(stage)
+}
+
+// Returns a copy of [stage], whose operands are simplified.
+func (simplifier *stageSimplifier) simplifyOperands(stage *evaluationStage) (*evaluationStage, error) {
+
+	var err error
+
+	ret := *stage
+
+	ret.leftStage, err = simplifier.simplifyStage(stage.leftStage)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.rightStage, err = simplifier.simplifyStage(stage.rightStage)
+	if err != nil {
+		return nil, err
+	}
+
+	// anything which became constant is evaluated now.
+	binder := &stageBinder{
+		options:   simplifier.options,
+		known:     MapParameters(map[string]interface{}{}),
+		evaluator: Expression{ChecksTypes: true, options: simplifier.options},
+	}
+	return binder.fold(&ret, nil), nil
+}
+
+// Returns the logic of [stage] (negated, if [negated]) as a term.
+func (simplifier *stageSimplifier) termOf(stage *evaluationStage, negated bool) (*boolTerm, error) {
+
+	for stage.symbol == noopSymbol && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+
+	switch stage.symbol {
+
+	case literal:
+		value, isBool := literalValueOf(stage).(bool)
+		if isBool {
+			return constantBoolTerm(value != negated), nil
+		}
+
+	case invert:
+		return simplifier.termOf(stage.rightStage, !negated)
+
+	case and, or:
+		// De Morgan's laws; "!(a && b)" is "!a || !b".
+		kind := andTerm
+		if (stage.symbol == or) != negated {
+			kind = orTerm
+		}
+
+		left, err := simplifier.termOf(stage.leftStage, negated)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := simplifier.termOf(stage.rightStage, negated)
+		if err != nil {
+			return nil, err
+		}
+		return newCompoundTerm(kind, []*boolTerm{left, right}), nil
+	}
+
+	condition, err := simplifier.simplifyOperands(stage)
+	if err != nil {
+		return nil, err
+	}
+
+	// simplifying the condition may have made it constant, or reduced it to logic (like "(a && b) ?? c" to "a && b").
+	if condition.symbol == literal || isLogicalStage(condition) {
+
+		_, isBool := literalValueOf(condition).(bool)
+		if condition.symbol != literal || isBool {
+			return simplifier.termOf(condition, negated)
+		}
+	}
+
+	// comparators which have a negation, like "==" and "!=", are negated by switching them.
+	_, hasComplement := complementarySymbols[condition.symbol]
+	if negated && hasComplement {
+
+		condition, err = simplifier.complementOf(condition)
+		if err != nil {
+			return nil, err
+		}
+		negated = false
+	}
+
+	key := simplifier.keyOf(condition)
+	complementKey := "!(" + key + ")"
+
+	if negated {
+		key, complementKey = complementKey, key
+	} else if hasComplement {
+
+		complementary, err := simplifier.complementOf(condition)
+		if err != nil {
+			return nil, err
+		}
+		complementKey = simplifier.keyOf(complementary)
+	}
+
+	return &boolTerm{
+		kind:          conditionTerm,
+		condition:     condition,
+		negated:       negated,
+		key:           key,
+		complementKey: complementKey,
+	}, nil
+}
+
+// Returns a copy of [condition] (which has a complementary symbol) that gives the opposite result, as "x != 1" does for "x == 1".
+func (simplifier *stageSimplifier) complementOf(condition *evaluationStage) (*evaluationStage, error) {
+
+	var err error
+
+	ret := *condition
+	ret.symbol = complementarySymbols[condition.symbol]
+	ret.operator = stageSymbolMap[ret.symbol]
+
+	if ret.symbol == req || ret.symbol == nreq {
+		ret.operator = makeRegexStage(simplifier.options.RegexCache, simplifier.options.Limits, ret.symbol == nreq)
+	}
+
+	if isMatchSymbol(ret.symbol) {
+		ret.operator, err = planMatchOperator(ret.symbol, ret.rightStage, simplifier.options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ret, nil
+}
+
+// Returns the key of a condition.
+// The only sure way to know that two conditions are the same is if they're written the same,
+// so those which can't be written out are only the same as themselves.
+func (simplifier *stageSimplifier) keyOf(condition *evaluationStage) string {
+
+	key, err := formatStage(condition, simplifier.options)
+	if err != nil {
+		return fmt.Sprintf("%p", condition)
+	}
+	return key
+}
+
+// the symbols whose results are the negation of each other's.
+var complementarySymbols = map[OperatorSymbol]OperatorSymbol{
+	eq:         neq,
+	neq:        eq,
+	req:        nreq,
+	nreq:       req,
+	in:         notIn,
+	notIn:      in,
+	like:       notLike,
+	notLike:    like,
+	ilike:      notIlike,
+	notIlike:   ilike,
+	glob:       notGlob,
+	notGlob:    glob,
+	between:    notBetween,
+	notBetween: between,
+	is:         isNot,
+	isNot:      is,
+}
+
+// Returns a simplified copy of [term].
+func (simplifier *stageSimplifier) simplify(term *boolTerm) *boolTerm {
+
+	if term.kind != andTerm && term.kind != orTerm {
+		return term
+	}
+
+	// "false" decides the result of "&&", and "true" that of "||".
+	deciding := term.kind == orTerm
+
+	var operands []*boolTerm
+	seen := make(map[string]bool)
+
+	for _, operand := range term.operands {
+
+		operand = simplifier.simplify(operand)
+
+		// flatten "a && (b && c)" into "a && b && c".
+		nested := []*boolTerm{operand}
+		if operand.kind == term.kind {
+			nested = operand.operands
+		}
+
+		for _, candidate := range nested {
+
+			if candidate.kind == constantTerm {
+				if candidate.value == deciding {
+					return candidate
+				}
+				continue
+			}
+
+			if seen[candidate.key] {
+				continue
+			}
+			seen[candidate.key] = true
+			operands = append(operands, candidate)
+		}
+	}
+
+	// "a && !a" is false; unless a is null, which can't be ruled out with three-valued logic.
+	if !simplifier.options.ThreeValuedLogic {
+		for _, operand := range operands {
+			if operand.kind == conditionTerm && seen[operand.complementKey] {
+				return constantBoolTerm(deciding)
+			}
+		}
+	}
+
+	operands = absorb(operands, term.kind)
+
+	switch len(operands) {
+	case 0:
+		return constantBoolTerm(!deciding)
+	case 1:
+		return operands[0]
+	}
+	return newCompoundTerm(term.kind, operands)
+}
+
+// Applies absorption to the [operands] of a term of the given [kind], returning those which remain.
+// Within "&&", an operand which is an "||" of (at least) everything in another operand is redundant; "a && (a || b)" is "a".
+// Likewise for "||", where "a || (a && b)" is "a".
+func absorb(operands []*boolTerm, kind boolTermKind) []*boolTerm {
+
+	var ret []*boolTerm
+
+	sets := make([]map[string]bool, len(operands))
+	for i, operand := range operands {
+
+		sets[i] = map[string]bool{operand.key: true}
+		if operand.kind != conditionTerm && operand.kind != kind {
+
+			sets[i] = make(map[string]bool)
+			for _, nested := range operand.operands {
+				sets[i][nested.key] = true
+			}
+		}
+	}
+
+	for i, operand := range operands {
+
+		absorbed := false
+		for j := range operands {
+
+			if i == j || len(sets[j]) > len(sets[i]) || !isSubset(sets[j], sets[i]) {
+				continue
+			}
+
+			// of two operands which are the same, the first is kept.
+			if len(sets[j]) < len(sets[i]) || j < i {
+				absorbed = true
+				break
+			}
+		}
+
+		if !absorbed {
+			ret = append(ret, operand)
+		}
+	}
+	return ret
+}
+
+// Returns [term], which is simplified, in the normal form whose [outer] terms are made of the other kind of term;
+// "&&" of "||" for conjunctive normal form, or "||" of "&&" for disjunctive.
+func (simplifier *stageSimplifier) normalize(term *boolTerm, outer boolTermKind) (*boolTerm, error) {
+
+	groups, err := groupsOf(term, outer)
+	if err != nil {
+		return nil, err
+	}
+
+	inner := andTerm
+	if outer == andTerm {
+		inner = orTerm
+	}
+
+	operands := make([]*boolTerm, len(groups))
+	for i, group := range groups {
+		operands[i] = newCompoundTerm(inner, group)
+	}
+	return simplifier.simplify(newCompoundTerm(outer, operands)), nil
+}
+
+// Returns the groups of terms which [term] is made of, in the normal form whose [outer] terms are made of the other kind.
+// Terms of the [outer] kind combine the groups of their operands; the other kind distributes over them,
+// so that in conjunctive normal form, "a || (b && c)" is "(a || b) && (a || c)".
+func groupsOf(term *boolTerm, outer boolTermKind) ([][]*boolTerm, error) {
+
+	if term.kind != andTerm && term.kind != orTerm {
+		return [][]*boolTerm{{term}}, nil
+	}
+
+	var ret [][]*boolTerm
+
+	if term.kind == outer {
+		for _, operand := range term.operands {
+
+			groups, err := groupsOf(operand, outer)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, groups...)
+		}
+		return ret, nil
+	}
+
+	ret = [][]*boolTerm{nil}
+
+	for _, operand := range term.operands {
+
+		groups, err := groupsOf(operand, outer)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ret)*len(groups) > maxNormalFormClauses {
+			errorMsg := fmt.Sprintf("Expression is too large to convert to a normal form, it would have over %d clauses", maxNormalFormClauses)
+			return nil, errors.New(errorMsg)
+		}
+
+		var distributed [][]*boolTerm
+		for _, existing := range ret {
+			for _, group := range groups {
+
+				combined := make([]*boolTerm, 0, len(existing)+len(group))
+				combined = append(combined, existing...)
+				combined = append(combined, group...)
+				distributed = append(distributed, combined)
+			}
+		}
+		ret = distributed
+	}
+	return ret, nil
+}
+
+// Returns the stage which evaluates [term].
+func (term *boolTerm) stage() *evaluationStage {
+
+	switch term.kind {
+
+	case constantTerm:
+		return literalStage(term.value)
+
+	case conditionTerm:
+		if term.negated {
+			return &evaluationStage{symbol: invert, rightStage: term.condition}
+		}
+		return term.condition
+	}
+
+	symbol := and
+	if term.kind == orTerm {
+		symbol = or
+	}
+
+	ret := term.operands[0].stage()
+	for _, operand := range term.operands[1:] {
+		ret = &evaluationStage{symbol: symbol, leftStage: ret, rightStage: operand.stage()}
+	}
+	return ret
+}
+
+func constantBoolTerm(value bool) *boolTerm {
+
+	return &boolTerm{
+		kind:  constantTerm,
+		value: value,
+		key:   fmt.Sprintf("%v", value),
+	}
+}
+
+func newCompoundTerm(kind boolTermKind, operands []*boolTerm) *boolTerm {
+
+	if len(operands) == 1 {
+		return operands[0]
+	}
+
+	keys := make([]string, len(operands))
+	for i, operand := range operands {
+		keys[i] = operand.key
+	}
+
+	// operands are kept in order, but the key doesn't depend on it, so that "a && b" is known to be the same as "b && a".
+	sort.Strings(keys)
+
+	separator := " && "
+	if kind == orTerm {
+		separator = " || "
+	}
+
+	return &boolTerm{
+		kind:     kind,
+		operands: operands,
+		key:      "(" + strings.Join(keys, separator) + ")",
+	}
+}
+
+// Returns true if the given stage is one of the logical operators which the simplifier takes apart: "&&", "||", or "!".
+func isLogicalStage(stage *evaluationStage) bool {
+
+	for stage != nil && stage.symbol == noopSymbol && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+
+	if stage == nil {
+		return false
+	}
+
+	switch stage.symbol {
+	case and, or, invert:
+		return true
+	}
+	return false
+}
+
+func isSubset(subset map[string]bool, set map[string]bool) bool {
+
+	for key := range subset {
+		if !set[key] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+package govaluate
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type SimplifyTest struct {
+	Name     string
+	Input    string
+	Options  ExpressionOptions
+	Expected string
+}
+
+func TestSimplify(test *testing.T) {
+
+	simplifyTests := []SimplifyTest{
+		{
+			Name:     "Identity and absorption",
+			Input:    "(a && true) || (a && b)",
+			Expected: "a",
+		},
+		{
+			Name:     "Annihilator",
+			Input:    "a || (b && true) || !false",
+			Expected: "true",
+		},
+		{
+			Name:     "Double negation",
+			Input:    "!(!a) && !(!(!(!b)))",
+			Expected: "a && b",
+		},
+		{
+			Name:     "De Morgan",
+			Input:    "!(a && b)",
+			Expected: "!a || !b",
+		},
+		{
+			Name:     "Negated comparators",
+			Input:    "!(x == 1 || y in [1, 2] || name like 'a%' || z is null)",
+			Options:  ExpressionOptions{PatternOperators: true},
+			Expected: "x != 1 && y not in [1, 2] && name not like 'a%' && z is not null",
+		},
+		{
+			Name:     "Negated ordering",
+			Input:    "!(x > 1)",
+			Expected: "!(x > 1)",
+		},
+		{
+			Name:     "Duplicates",
+			Input:    "a && b && a && (b && c)",
+			Expected: "a && b && c",
+		},
+		{
+			Name:     "Duplicates in a different order",
+			Input:    "(a || b) && (b || a)",
+			Expected: "a || b",
+		},
+		{
+			Name:     "Flattening",
+			Input:    "(a || (b || (c || d)))",
+			Expected: "a || b || c || d",
+		},
+		{
+			Name:     "Complement",
+			Input:    "(x == 1 && !(x == 1)) || (a && !a) || b",
+			Expected: "b",
+		},
+		{
+			Name:     "Complement of a negated comparator",
+			Input:    "x == 1 || x != 1",
+			Expected: "true",
+		},
+		{
+			Name:     "Absorption of a larger term",
+			Input:    "(a || b) && (c || b || a) && (a || b || d)",
+			Expected: "a || b",
+		},
+		{
+			Name:     "Order is kept",
+			Input:    "x is not null && x > 1 && true",
+			Expected: "x is not null && x > 1",
+		},
+		{
+			Name:     "Constants",
+			Input:    "(x > 1 + 1 && 'a' == 'a') || [y] == 2 * 3",
+			Expected: "x > 2 || y == 6",
+		},
+		{
+			Name:     "Within other stages",
+			Input:    "(a && true ? x : y) + len(items) > 1 && any(items, # > 1 || false)",
+			Options:  ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Expected: "(a ? x : y) + len(items) > 1 && any(items, # > 1)",
+		},
+		{
+			Name:     "Within arguments",
+			Input:    "upper(!(!a) ? 'y' : 'n') == 'Y'",
+			Options:  ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Expected: "upper(a ? 'y' : 'n') == 'Y'",
+		},
+		{
+			Name:     "Non-logical expression",
+			Input:    "x * (y + 1)",
+			Expected: "x * (y + 1)",
+		},
+		{
+			Name:     "Three-valued logic keeps complements",
+			Input:    "(a || !a) && b && b",
+			Options:  ExpressionOptions{ThreeValuedLogic: true},
+			Expected: "(a || !a) && b",
+		},
+		{
+			Name:     "Word operators",
+			Input:    "not (x between 1 and 5) and not (not a)",
+			Options:  ExpressionOptions{WordOperators: true},
+			Expected: "x not between 1 and 5 && a",
+		},
+	}
+
+	for _, simplifyTest := range simplifyTests {
+
+		expression, err := NewExpressionWithOptions(simplifyTest.Input, simplifyTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", simplifyTest.Name, err)
+			continue
+		}
+
+		simplified, err := expression.Simplify()
+		if err != nil {
+			test.Errorf("Test '%s' failed to simplify: %v", simplifyTest.Name, err)
+			continue
+		}
+
+		if simplified.String() != simplifyTest.Expected {
+			test.Errorf("Test '%s' failed: expected '%s', got '%s'", simplifyTest.Name, simplifyTest.Expected, simplified.String())
+		}
+	}
+}
+
+func TestNormalForms(test *testing.T) {
+
+	normalFormTests := []struct {
+		Name string
+		Input,
+		CNF,
+		DNF string
+	}{
+		{
+			Name:  "Distribution",
+			Input: "a || (b && c)",
+			CNF:   "(a || b) && (a || c)",
+			DNF:   "a || b && c",
+		},
+		{
+			Name:  "Product of sums",
+			Input: "(a || b) && (c || d)",
+			CNF:   "(a || b) && (c || d)",
+			DNF:   "a && c || a && d || b && c || b && d",
+		},
+		{
+			Name:  "Negation",
+			Input: "!(a || (b && x > 1))",
+			CNF:   "!a && (!b || !(x > 1))",
+			DNF:   "!a && !b || !a && !(x > 1)",
+		},
+		{
+			Name:  "Redundant clauses",
+			Input: "(a && b) || (a && !b) || a",
+			CNF:   "a",
+			DNF:   "a",
+		},
+		{
+			Name:  "Contradiction",
+			Input: "(a || b) && !a && !b",
+			CNF:   "(a || b) && !a && !b",
+			DNF:   "false",
+		},
+		{
+			Name:  "Condition",
+			Input: "x + 1 > y",
+			CNF:   "x + 1 > y",
+			DNF:   "x + 1 > y",
+		},
+	}
+
+	for _, normalFormTest := range normalFormTests {
+
+		expression, err := NewExpression(normalFormTest.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", normalFormTest.Name, err)
+			continue
+		}
+
+		cnf, err := expression.ToCNF()
+		if err != nil {
+			test.Errorf("Test '%s' failed to convert to CNF: %v", normalFormTest.Name, err)
+		} else if cnf.String() != normalFormTest.CNF {
+			test.Errorf("Test '%s' failed: expected the CNF '%s', got '%s'", normalFormTest.Name, normalFormTest.CNF, cnf.String())
+		}
+
+		dnf, err := expression.ToDNF()
+		if err != nil {
+			test.Errorf("Test '%s' failed to convert to DNF: %v", normalFormTest.Name, err)
+		} else if dnf.String() != normalFormTest.DNF {
+			test.Errorf("Test '%s' failed: expected the DNF '%s', got '%s'", normalFormTest.Name, normalFormTest.DNF, dnf.String())
+		}
+	}
+}
+
+func TestNormalFormTooLarge(test *testing.T) {
+
+	var clauses []string
+	for i := 0; i < 13; i++ {
+		clauses = append(clauses, fmt.Sprintf("(a%d && b%d)", i, i))
+	}
+
+	expression, err := NewExpression(strings.Join(clauses, " || "))
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, err = expression.ToCNF()
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		test.Errorf("Expected a CNF of 8192 clauses to fail, got %v", err)
+	}
+
+	dnf, err := expression.ToDNF()
+	if err != nil || len(dnf.Vars()) != 26 {
+		test.Errorf("Expected the DNF to be unchanged, got %v (%v)", dnf, err)
+	}
+}
+
+// Tests that randomly generated expressions evaluate the same once simplified, or converted to a normal form, for every combination of parameters.
+func TestSimplifyEquivalence(test *testing.T) {
+
+	random := rand.New(rand.NewSource(43))
+
+	domains := map[string][]interface{}{
+		"a": {true, false},
+		"b": {true, false},
+		"c": {true, false},
+		"x": {0.0, 1.0, 2.0, 3.0},
+		"y": {1.0, nil},
+	}
+	conditions := []string{"a", "b", "c", "x == 1", "x != 2", "x > 1", "x in [1, 3]", "y is null", "true", "false"}
+
+	checkEquivalence(test, random, ExpressionOptions{}, domains, conditions)
+
+	// with three-valued logic, some laws (like "a || !a" being true) don't hold for null.
+	domains = map[string][]interface{}{
+		"a": {true, false, nil},
+		"b": {true, false, nil},
+		"c": {true, false, nil},
+		"x": {0.0, 1.0, 2.0, nil},
+	}
+	conditions = []string{"a", "b", "c", "x == 1", "x != 2", "x > 1", "x in [1, 3]", "x is null", "null", "true", "false"}
+
+	checkEquivalence(test, random, ExpressionOptions{ThreeValuedLogic: true}, domains, conditions)
+}
+
+func checkEquivalence(test *testing.T, random *rand.Rand, options ExpressionOptions, domains map[string][]interface{}, conditions []string) {
+
+	names := []string{"a", "b", "c", "x", "y"}
+
+	var assignments []map[string]interface{}
+	assignments = append(assignments, map[string]interface{}{})
+
+	for _, name := range names {
+
+		var expanded []map[string]interface{}
+		for _, assignment := range assignments {
+			for _, value := range domains[name] {
+
+				next := map[string]interface{}{name: value}
+				for other, otherValue := range assignment {
+					next[other] = otherValue
+				}
+				expanded = append(expanded, next)
+			}
+		}
+
+		if len(domains[name]) > 0 {
+			assignments = expanded
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+
+		input := randomLogic(random, conditions, 4)
+
+		expression, err := NewExpressionWithOptions(input, options)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		transforms := map[string]func() (*Expression, error){
+			"Simplify": expression.Simplify,
+			"ToCNF":    expression.ToCNF,
+			"ToDNF":    expression.ToDNF,
+		}
+
+		for transformName, transform := range transforms {
+
+			transformed, err := transform()
+			if err != nil {
+				test.Errorf("%s failed on '%s': %v", transformName, input, err)
+				continue
+			}
+
+			for _, assignment := range assignments {
+
+				expected, err := expression.Evaluate(assignment)
+				if err != nil {
+					test.Fatalf("Unable to evaluate '%s' with %v: %v", input, assignment, err)
+				}
+
+				actual, err := transformed.Evaluate(assignment)
+				if err != nil || !reflect.DeepEqual(actual, expected) {
+					test.Errorf("%s of '%s' is '%s', which evaluates to %v (%v) rather than %v, given %v",
+						transformName, input, transformed.String(), actual, err, expected, assignment)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Returns a random expression of "&&", "||", and "!" over the given conditions, nested at most [depth] times.
+func randomLogic(random *rand.Rand, conditions []string, depth int) string {
+
+	choice := random.Intn(5)
+	if depth == 0 || choice == 0 {
+		return conditions[random.Intn(len(conditions))]
+	}
+
+	switch choice {
+	case 1:
+		return "!(" + randomLogic(random, conditions, depth-1) + ")"
+	case 2, 3:
+		return "(" + randomLogic(random, conditions, depth-1) + " && " + randomLogic(random, conditions, depth-1) + ")"
+	}
+	return "(" + randomLogic(random, conditions, depth-1) + " || " + randomLogic(random, conditions, depth-1) + ")"
+}
@@ -8,6 +8,7 @@ import (
 type QueryTest struct {
 	Name     string
 	Input    string
+	Options  ExpressionOptions
 	Expected string
 }
 
@@ -131,6 +132,26 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "foo IN (1, 2, 3)",
 			Expected: "[foo] in ( 1 , 2 , 3 )",
 		},
+		{
+			Name:     "Membership operator with array literal",
+			Input:    "foo in [1, 2, 3]",
+			Expected: "[foo] in ( 1 , 2 , 3 )",
+		},
+		{
+			Name:     "Membership of a single-element array literal",
+			Input:    "foo in [1,]",
+			Expected: "[foo] in ( 1 )",
+		},
+		{
+			Name:     "Membership of an array literal with a trailing comma",
+			Input:    "foo in [1, 2,]",
+			Expected: "[foo] in ( 1 , 2 )",
+		},
+		{
+			Name:     "Negated membership operator",
+			Input:    "foo not in [1, 2]",
+			Expected: "[foo] NOT IN ( 1 , 2 )",
+		},
 		{
 			Name:     "Null coalescence",
 			Input:    "foo ?? bar",
@@ -172,6 +193,35 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "'foo' !~ '[fF][oO]+'",
 			Expected: "'foo' NOT RLIKE '[fF][oO]+'",
 		},
+		{
+			Name:     "Null predicates",
+			Input:    "foo is null || bar IS NOT NULL",
+			Expected: "[foo] IS NULL OR [bar] IS NOT NULL",
+		},
+		{
+			Name:     "Null literal",
+			Input:    "foo == null",
+			Options:  ExpressionOptions{ThreeValuedLogic: true},
+			Expected: "[foo] = NULL",
+		},
+		{
+			Name:     "Like",
+			Input:    "foo like 'a%' && foo NOT LIKE '%b_'",
+			Options:  ExpressionOptions{PatternOperators: true},
+			Expected: "[foo] LIKE 'a%' AND [foo] NOT LIKE '%b_'",
+		},
+		{
+			Name:     "Case insensitive like",
+			Input:    "foo ilike 'a%' || foo not ilike 'b%'",
+			Options:  ExpressionOptions{PatternOperators: true},
+			Expected: "[foo] ILIKE 'a%' OR [foo] NOT ILIKE 'b%'",
+		},
+		{
+			Name:     "Glob",
+			Input:    "foo glob '*.[ch]' && foo not glob 'x?'",
+			Options:  ExpressionOptions{PatternOperators: true},
+			Expected: "[foo] GLOB '*.[ch]' AND [foo] NOT GLOB 'x?'",
+		},
 	}
 
 	runQueryTests(testCases, test)
@@ -188,7 +238,7 @@ func runQueryTests(testCases []QueryTest, test *testing.T) {
 	// Run the test cases.
 	for _, testCase := range testCases {
 
-		expression, err = NewExpression(testCase.Input)
+		expression, err = NewExpressionWithOptions(testCase.Input, testCase.Options)
 
 		if err != nil {
 
@@ -0,0 +1,578 @@
+package govaluate
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// How tightly each kind of stage holds on to its operands when written out, from loosest to tightest.
+// This follows the chain of precedents that plans them, which isn't the same as the order of operatorPrecedence.
+type stageBinding int
+
+const (
+	separatorBinding stageBinding = iota
+	ternaryBinding
+	logicalOrBinding
+	logicalXorBinding
+	logicalAndBinding
+	comparatorBinding
+	betweenBinding
+	bitwiseBinding
+	shiftBinding
+	additiveBinding
+	multiplicativeBinding
+	exponentialBinding
+	prefixBinding
+	valueBinding
+)
+
+var bindingsByPrecedence = map[operatorPrecedence]stageBinding{
+	separatePrecedence:       separatorBinding,
+	ternaryPrecedence:        ternaryBinding,
+	logicalOrPrecedence:      logicalOrBinding,
+	logicalXorPrecedence:     logicalXorBinding,
+	logicalAndPrecedence:     logicalAndBinding,
+	comparatorPrecedence:     comparatorBinding,
+	betweenPrecedence:        betweenBinding,
+	bitwisePrecedence:        bitwiseBinding,
+	bitwiseShiftPrecedence:   shiftBinding,
+	additivePrecedence:       additiveBinding,
+	multiplicativePrecedence: multiplicativeBinding,
+	exponentialPrecedence:    exponentialBinding,
+	prefixPrecedence:         prefixBinding,
+}
+
+// Writes stages back out as the text of an expression, which parses (with the same options) to the same stages.
+type stageFormatter struct {
+	options ExpressionOptions
+	buffer  bytes.Buffer
+
+	// where each stage was written in the buffer, if not nil.
+	spans map[*evaluationStage]stageSpan
+}
+
+// The offsets in the text of an expression where a stage starts, and ends.
+type stageSpan struct {
+	start, end int
+}
+
+// Returns the text of an expression equivalent to [stage], as it would be written with the given [options].
+// Parenthesis are only written where they're needed to keep the same order of operations.
+// Fails if the stage holds something which can't be written out, such as a literal struct, or a function whose name isn't known.
+func formatStage(stage *evaluationStage, options ExpressionOptions) (string, error) {
+
+	formatter := &stageFormatter{options: options}
+
+	err := formatter.write(stage)
+	if err != nil {
+		return "", err
+	}
+	return formatter.buffer.String(), nil
+}
+
+// Like formatStage, but also returns where each stage was written in the text; except for parenthesis, and stages which are
+// written as part of another, like the elements of an array which are added before the last.
+func formatStageWithSpans(stage *evaluationStage, options ExpressionOptions) (string, map[*evaluationStage]stageSpan, error) {
+
+	formatter := &stageFormatter{
+		options: options,
+		spans:   make(map[*evaluationStage]stageSpan),
+	}
+
+	err := formatter.write(stage)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatter.buffer.String(), formatter.spans, nil
+}
+
+// Returns a new expression made from [stage], which is written out and parsed again with the same options as [expr].
+// The new expression also keeps any parameters which were bound to [expr].
+func (expr Expression) rewrite(stage *evaluationStage) (*Expression, error) {
+
+	text, err := formatStage(stage, expr.options)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := NewExpressionWithOptions(text, expr.options)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.QueryDateFormat = expr.QueryDateFormat
+	ret.ChecksTypes = expr.ChecksTypes
+	ret.boundParameters = expr.boundParameters
+	return ret, nil
+}
+
+// Returns how tightly the given stage holds on to its operands. Parenthesis are transparent, since they're only written where needed.
+func bindingOf(stage *evaluationStage) stageBinding {
+
+	for stage != nil && stage.symbol == noopSymbol && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+
+	if stage == nil {
+		return valueBinding
+	}
+
+	switch stage.symbol {
+	case literal:
+		// negative numbers are written with a prefix.
+		number, isNumber := literalValueOf(stage).(float64)
+		if isNumber && (number < 0 || (number == 0 && math.Signbit(number))) {
+			return prefixBinding
+		}
+		return valueBinding
+	case ternaryTrue, ternaryFalse, coalesce:
+		return ternaryBinding
+	case separate:
+		return separatorBinding
+	}
+
+	binding, found := bindingsByPrecedence[findOperatorPrecedenceForSymbol(stage.symbol)]
+	if !found {
+		return valueBinding
+	}
+	return binding
+}
+
+func (formatter *stageFormatter) write(stage *evaluationStage) error {
+
+	if stage == nil {
+		return nil
+	}
+
+	if formatter.spans != nil && stage.symbol != noopSymbol {
+
+		start := formatter.buffer.Len()
+		defer func() {
+			formatter.spans[stage] = stageSpan{start: start, end: formatter.buffer.Len()}
+		}()
+	}
+
+	switch stage.symbol {
+
+	case noopSymbol:
+		return formatter.write(stage.rightStage)
+
+	case literal:
+		return formatter.writeLiteral(literalValueOf(stage))
+
+	case value:
+		return formatter.writeParameter(stage.name)
+
+	case functional:
+		if stage.name == "" {
+			return fmt.Errorf("Unable to write out a call to an unnamed function")
+		}
+		formatter.buffer.WriteString(stage.name)
+		return formatter.writeArguments(stage.rightStage, true)
+
+	case access:
+		formatter.buffer.WriteString(stage.name)
+		return formatter.writeArguments(stage.rightStage, false)
+
+	case collectionFunctional:
+		return formatter.writeCollectionFunction(stage)
+
+	case arrayElement:
+		return formatter.writeArray(stage)
+
+	case mapElement:
+		return formatter.writeMap(stage)
+
+	case negate, invert, bitwiseNot:
+		formatter.buffer.WriteString(stage.symbol.String())
+		return formatter.writeOperand(stage.rightStage, valueBinding)
+
+	case between, notBetween:
+		return formatter.writeBetween(stage)
+	}
+
+	if stage.leftStage == nil || stage.rightStage == nil {
+		return fmt.Errorf("Unable to write out the operator '%v'", stage.symbol)
+	}
+
+	// the null of "is null" is written along with it, since it's read as null even where "null" isn't otherwise a keyword.
+	if (stage.symbol == is || stage.symbol == isNot) && stage.rightStage.symbol == literal && literalValueOf(stage.rightStage) == nil {
+
+		err := formatter.writeOperand(stage.leftStage, bindingOf(stage))
+		if err != nil {
+			return err
+		}
+
+		formatter.buffer.WriteString(" " + stage.symbol.String() + " null")
+		return nil
+	}
+
+	// operators of the same precedence are evaluated left to right, so only the right side needs parenthesis to keep them apart.
+	binding := bindingOf(stage)
+
+	err := formatter.writeOperand(stage.leftStage, binding)
+	if err != nil {
+		return err
+	}
+
+	switch stage.symbol {
+	case separate:
+		formatter.buffer.WriteString(", ")
+	case eq:
+		formatter.buffer.WriteString(" == ")
+	default:
+		formatter.buffer.WriteString(" " + stage.symbol.String() + " ")
+	}
+	return formatter.writeOperand(stage.rightStage, binding+1)
+}
+
+// Writes [stage], in parenthesis if it binds less tightly than [binding].
+func (formatter *stageFormatter) writeOperand(stage *evaluationStage, binding stageBinding) error {
+
+	if bindingOf(stage) >= binding {
+		return formatter.write(stage)
+	}
+
+	formatter.buffer.WriteString("(")
+	err := formatter.write(stage)
+	formatter.buffer.WriteString(")")
+	return err
+}
+
+// Writes the arguments of a function or method call, whose [stage] is a clause (or nil if there isn't one).
+// Accessors without a clause are fields, so nothing is written for them unless [required].
+func (formatter *stageFormatter) writeArguments(stage *evaluationStage, required bool) error {
+
+	if stage == nil && !required {
+		return nil
+	}
+
+	if stage != nil && stage.symbol == noopSymbol {
+		stage = stage.rightStage
+	}
+
+	formatter.buffer.WriteString("(")
+	err := formatter.write(stage)
+	formatter.buffer.WriteString(")")
+	return err
+}
+
+func (formatter *stageFormatter) writeCollectionFunction(stage *evaluationStage) error {
+
+	call := stage.collection
+
+	formatter.buffer.WriteString(call.name + "(")
+
+	err := formatter.writeOperand(stage.leftStage, ternaryBinding)
+	if err != nil {
+		return err
+	}
+
+	if stage.rightStage != nil {
+
+		formatter.buffer.WriteString(", ")
+		if call.parameterName != placeholderParameter {
+			formatter.buffer.WriteString(call.parameterName + " " + lambdaArrow + " ")
+		}
+
+		err = formatter.writeOperand(stage.rightStage, ternaryBinding)
+		if err != nil {
+			return err
+		}
+	}
+
+	formatter.buffer.WriteString(")")
+	return nil
+}
+
+// Writes "x between a and b", whose value and bounds are planned as bitwise operators.
+func (formatter *stageFormatter) writeBetween(stage *evaluationStage) error {
+
+	bounds := stage.rightStage
+	if bounds == nil || bounds.symbol != betweenBounds {
+		return fmt.Errorf("Unable to write out the bounds of '%v'", stage.symbol)
+	}
+
+	err := formatter.writeOperand(stage.leftStage, bitwiseBinding)
+	if err != nil {
+		return err
+	}
+
+	formatter.buffer.WriteString(" " + stage.symbol.String() + " ")
+
+	err = formatter.writeOperand(bounds.leftStage, bitwiseBinding)
+	if err != nil {
+		return err
+	}
+
+	formatter.buffer.WriteString(" and ")
+	return formatter.writeOperand(bounds.rightStage, bitwiseBinding)
+}
+
+// Writes an array literal, whose elements are each added by a stage on top of the stages that build the elements before it.
+func (formatter *stageFormatter) writeArray(stage *evaluationStage) error {
+
+	var elements []*evaluationStage
+
+	for ; stage.symbol == arrayElement; stage = stage.leftStage {
+		elements = append([]*evaluationStage{stage.rightStage}, elements...)
+	}
+
+	// whatever is left is the (possibly already built) start of the array.
+	if stage.symbol != literal {
+		return fmt.Errorf("Unable to write out the array literal")
+	}
+
+	initial, isArray := literalValueOf(stage).([]interface{})
+	if !isArray {
+		return fmt.Errorf("Unable to write out the array literal")
+	}
+
+	formatter.buffer.WriteString("[")
+
+	for i, element := range initial {
+
+		if i > 0 {
+			formatter.buffer.WriteString(", ")
+		}
+
+		err := formatter.writeLiteral(element)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, element := range elements {
+
+		if i > 0 || len(initial) > 0 {
+			formatter.buffer.WriteString(", ")
+		}
+
+		err := formatter.writeOperand(element, ternaryBinding)
+		if err != nil {
+			return err
+		}
+	}
+
+	// a lone element needs a trailing comma, or else "[foo]" would be a parameter.
+	if len(initial)+len(elements) == 1 {
+		formatter.buffer.WriteString(",")
+	}
+	formatter.buffer.WriteString("]")
+	return nil
+}
+
+// Writes a map literal, which is built up one entry at a time, like an array.
+func (formatter *stageFormatter) writeMap(stage *evaluationStage) error {
+
+	var entries []*evaluationStage
+
+	for ; stage.symbol == mapElement && stage.rightStage != nil; stage = stage.leftStage {
+		entries = append([]*evaluationStage{stage.rightStage}, entries...)
+	}
+
+	// the start of the map may have already been built.
+	var initial map[string]interface{}
+	if stage.symbol == literal {
+		initial, _ = literalValueOf(stage).(map[string]interface{})
+	}
+
+	formatter.buffer.WriteString("{")
+
+	err := formatter.writeLiteralEntries(initial)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+
+		if i > 0 || len(initial) > 0 {
+			formatter.buffer.WriteString(", ")
+		}
+
+		// keys are planned as logical operators, since a ternary's ":" would be mistaken for the end of the key.
+		err = formatter.writeOperand(entry.leftStage, logicalOrBinding)
+		if err != nil {
+			return err
+		}
+
+		formatter.buffer.WriteString(": ")
+
+		err = formatter.writeOperand(entry.rightStage, ternaryBinding)
+		if err != nil {
+			return err
+		}
+	}
+
+	formatter.buffer.WriteString("}")
+	return nil
+}
+
+// Writes the entries of a literal map (without its braces), in order of their keys.
+func (formatter *stageFormatter) writeLiteralEntries(entries map[string]interface{}) error {
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+
+		if i > 0 {
+			formatter.buffer.WriteString(", ")
+		}
+
+		err := formatter.writeLiteral(key)
+		if err != nil {
+			return err
+		}
+		formatter.buffer.WriteString(": ")
+
+		err = formatter.writeLiteral(entries[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (formatter *stageFormatter) writeLiteral(literal interface{}) error {
+
+	switch typed := literal.(type) {
+
+	case nil:
+		if !formatter.options.hasNullKeyword() {
+			return fmt.Errorf("Unable to write out null, since it's only a literal with three-valued logic or word operators")
+		}
+		if formatter.options.hasFunction("null") {
+			return fmt.Errorf("Unable to write out null, since a function is named 'null'")
+		}
+		formatter.buffer.WriteString("null")
+
+	case bool:
+		formatter.buffer.WriteString(strconv.FormatBool(typed))
+
+	case float64:
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return fmt.Errorf("Value '%v' cannot be written as a literal", typed)
+		}
+		formatter.buffer.WriteString(strconv.FormatFloat(typed, 'g', -1, 64))
+
+	case string:
+		// strings which look like dates would be read back as dates.
+		_, isTime := tryParseTime(typed)
+		if isTime {
+			return fmt.Errorf("Value '%v' cannot be written as a literal, it would be read as a date", typed)
+		}
+		formatter.buffer.WriteString(quoteString(typed))
+
+	case *regexp.Regexp:
+		formatter.buffer.WriteString(quoteString(typed.String()))
+
+	case []interface{}:
+		formatter.buffer.WriteString("[")
+		for i, element := range typed {
+
+			if i > 0 {
+				formatter.buffer.WriteString(", ")
+			}
+
+			err := formatter.writeLiteral(element)
+			if err != nil {
+				return err
+			}
+		}
+		if len(typed) == 1 {
+			formatter.buffer.WriteString(",")
+		}
+		formatter.buffer.WriteString("]")
+
+	case map[string]interface{}:
+		formatter.buffer.WriteString("{")
+		err := formatter.writeLiteralEntries(typed)
+		if err != nil {
+			return err
+		}
+		formatter.buffer.WriteString("}")
+
+	default:
+		return fmt.Errorf("Value '%v' (%T) cannot be written as a literal", literal, literal)
+	}
+	return nil
+}
+
+// Writes the name of a parameter, in brackets if it isn't a plain name, or if it would otherwise be read as a keyword or function.
+func (formatter *stageFormatter) writeParameter(name string) error {
+
+	if name == placeholderParameter || (isPlainName(name) && !formatter.isReservedName(name)) {
+		formatter.buffer.WriteString(name)
+		return nil
+	}
+
+	// names with commas would be read as arrays.
+	if name == "" || strings.ContainsAny(name, "],") {
+		return fmt.Errorf("Parameter name '%s' cannot be written out", name)
+	}
+
+	formatter.buffer.WriteString("[" + name + "]")
+	return nil
+}
+
+func (formatter *stageFormatter) isReservedName(name string) bool {
+
+	switch name {
+	case "true", "false", "in", "IN", "is", "IS", "not", "NOT":
+		return true
+	case "null", "NULL":
+		return formatter.options.hasNullKeyword()
+	}
+
+	if formatter.options.PatternOperators && isPatternWord(name, formatter.options.WordOperators) {
+		return true
+	}
+
+	if formatter.options.WordOperators {
+
+		lower := strings.ToLower(name)
+		if isWordOperator(lower, formatter.options) || lower == "is" {
+			return true
+		}
+	}
+	return formatter.options.hasFunction(name)
+}
+
+// Returns true if [name] is made entirely of letters, digits, and underscores, and doesn't start with a digit.
+func isPlainName(name string) bool {
+
+	for i, character := range name {
+		if !isVariableName(character) || (i == 0 && unicode.IsDigit(character)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Quotes [str] so that the lexer reads it back as the same string.
+// The lexer unquotes strings as Go does, except that they're delimited by single quotes, and double quotes aren't escaped.
+func quoteString(str string) string {
+
+	quoted := strconv.Quote(str)
+	quoted = quoted[1 : len(quoted)-1]
+	quoted = strings.Replace(quoted, `\"`, `"`, -1)
+	quoted = strings.Replace(quoted, `'`, `\'`, -1)
+	return "'" + quoted + "'"
+}
+
+// Returns the value of a literal stage.
+func literalValueOf(stage *evaluationStage) interface{} {
+
+	value, _ := stage.operator(nil, nil, nil)
+	return value
+}
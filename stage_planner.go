@@ -13,11 +13,17 @@ var stageSymbolMap = map[OperatorSymbol]evaluationOperator{
 	lt:            ltStage,
 	gte:           gteStage,
 	lte:           lteStage,
-	req:           regexStage,
-	nreq:          notRegexStage,
+	req:           makeRegexStage(nil, Limits{}, false),
+	nreq:          makeRegexStage(nil, Limits{}, true),
 	and:           andStage,
 	or:            orStage,
+	xor:           xorStage,
+	is:            isNullStage,
+	isNot:         isNotNullStage,
+	between:       betweenStage,
+	notBetween:    notBetweenStage,
 	in:            inStage,
+	notIn:         notInStage,
 	bitwiseOr:     bitwiseOrStage,
 	bitwiseAnd:    bitwiseAndStage,
 	bitwiseXor:    bitwiseXORStage,
@@ -64,6 +70,7 @@ var planBitwise precedent
 var planShift precedent
 var planComparator precedent
 var planLogicalAnd precedent
+var planLogicalXor precedent
 var planLogicalOr precedent
 var planTernary precedent
 var planSeparator precedent
@@ -113,19 +120,25 @@ func init() {
 		validSymbols:    comparatorSymbols,
 		validKinds:      []TokenKind{comparator},
 		typeErrorFormat: comparatorErrorFormat,
-		next:            planBitwise,
+		next:            planBetween,
 	})
 	planLogicalAnd = makePrecedentFromPlanner(&precedencePlanner{
 		validSymbols:    map[string]OperatorSymbol{"&&": and},
 		validKinds:      []TokenKind{logicalop},
 		typeErrorFormat: logicalErrorFormat,
-		next:            planComparator,
+		next:            planWordNot,
+	})
+	planLogicalXor = makePrecedentFromPlanner(&precedencePlanner{
+		validSymbols:    map[string]OperatorSymbol{"xor": xor},
+		validKinds:      []TokenKind{logicalop},
+		typeErrorFormat: logicalErrorFormat,
+		next:            planLogicalAnd,
 	})
 	planLogicalOr = makePrecedentFromPlanner(&precedencePlanner{
 		validSymbols:    map[string]OperatorSymbol{"||": or},
 		validKinds:      []TokenKind{logicalop},
 		typeErrorFormat: logicalErrorFormat,
-		next:            planLogicalAnd,
+		next:            planLogicalXor,
 	})
 	planTernary = makePrecedentFromPlanner(&precedencePlanner{
 		validSymbols:    ternarySymbols,
@@ -170,9 +183,12 @@ func makePrecedentFromPlanner(planner *precedencePlanner) precedent {
 // Creates a `evaluationStageList` object which represents an execution plan (or tree)
 // which is used to completely evaluate a set of tokens at evaluation-time.
 // The three stages of evaluation can be thought of as parsing strings to tokens, then tokens to a stage list, then evaluation with parameters.
-func planStages(tokens []ExpressionToken) (*evaluationStage, error) {
+func planStages(tokens []ExpressionToken, options ExpressionOptions, functionNames map[int]string, functionCalls map[int]functionSignature) (*evaluationStage, error) {
 
 	stream := newTokenStream(tokens)
+	stream.options = options
+	stream.functionNames = functionNames
+	stream.functionCalls = functionCalls
 
 	stage, err := planTokens(stream)
 	if err != nil {
@@ -183,7 +199,19 @@ func planStages(tokens []ExpressionToken) (*evaluationStage, error) {
 	// this could probably be avoided with a different planning method
 	reorderStages(stage)
 
-	stage = elideLiterals(stage)
+	if stage != nil {
+
+		err = checkFunctionResultTypes(stage)
+		if err != nil {
+			return nil, err
+		}
+		stage = elideLiterals(stage)
+
+		err = bindMatchPatterns(stage, options)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return stage, nil
 }
 
@@ -253,20 +281,46 @@ func planPrecedenceLevel(
 		}
 
 		if rightPrecedent != nil {
+
+			err = stream.descend()
+			if err != nil {
+				return nil, err
+			}
+
 			rightStage, err = rightPrecedent(stream)
 			if err != nil {
 				return nil, err
 			}
+			stream.ascend()
 		}
 
 		checks = findTypeChecks(symbol)
+		if (symbol == eq || symbol == neq) && stream.options.hasNullKeyword() {
+			checks.combined = nullableEqualityTypeCheck
+		}
+
+		if checks.errorFormat != "" {
+			typeErrorFormat = checks.errorFormat
+		}
+
+		operator := stageSymbolMap[symbol]
+
+		// regex comparators compile patterns from parameters through the expression's own cache, within its limits.
+		if symbol == req || symbol == nreq {
+			operator = makeRegexStage(stream.options.RegexCache, stream.options.Limits, symbol == nreq)
+		}
+
+		// likewise for pattern matching comparators. Their right stage may still be reordered, so constant patterns are bound later (see bindMatchPatterns).
+		if isMatchSymbol(symbol) {
+			operator = makeMatchStage(symbol, stream.options.RegexCache, stream.options.Limits)
+		}
 
 		return &evaluationStage{
 
 			symbol:     symbol,
 			leftStage:  leftStage,
 			rightStage: rightStage,
-			operator:   stageSymbolMap[symbol],
+			operator:   operator,
 
 			leftTypeCheck:   checks.left,
 			rightTypeCheck:  checks.right,
@@ -279,6 +333,43 @@ func planPrecedenceLevel(
 	return leftStage, nil
 }
 
+// Plans the "not" of word operator mode, which (as in SQL) binds more loosely than comparators, but more tightly than "and";
+// so "not x > 5" is "!(x > 5)". Where it isn't a "not", this plans a comparator.
+func planWordNot(stream *tokenStream) (*evaluationStage, error) {
+
+	if !stream.hasNext() {
+		return planComparator(stream)
+	}
+
+	token := stream.next()
+	if token.Kind != prefix || token.Value != "not" {
+		stream.rewind()
+		return planComparator(stream)
+	}
+
+	err := stream.descend()
+	if err != nil {
+		return nil, err
+	}
+
+	rightStage, err := planWordNot(stream)
+	if err != nil {
+		return nil, err
+	}
+	stream.ascend()
+
+	checks := findTypeChecks(invert)
+	return &evaluationStage{
+
+		symbol:     invert,
+		rightStage: rightStage,
+		operator:   invertStage,
+
+		rightTypeCheck:  checks.right,
+		typeErrorFormat: prefixErrorFormat,
+	}, nil
+}
+
 // A special case where functions need to be of higher precedence than values, and need a special wrapped execution stage operator.
 func planFunction(stream *tokenStream) (*evaluationStage, error) {
 
@@ -288,22 +379,120 @@ func planFunction(stream *tokenStream) (*evaluationStage, error) {
 
 	token = stream.next()
 
+	if token.Kind == collectionFunction {
+		return planCollectionFunction(stream, token.Value.(string))
+	}
+
 	if token.Kind != function {
 		stream.rewind()
 		return planAccessor(stream)
 	}
 
+	signature, knownSignature := stream.functionCalls[stream.index-1]
+	name := stream.functionNames[stream.index-1]
+
 	rightStage, err = planAccessor(stream)
 	if err != nil {
 		return nil, err
 	}
+	markArgumentSeparators(rightStage)
+	argumentCount := countArguments(rightStage)
 
-	return &evaluationStage{
+	ret := &evaluationStage{
 
 		symbol:          functional,
 		rightStage:      rightStage,
-		operator:        makeFunctionStage(token.Value.(ExpressionFunction)),
+		operator:        makeFunctionStage(token.Value.(ExpressionFunction), argumentCount > 0),
 		typeErrorFormat: "Unable to run function '%v': %v",
+		name:            name,
+	}
+
+	if knownSignature {
+
+		err = checkArgumentCount(signature.name, argumentCount, signature.minArguments, signature.maxArguments)
+		if err != nil {
+			return nil, err
+		}
+		ret.function = &signature
+
+		if signature.contextFunction != nil {
+			ret.operator = makeContextFunctionStage(signature.contextFunction, argumentCount > 0)
+		}
+	}
+	return ret, nil
+}
+
+// Plans a call to a built-in collection function, like "any(items, #.Price > 100)" or "all(items, x => x > 1)",
+// whose name has already been read.
+// The collection is planned as the left stage, and the predicate as the right; which is evaluated once for each element of the collection.
+func planCollectionFunction(stream *tokenStream, name string) (*evaluationStage, error) {
+
+	var token ExpressionToken
+	var collectionStage, predicateStage *evaluationStage
+	var err error
+
+	call := &collectionFunctionCall{
+		name:          name,
+		definition:    collectionFunctions[name],
+		parameterName: placeholderParameter,
+	}
+
+	// skip the opening clause, which the lexer guarantees is there.
+	stream.next()
+
+	collectionStage, err = planTernary(stream)
+	if err != nil {
+		return nil, err
+	}
+	if collectionStage == nil {
+		return nil, errors.New("Collection function '" + name + "' requires a collection")
+	}
+
+	token = stream.next()
+	if token.Kind == separator {
+
+		// named lambda parameter? ("x => x > 1")
+		if stream.index+1 < stream.tokenLength &&
+			stream.tokens[stream.index].Kind == variable &&
+			stream.tokens[stream.index+1].Kind == lambda {
+
+			call.parameterName = stream.next().Value.(string)
+			stream.next()
+		}
+
+		predicateStage, err = planTernary(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		token = stream.next()
+	}
+
+	if token.Kind != clauseClose {
+		errorMsg := fmt.Sprintf("Unexpected token '%v' in call to collection function '%s'", token.Value, name)
+		return nil, errors.New(errorMsg)
+	}
+
+	if predicateStage == nil && !call.definition.predicateOptional {
+		return nil, errors.New("Collection function '" + name + "' requires a predicate")
+	}
+
+	// the predicate is wrapped in a noop so that it isn't reordered as part of the surrounding expression.
+	if predicateStage != nil {
+		predicateStage = &evaluationStage{
+			rightStage: predicateStage,
+			operator:   noopStageRight,
+			symbol:     noopSymbol,
+		}
+	}
+
+	return &evaluationStage{
+
+		symbol:          collectionFunctional,
+		leftStage:       collectionStage,
+		rightStage:      predicateStage,
+		collection:      call,
+		typeErrorFormat: "Value '%[1]v' cannot be used with the collection function '" + name + "', it is not an array",
 	}, nil
 }
 
@@ -324,6 +513,19 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 		return planValue(stream)
 	}
 
+	pair := token.Value.([]string)
+	policy := stream.options.AccessPolicy
+
+	// if the parameter's type is known, the policy can be checked now rather than when evaluated.
+	parameterType, known := stream.options.ParameterTypes[pair[0]]
+	if known && parameterType != nil && !policy.isUnrestricted() {
+
+		err = policy.checkAccessorType(pair, parameterType, stream.options.accessorTag())
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// check if this is meant to be a function or a field.
 	// fields have a clause next to them, functions do not.
 	// if it's a function, parse the arguments. Otherwise leave the right stage null.
@@ -334,24 +536,128 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 
 			stream.rewind()
 
-			rightStage, err = planTokens(stream)
+			rightStage, err = planValue(stream)
 			if err != nil {
 				return nil, err
 			}
+			markArgumentSeparators(rightStage)
 		} else {
 			stream.rewind()
 		}
 	}
 
+	_, _, name := splitAccessor(pair)
+
 	return &evaluationStage{
 
 		symbol:          access,
 		rightStage:      rightStage,
-		operator:        makeAccessorStage(token.Value.([]string)),
+		operator:        makeAccessorStage(pair, stream.options.accessorTag(), policy),
 		typeErrorFormat: "Unable to access parameter field or method '%v': %v",
+		name:            name,
+	}, nil
+}
+
+// Plans a "between" (as in "x between 1 and 5"), whose value and bounds bind more tightly than any other comparator.
+// The bounds are paired up as the right side of the stage.
+func planBetween(stream *tokenStream) (*evaluationStage, error) {
+
+	leftStage, err := planBitwise(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stream.hasNext() {
+		return leftStage, nil
+	}
+
+	token := stream.next()
+
+	symbol, found := betweenSymbols[fmt.Sprintf("%v", token.Value)]
+	if token.Kind != comparator || !found {
+		stream.rewind()
+		return leftStage, nil
+	}
+
+	lowerStage, err := planBitwise(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stream.hasNext() {
+		return nil, fmt.Errorf("Expected 'and' between the bounds of '%s'", symbol.String())
+	}
+
+	token = stream.next()
+	if token.Kind != boundsSeparator {
+		return nil, fmt.Errorf("Expected 'and' between the bounds of '%s', found '%v'", symbol.String(), token.Value)
+	}
+
+	upperStage, err := planBitwise(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := findTypeChecks(symbol)
+
+	return &evaluationStage{
+
+		symbol:    symbol,
+		leftStage: leftStage,
+		rightStage: &evaluationStage{
+			symbol:     betweenBounds,
+			leftStage:  lowerStage,
+			rightStage: upperStage,
+			operator:   betweenBoundsStage,
+		},
+		operator: stageSymbolMap[symbol],
+
+		typeCheck:       checks.combined,
+		typeErrorFormat: checks.errorFormat,
 	}, nil
 }
 
+// Returns the operator for the pattern matching comparator [symbol], precompiling its pattern if [rightStage] is a constant string.
+// Compiles the constant patterns of pattern matching comparators, such as the "'a%'" of "name like 'a%'", so they needn't be compiled when evaluated.
+// This is done once the stages are in their final order, so that each comparator's pattern is the one it will be given.
+func bindMatchPatterns(stage *evaluationStage, options ExpressionOptions) error {
+
+	var err error
+
+	if stage.leftStage != nil {
+		err = bindMatchPatterns(stage.leftStage, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stage.rightStage != nil {
+		err = bindMatchPatterns(stage.rightStage, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isMatchSymbol(stage.symbol) {
+		stage.operator, err = planMatchOperator(stage.symbol, stage.rightStage, options)
+	}
+	return err
+}
+
+func planMatchOperator(symbol OperatorSymbol, rightStage *evaluationStage, options ExpressionOptions) (evaluationOperator, error) {
+
+	if rightStage != nil && rightStage.symbol == literal {
+
+		value, _ := rightStage.operator(nil, nil, nil)
+
+		source, isString := value.(string)
+		if isString {
+			return makeConstantMatchStage(symbol, source, options.RegexCache, options.Limits)
+		}
+	}
+	return makeMatchStage(symbol, options.RegexCache, options.Limits), nil
+}
+
 // A truly special precedence function, this handles all the "lowest-case" errata of the process, including literals, parmeters,
 // clauses, and prefixes.
 func planValue(stream *tokenStream) (*evaluationStage, error) {
@@ -368,6 +674,15 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 	token = stream.next()
 
+	if token.Kind == clause || token.Kind == arrayClause || token.Kind == mapClause {
+
+		err = stream.descend()
+		if err != nil {
+			return nil, err
+		}
+		defer stream.ascend()
+	}
+
 	switch token.Kind {
 
 	case clause:
@@ -391,6 +706,31 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 		return ret, nil
 
+	case arrayClause:
+		ret, err = planArray(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		// like clauses, literals are wrapped in a noop so that the stages of nested literals aren't reordered amongst each other.
+		return &evaluationStage{
+			rightStage: ret,
+			operator:   noopStageRight,
+			symbol:     noopSymbol,
+		}, nil
+
+	case mapClause:
+		ret, err = planMap(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		return &evaluationStage{
+			rightStage: ret,
+			operator:   noopStageRight,
+			symbol:     noopSymbol,
+		}, nil
+
 	case clauseClose:
 
 		// when functions have empty params, this will be hit. In this case, we don't have any evaluation stage to do,
@@ -407,6 +747,8 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 		fallthrough
 	case pattern:
 		fallthrough
+	case nullToken:
+		fallthrough
 	case boolean:
 		symbol = literal
 		operator = makeLiteralStage(token.Value)
@@ -416,6 +758,9 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 	case prefix:
 		stream.rewind()
+		if token.Value == "not" {
+			return planWordNot(stream)
+		}
 		return planPrefix(stream)
 	}
 
@@ -424,10 +769,171 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 		return nil, errors.New(errorMsg)
 	}
 
-	return &evaluationStage{
+	ret = &evaluationStage{
 		symbol:   symbol,
 		operator: operator,
-	}, nil
+	}
+
+	if token.Kind == variable {
+		ret.name = token.Value.(string)
+	}
+	return ret, nil
+}
+
+// Plans an array literal, such as "[1, 2, foo]", whose opening bracket has already been read.
+// Each element is a stage which appends that element to the array built by all the stages before it (its left stage).
+func planArray(stream *tokenStream) (*evaluationStage, error) {
+
+	var token ExpressionToken
+	var ret, element *evaluationStage
+	var err error
+
+	ret = &evaluationStage{
+		symbol:   literal,
+		operator: makeLiteralStage([]interface{}{}),
+	}
+
+	for {
+
+		token = stream.next()
+		if token.Kind == arrayClauseClose {
+			return ret, nil
+		}
+		stream.rewind()
+
+		element, err = planTernary(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = &evaluationStage{
+			symbol:     arrayElement,
+			leftStage:  ret,
+			rightStage: element,
+			operator:   arrayElementStage,
+		}
+
+		// elements are followed either by a separator (even the last one, optionally), or the end of the array.
+		// The separator itself will not have been consumed, since it's lower precedence than the element's planner.
+		token = stream.next()
+		if token.Kind == arrayClauseClose {
+			return ret, nil
+		}
+		if token.Kind != separator {
+			errorMsg := fmt.Sprintf("Unexpected token '%v' in array literal", token.Value)
+			return nil, errors.New(errorMsg)
+		}
+	}
+}
+
+// Plans a map literal, such as "{'a': 1, 'b': foo}", whose opening brace has already been read.
+// Like arrays, each entry is a stage which adds that entry to the map built by the stages before it.
+func planMap(stream *tokenStream) (*evaluationStage, error) {
+
+	var token ExpressionToken
+	var ret, key, value *evaluationStage
+	var err error
+
+	ret = &evaluationStage{
+		symbol:   mapElement,
+		operator: mapElementStage,
+	}
+
+	for {
+
+		token = stream.next()
+		if token.Kind == mapClauseClose {
+			return ret, nil
+		}
+		stream.rewind()
+
+		// keys can't be ternaries, since the key/value separator is the same symbol as the ternary "else".
+		key, err = planLogicalOr(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		token = stream.next()
+		if token.Kind != ternary || ternarySymbols[token.Value.(string)] != ternaryFalse {
+			errorMsg := fmt.Sprintf("Expected ':' after map key, found '%v'", token.Value)
+			return nil, errors.New(errorMsg)
+		}
+
+		value, err = planTernary(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		checks := findTypeChecks(mapEntry)
+		ret = &evaluationStage{
+			symbol:    mapElement,
+			leftStage: ret,
+			rightStage: &evaluationStage{
+				symbol:          mapEntry,
+				leftStage:       key,
+				rightStage:      value,
+				operator:        mapEntryStage,
+				leftTypeCheck:   checks.left,
+				typeErrorFormat: mapKeyErrorFormat,
+			},
+			operator: mapElementStage,
+		}
+
+		token = stream.next()
+		if token.Kind == mapClauseClose {
+			return ret, nil
+		}
+		if token.Kind != separator {
+			errorMsg := fmt.Sprintf("Unexpected token '%v' in map literal", token.Value)
+			return nil, errors.New(errorMsg)
+		}
+	}
+}
+
+// Function and method arguments are planned as a normal parenthesized clause, whose separators would normally build an array.
+// This changes the separators at the top of that clause to build an argument list instead,
+// so that arrays given as arguments are passed as a single argument.
+func markArgumentSeparators(stage *evaluationStage) {
+
+	if stage == nil || stage.symbol != noopSymbol {
+		return
+	}
+	markSeparatorChain(stage.rightStage)
+}
+
+// Returns the number of arguments given to a function, whose argument stage was marked by markArgumentSeparators.
+func countArguments(stage *evaluationStage) int {
+
+	if stage == nil {
+		return 0
+	}
+
+	if stage.symbol == noopSymbol {
+		if stage.rightStage == nil {
+			return 0
+		}
+		stage = stage.rightStage
+	}
+	return countSeparatedArguments(stage)
+}
+
+func countSeparatedArguments(stage *evaluationStage) int {
+
+	if stage.symbol != separate {
+		return 1
+	}
+	return countSeparatedArguments(stage.leftStage) + countSeparatedArguments(stage.rightStage)
+}
+
+func markSeparatorChain(stage *evaluationStage) {
+
+	if stage == nil || stage.symbol != separate {
+		return
+	}
+
+	stage.operator = argumentSeparatorStage
+	markSeparatorChain(stage.leftStage)
+	markSeparatorChain(stage.rightStage)
 }
 
 // Convenience function to pass a triplet of typechecks between `findTypeChecks` and `planPrecedenceLevel`.
@@ -436,6 +942,9 @@ type typeChecks struct {
 	left     stageTypeCheck
 	right    stageTypeCheck
 	combined stageCombinedTypeCheck
+
+	// if set, overrides the error format of the precedence level the symbol was planned in.
+	errorFormat string
 }
 
 // Maps a given [symbol] to a set of typechecks to be used during runtime.
@@ -461,14 +970,31 @@ func findTypeChecks(symbol OperatorSymbol) typeChecks {
 		}
 	case and:
 		fallthrough
+	case xor:
+		fallthrough
 	case or:
 		return typeChecks{
 			left:  isBool,
 			right: isBool,
 		}
+	case like, notLike, ilike, notIlike, glob, notGlob:
+		return typeChecks{
+			left:  isString,
+			right: isString,
+		}
+	case between:
+		fallthrough
+	case notBetween:
+		return typeChecks{
+			combined:    betweenTypeCheck,
+			errorFormat: betweenErrorFormat,
+		}
 	case in:
+		fallthrough
+	case notIn:
 		return typeChecks{
-			right: isArray,
+			combined:    membershipTypeCheck,
+			errorFormat: membershipErrorFormat,
 		}
 	case bitwiseLshift:
 		fallthrough
@@ -516,6 +1042,10 @@ func findTypeChecks(symbol OperatorSymbol) typeChecks {
 		return typeChecks{
 			left: isBool,
 		}
+	case mapEntry:
+		return typeChecks{
+			left: isString,
+		}
 
 	// unchecked cases
 	case eq:
@@ -645,6 +1175,10 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	var leftValue, rightValue, result interface{}
 	var err error
 
+	if root.function != nil && root.function.pure {
+		return elideFunctionCall(root)
+	}
+
 	// right side must be a non-nil value. Left side must be nil or a value.
 	if root.rightStage == nil ||
 		root.rightStage.symbol != literal ||
@@ -657,7 +1191,19 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	switch root.symbol {
 	case separate:
 		fallthrough
+	case betweenBounds:
+		fallthrough
+	case arrayElement:
+		fallthrough
+	case mapElement:
+		fallthrough
+	case mapEntry:
+		fallthrough
+	case collectionFunctional:
+		fallthrough
 	case in:
+		fallthrough
+	case notIn:
 		return root
 	}
 
@@ -673,6 +1219,11 @@ func elideStage(root *evaluationStage) *evaluationStage {
 		return root
 	}
 
+	// nulls are left to be evaluated, where they may follow three-valued logic.
+	if leftValue == nil || rightValue == nil {
+		return root
+	}
+
 	// typcheck, since the grammar checker is a bit loose with which operator symbols go together.
 	err = typeCheck(root.leftTypeCheck, leftValue, root.symbol, root.typeErrorFormat)
 	if err != nil {
@@ -699,3 +1250,143 @@ func elideStage(root *evaluationStage) *evaluationStage {
 		operator: makeLiteralStage(result),
 	}
 }
+
+// Evaluates a call to a pure function whose arguments are all constant, and returns a new stage representing the result.
+// Returns the unmodified [root] stage if any argument isn't constant, or if the call fails or panics
+// (so that it does so again when evaluated, rather than while parsing).
+func elideFunctionCall(root *evaluationStage) (ret *evaluationStage) {
+
+	if !isConstantStage(root.rightStage) {
+		return root
+	}
+
+	defer func() {
+		if recover() != nil {
+			ret = root
+		}
+	}()
+
+	// since nothing involved can refer to parameters, an empty expression is enough to evaluate the call.
+	result, err := Expression{}.evaluateStage(root, nil)
+	if err != nil {
+		return root
+	}
+
+	// arrays and maps would be shared between every evaluation, where a caller could modify them.
+	if isArray(result) || isMap(result) {
+		return root
+	}
+
+	return &evaluationStage{
+		symbol:   literal,
+		operator: makeLiteralStage(result),
+	}
+}
+
+// Returns true if the given stage (such as the arguments to a function) is made entirely of literals.
+func isConstantStage(stage *evaluationStage) bool {
+
+	if stage == nil {
+		return true
+	}
+
+	switch stage.symbol {
+	case literal:
+		return true
+	case noopSymbol, separate, betweenBounds, arrayElement, mapElement, mapEntry:
+		return isConstantStage(stage.leftStage) && isConstantStage(stage.rightStage)
+	}
+	return false
+}
+
+// Checks, ahead of time, that the results of functions with known return types can be used with the operators they're given to.
+// For instance, "lower(name) - 1" can never succeed if "lower" returns a string.
+func checkFunctionResultTypes(stage *evaluationStage) error {
+
+	var err error
+
+	if stage.leftStage != nil {
+		err = checkFunctionResultTypes(stage.leftStage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stage.rightStage != nil {
+		err = checkFunctionResultTypes(stage.rightStage)
+		if err != nil {
+			return err
+		}
+	}
+
+	leftFunction := findResultFunction(stage.leftStage)
+	rightFunction := findResultFunction(stage.rightStage)
+
+	if leftFunction == nil && rightFunction == nil {
+		return nil
+	}
+
+	if stage.typeCheck != nil {
+
+		// combined checks need to know both sides, so they're only checked if the other side is known too.
+		left, leftKnown := staticValueOf(stage.leftStage)
+		right, rightKnown := staticValueOf(stage.rightStage)
+
+		if leftKnown && rightKnown && !stage.typeCheck(left, right) {
+
+			function := leftFunction
+			if function == nil {
+				function = rightFunction
+			}
+			return functionResultTypeError(function, stage.symbol)
+		}
+		return nil
+	}
+
+	if leftFunction != nil && stage.leftTypeCheck != nil && !stage.leftTypeCheck(exampleValueOf(leftFunction.returnType)) {
+		return functionResultTypeError(leftFunction, stage.symbol)
+	}
+
+	if rightFunction != nil && stage.rightTypeCheck != nil && !stage.rightTypeCheck(exampleValueOf(rightFunction.returnType)) {
+		return functionResultTypeError(rightFunction, stage.symbol)
+	}
+	return nil
+}
+
+// Returns the signature of the function whose result the given stage produces, if that function's return type is known.
+// Sees through parenthesis, so "(lower(name))" produces the result of "lower".
+func findResultFunction(stage *evaluationStage) *functionSignature {
+
+	for stage != nil && stage.symbol == noopSymbol && stage.leftStage == nil {
+		stage = stage.rightStage
+	}
+
+	if stage == nil || stage.function == nil || stage.function.returnType == AnyType {
+		return nil
+	}
+	return stage.function
+}
+
+// Returns a value with the same type that the given stage will produce, if that's known ahead of time.
+func staticValueOf(stage *evaluationStage) (interface{}, bool) {
+
+	function := findResultFunction(stage)
+	if function != nil {
+		return exampleValueOf(function.returnType), true
+	}
+
+	for stage != nil && stage.symbol == noopSymbol && stage.leftStage == nil {
+		stage = stage.rightStage
+	}
+
+	if stage != nil && stage.symbol == literal {
+		value, err := stage.operator(nil, nil, nil)
+		return value, err == nil
+	}
+	return nil, false
+}
+
+func functionResultTypeError(function *functionSignature, symbol OperatorSymbol) error {
+	errorMsg := fmt.Sprintf("Function '%s' returns a %s, which cannot be used with '%s'", function.name, function.returnType, symbol)
+	return errors.New(errorMsg)
+}
@@ -0,0 +1,779 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+	The standard library is a set of commonly-needed ExpressionFunctions, which can be given to [NewExpressionWithFunctions]
+	(or [ExpressionOptions].Functions) either as a whole, with [StandardFunctions], or one group at a time.
+	None of them are available unless they are registered.
+*/
+
+// standardFunction describes a single function within the standard library.
+type standardFunction struct {
+
+	// the fewest and most arguments the function accepts. A [maxArguments] of -1 means there is no maximum.
+	minArguments int
+	maxArguments int
+
+	// the type of value the function returns, if it's always the same.
+	returnType ValueType
+
+	// whether or not the function can return different results for the same arguments, like "now()".
+	impure bool
+
+	body func(name string, arguments []interface{}) (interface{}, error)
+}
+
+// MathFunctions returns the standard math functions:
+// abs, ceil, floor, round, trunc, sqrt, pow, min, and max.
+func MathFunctions() map[string]ExpressionFunction {
+	return makeStandardFunctions(mathFunctions)
+}
+
+// StringFunctions returns the standard string functions:
+// lower, upper, trim, contains, startsWith, endsWith, replace, substr, split, join, and format.
+func StringFunctions() map[string]ExpressionFunction {
+	return makeStandardFunctions(stringFunctions)
+}
+
+// TimeFunctions returns the standard time functions:
+// now, date, duration, formatDate, year, month, day, hour, minute, and weekday.
+// Like date literals, times are represented as the number of seconds since the Unix epoch.
+func TimeFunctions() map[string]ExpressionFunction {
+	return makeStandardFunctions(timeFunctions)
+}
+
+// CollectionFunctions returns the standard collection functions:
+// len, first, last, keys, values, and reverse.
+func CollectionFunctions() map[string]ExpressionFunction {
+	return makeStandardFunctions(standardCollectionFunctions)
+}
+
+// StandardFunctions returns every function in the standard library.
+// Each call returns a new map, which callers are free to modify.
+func StandardFunctions() map[string]ExpressionFunction {
+	return MergeFunctions(MathFunctions(), StringFunctions(), TimeFunctions(), CollectionFunctions())
+}
+
+// StandardFunctionDescriptors returns every function in the standard library as a FunctionDescriptor,
+// for use with [ExpressionOptions].FunctionDescriptors. Unlike the plain functions, calls to these are checked for the right number
+// of arguments and for their result types when the expression is parsed, and those with constant arguments (such as "lower('ABC')")
+// are evaluated once, while parsing.
+func StandardFunctionDescriptors() map[string]FunctionDescriptor {
+
+	ret := make(map[string]FunctionDescriptor)
+
+	for _, group := range []map[string]standardFunction{mathFunctions, stringFunctions, timeFunctions, standardCollectionFunctions} {
+		for name, definition := range group {
+
+			ret[name] = FunctionDescriptor{
+				Function:     definition.makeFunction(name),
+				MinArguments: definition.minArguments,
+				MaxArguments: definition.maxArguments,
+				Pure:         !definition.impure,
+				ReturnType:   definition.returnType,
+			}
+		}
+	}
+	return ret
+}
+
+// MergeFunctions combines several maps of functions into a new map, such as a standard library group and a caller's own functions.
+// If more than one map defines a function of the same name, the one given last is used.
+func MergeFunctions(functionMaps ...map[string]ExpressionFunction) map[string]ExpressionFunction {
+
+	ret := make(map[string]ExpressionFunction)
+
+	for _, functions := range functionMaps {
+		for name, function := range functions {
+			ret[name] = function
+		}
+	}
+	return ret
+}
+
+func makeStandardFunctions(definitions map[string]standardFunction) map[string]ExpressionFunction {
+
+	ret := make(map[string]ExpressionFunction, len(definitions))
+
+	for name, definition := range definitions {
+		ret[name] = definition.makeFunction(name)
+	}
+	return ret
+}
+
+// makeFunction wraps the function's body so that its arguments are counted, and numeric arguments converted to float64, before it runs.
+func (definition standardFunction) makeFunction(name string) ExpressionFunction {
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		err := checkArgumentCount(name, len(arguments), definition.minArguments, definition.maxArguments)
+		if err != nil {
+			return nil, err
+		}
+
+		sanitized := make([]interface{}, len(arguments))
+		for i, argument := range arguments {
+			sanitized[i] = castToFloat64(argument)
+		}
+		return definition.body(name, sanitized)
+	}
+}
+
+func checkArgumentCount(name string, count int, min int, max int) error {
+
+	if count >= min && (count <= max || max < 0) {
+		return nil
+	}
+
+	var expected string
+	switch {
+	case min == max:
+		expected = pluralizeArguments(min)
+	case max < 0:
+		expected = "at least " + pluralizeArguments(min)
+	default:
+		expected = fmt.Sprintf("%d to %d arguments", min, max)
+	}
+
+	errorMsg := fmt.Sprintf("Function '%s' expects %s, got %d", name, expected, count)
+	return errors.New(errorMsg)
+}
+
+func pluralizeArguments(count int) string {
+
+	if count == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", count)
+}
+
+func argumentTypeError(name string, index int, value interface{}, expected string) error {
+	errorMsg := fmt.Sprintf("Value '%v' cannot be used as argument %d of '%s', it is not %s", value, index+1, name, expected)
+	return errors.New(errorMsg)
+}
+
+func numberArgument(name string, arguments []interface{}, index int) (float64, error) {
+
+	value, ok := arguments[index].(float64)
+	if !ok {
+		return 0, argumentTypeError(name, index, arguments[index], "a number")
+	}
+	return value, nil
+}
+
+// Like numberArgument, but also requires the number to be a whole number.
+func integerArgument(name string, arguments []interface{}, index int) (int, error) {
+
+	value, err := numberArgument(name, arguments, index)
+	if err != nil {
+		return 0, err
+	}
+
+	if value != math.Trunc(value) {
+		return 0, argumentTypeError(name, index, value, "a whole number")
+	}
+	return int(value), nil
+}
+
+func stringArgument(name string, arguments []interface{}, index int) (string, error) {
+
+	value, ok := arguments[index].(string)
+	if !ok {
+		return "", argumentTypeError(name, index, arguments[index], "a string")
+	}
+	return value, nil
+}
+
+func arrayArgument(name string, arguments []interface{}, index int) (reflect.Value, error) {
+
+	if !isArray(arguments[index]) {
+		return reflect.Value{}, argumentTypeError(name, index, arguments[index], "an array")
+	}
+	return reflect.ValueOf(arguments[index]), nil
+}
+
+// Times may be given as seconds since the epoch (which is what date literals become), as a time.Time, or as a date string.
+func timeArgument(name string, arguments []interface{}, index int) (time.Time, error) {
+
+	switch value := arguments[index].(type) {
+	case float64:
+		seconds, fraction := math.Modf(value)
+		return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+	case time.Time:
+		return value, nil
+	case string:
+		parsed, found := tryParseTime(value)
+		if found {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, argumentTypeError(name, index, arguments[index], "a time")
+}
+
+func timeResult(value time.Time) interface{} {
+	return float64(value.UnixNano()) / 1e9
+}
+
+/*
+	Math
+*/
+
+var mathFunctions = map[string]standardFunction{
+	"abs":   makeUnaryMathFunction(math.Abs),
+	"ceil":  makeUnaryMathFunction(math.Ceil),
+	"floor": makeUnaryMathFunction(math.Floor),
+	"trunc": makeUnaryMathFunction(math.Trunc),
+	"sqrt": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := numberArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if value < 0 {
+				return nil, argumentTypeError(name, 0, value, "a non-negative number")
+			}
+			return math.Sqrt(value), nil
+		},
+	},
+	"pow": {
+		minArguments: 2,
+		maxArguments: 2,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			base, err := numberArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			exponent, err := numberArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+			return math.Pow(base, exponent), nil
+		},
+	},
+	"round": {
+		minArguments: 1,
+		maxArguments: 2,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := numberArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(arguments) == 1 {
+				return math.Round(value), nil
+			}
+
+			digits, err := integerArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			scale := math.Pow(10, float64(digits))
+			return math.Round(value*scale) / scale, nil
+		},
+	},
+	"min": makeExtremeFunction(func(candidate float64, extant float64) bool { return candidate < extant }),
+	"max": makeExtremeFunction(func(candidate float64, extant float64) bool { return candidate > extant }),
+}
+
+func makeUnaryMathFunction(operation func(float64) float64) standardFunction {
+
+	return standardFunction{
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := numberArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+			return operation(value), nil
+		},
+	}
+}
+
+// min and max accept any number of arguments, or a single array of them, like "max(a, b)" or "max([a, b])".
+func makeExtremeFunction(replaces func(candidate float64, extant float64) bool) standardFunction {
+
+	return standardFunction{
+		minArguments: 1,
+		maxArguments: -1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			if len(arguments) == 1 && isArray(arguments[0]) {
+
+				elements := reflect.ValueOf(arguments[0])
+				if elements.Len() == 0 {
+					return nil, argumentTypeError(name, 0, arguments[0], "a non-empty array")
+				}
+
+				arguments = make([]interface{}, elements.Len())
+				for i := range arguments {
+					arguments[i] = castToFloat64(elements.Index(i).Interface())
+				}
+			}
+
+			ret, err := numberArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := 1; i < len(arguments); i++ {
+
+				value, err := numberArgument(name, arguments, i)
+				if err != nil {
+					return nil, err
+				}
+
+				if replaces(value, ret) {
+					ret = value
+				}
+			}
+			return ret, nil
+		},
+	}
+}
+
+/*
+	Strings
+*/
+
+var stringFunctions = map[string]standardFunction{
+	"lower": makeUnaryStringFunction(strings.ToLower),
+	"upper": makeUnaryStringFunction(strings.ToUpper),
+	"trim": {
+		minArguments: 1,
+		maxArguments: 2,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(arguments) == 1 {
+				return strings.TrimSpace(value), nil
+			}
+
+			cutset, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Trim(value, cutset), nil
+		},
+	},
+	"contains":   makeStringPredicateFunction(strings.Contains),
+	"startsWith": makeStringPredicateFunction(strings.HasPrefix),
+	"endsWith":   makeStringPredicateFunction(strings.HasSuffix),
+	"replace": {
+		minArguments: 3,
+		maxArguments: 3,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			var values [3]string
+			var err error
+
+			for i := range values {
+				values[i], err = stringArgument(name, arguments, i)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return strings.Replace(values[0], values[1], values[2], -1), nil
+		},
+	},
+	"substr": {
+		minArguments: 2,
+		maxArguments: 3,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			start, err := integerArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+			if start < 0 {
+				return nil, argumentTypeError(name, 1, start, "a non-negative number")
+			}
+
+			// positions are counted in characters, not bytes. Ranges beyond the end of the string are cut short.
+			runes := []rune(value)
+			if start > len(runes) {
+				start = len(runes)
+			}
+
+			end := len(runes)
+			if len(arguments) == 3 {
+
+				length, err := integerArgument(name, arguments, 2)
+				if err != nil {
+					return nil, err
+				}
+				if length < 0 {
+					return nil, argumentTypeError(name, 2, length, "a non-negative number")
+				}
+
+				if start+length < end {
+					end = start + length
+				}
+			}
+			return string(runes[start:end]), nil
+		},
+	},
+	"split": {
+		minArguments: 2,
+		maxArguments: 2,
+		returnType:   ArrayType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			separator, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			parts := strings.Split(value, separator)
+			ret := make([]interface{}, len(parts))
+			for i, part := range parts {
+				ret[i] = part
+			}
+			return ret, nil
+		},
+	},
+	"join": {
+		minArguments: 2,
+		maxArguments: 2,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			elements, err := arrayArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			separator, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			parts := make([]string, elements.Len())
+			for i := range parts {
+				parts[i] = fmt.Sprintf("%v", castToFloat64(elements.Index(i).Interface()))
+			}
+			return strings.Join(parts, separator), nil
+		},
+	},
+	"format": {
+		minArguments: 1,
+		maxArguments: -1,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			format, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf(format, arguments[1:]...), nil
+		},
+	},
+}
+
+func makeUnaryStringFunction(operation func(string) string) standardFunction {
+
+	return standardFunction{
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+			return operation(value), nil
+		},
+	}
+}
+
+func makeStringPredicateFunction(predicate func(string, string) bool) standardFunction {
+
+	return standardFunction{
+		minArguments: 2,
+		maxArguments: 2,
+		returnType:   BoolType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			candidate, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+			return predicate(value, candidate), nil
+		},
+	}
+}
+
+/*
+	Time
+*/
+
+var timeFunctions = map[string]standardFunction{
+	"now": {
+		minArguments: 0,
+		maxArguments: 0,
+		returnType:   NumberType,
+		impure:       true,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+			return timeResult(time.Now()), nil
+		},
+	},
+	"date": {
+		minArguments: 1,
+		maxArguments: 2,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			if len(arguments) == 1 {
+
+				value, err := timeArgument(name, arguments, 0)
+				if err != nil {
+					return nil, err
+				}
+				return timeResult(value), nil
+			}
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			layout, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			parsed, found := tryParseExactTime(value, layout)
+			if !found {
+				errorMsg := fmt.Sprintf("Value '%s' cannot be used as argument 1 of '%s', it does not match the layout '%s'", value, name, layout)
+				return nil, errors.New(errorMsg)
+			}
+			return timeResult(parsed), nil
+		},
+	},
+	"duration": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := stringArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, argumentTypeError(name, 0, value, "a duration")
+			}
+			return duration.Seconds(), nil
+		},
+	},
+	"formatDate": {
+		minArguments: 2,
+		maxArguments: 2,
+		returnType:   StringType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := timeArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			layout, err := stringArgument(name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+			return value.Format(layout), nil
+		},
+	},
+	"year":    makeTimePartFunction(func(value time.Time) int { return value.Year() }),
+	"month":   makeTimePartFunction(func(value time.Time) int { return int(value.Month()) }),
+	"day":     makeTimePartFunction(func(value time.Time) int { return value.Day() }),
+	"hour":    makeTimePartFunction(func(value time.Time) int { return value.Hour() }),
+	"minute":  makeTimePartFunction(func(value time.Time) int { return value.Minute() }),
+	"weekday": makeTimePartFunction(func(value time.Time) int { return int(value.Weekday()) }),
+}
+
+// Time parts are given in local time, since that is how date literals are parsed.
+func makeTimePartFunction(part func(time.Time) int) standardFunction {
+
+	return standardFunction{
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value, err := timeArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+			return float64(part(value.Local())), nil
+		},
+	}
+}
+
+/*
+	Collections
+*/
+
+var standardCollectionFunctions = map[string]standardFunction{
+	"len": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   NumberType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			value := arguments[0]
+
+			if isString(value) {
+				return float64(utf8.RuneCountInString(value.(string))), nil
+			}
+
+			if isArray(value) || isMap(value) {
+				return float64(reflect.ValueOf(value).Len()), nil
+			}
+			return nil, argumentTypeError(name, 0, value, "a string, array, or map")
+		},
+	},
+	"first": makeElementFunction(func(length int) int { return 0 }),
+	"last":  makeElementFunction(func(length int) int { return length - 1 }),
+	"reverse": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   ArrayType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			elements, err := arrayArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			length := elements.Len()
+			ret := make([]interface{}, length)
+			for i := range ret {
+				ret[i] = castToFloat64(elements.Index(length - i - 1).Interface())
+			}
+			return ret, nil
+		},
+	},
+	"keys": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   ArrayType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			_, keys, err := sortedMapKeys(name, arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(keys))
+			for i, key := range keys {
+				ret[i] = castToFloat64(key.Interface())
+			}
+			return ret, nil
+		},
+	},
+	"values": {
+		minArguments: 1,
+		maxArguments: 1,
+		returnType:   ArrayType,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			container, keys, err := sortedMapKeys(name, arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			ret := make([]interface{}, len(keys))
+			for i, key := range keys {
+				ret[i] = castToFloat64(container.MapIndex(key).Interface())
+			}
+			return ret, nil
+		},
+	},
+}
+
+func makeElementFunction(position func(length int) int) standardFunction {
+
+	return standardFunction{
+		minArguments: 1,
+		maxArguments: 1,
+		body: func(name string, arguments []interface{}) (interface{}, error) {
+
+			elements, err := arrayArgument(name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			// like accessing an optional link, an empty array has no element rather than an error.
+			if elements.Len() == 0 {
+				return nil, nil
+			}
+			return castToFloat64(elements.Index(position(elements.Len())).Interface()), nil
+		},
+	}
+}
+
+// Returns the keys of the map given as the first argument, sorted by their string representation so that results are stable.
+func sortedMapKeys(name string, arguments []interface{}) (reflect.Value, []reflect.Value, error) {
+
+	if !isMap(arguments[0]) {
+		return reflect.Value{}, nil, argumentTypeError(name, 0, arguments[0], "a map")
+	}
+
+	container := reflect.ValueOf(arguments[0])
+	keys := container.MapKeys()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%v", keys[i].Interface()) < fmt.Sprintf("%v", keys[j].Interface())
+	})
+	return container, keys, nil
+}
@@ -0,0 +1,196 @@
+package govaluate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Represents a test of a single standard library function.
+// If [ExpectedError] is set, evaluation is expected to fail with an error containing it; otherwise it should produce [Expected].
+type StandardFunctionTest struct {
+	Input         string
+	Expected      interface{}
+	ExpectedError string
+}
+
+func TestMathFunctions(test *testing.T) {
+
+	standardFunctionTests := []StandardFunctionTest{
+		{Input: "abs(-2.5)", Expected: 2.5},
+		{Input: "abs(small)", Expected: 3.0},
+		{Input: "abs('x')", ExpectedError: "Value 'x' cannot be used as argument 1 of 'abs', it is not a number"},
+		{Input: "abs()", ExpectedError: "Function 'abs' expects 1 argument, got 0"},
+		{Input: "ceil(1.2)", Expected: 2.0},
+		{Input: "ceil(-1.2)", Expected: -1.0},
+		{Input: "floor(1.8)", Expected: 1.0},
+		{Input: "floor(-1.2)", Expected: -2.0},
+		{Input: "trunc(-1.8)", Expected: -1.0},
+		{Input: "round(1.5)", Expected: 2.0},
+		{Input: "round(1.25, 1)", Expected: 1.3},
+		{Input: "round(1234, -2)", Expected: 1200.0},
+		{Input: "round(1.5, 0.5)", ExpectedError: "it is not a whole number"},
+		{Input: "round(1, 2, 3)", ExpectedError: "Function 'round' expects 1 to 2 arguments, got 3"},
+		{Input: "sqrt(16)", Expected: 4.0},
+		{Input: "sqrt(-1)", ExpectedError: "it is not a non-negative number"},
+		{Input: "pow(2, 10)", Expected: 1024.0},
+		{Input: "pow(2)", ExpectedError: "Function 'pow' expects 2 arguments, got 1"},
+		{Input: "min(3, -1, 2)", Expected: -1.0},
+		{Input: "min(numbers)", Expected: 1.0},
+		{Input: "min([])", ExpectedError: "it is not a non-empty array"},
+		{Input: "min()", ExpectedError: "Function 'min' expects at least 1 argument, got 0"},
+		{Input: "max(3, -1, 2)", Expected: 3.0},
+		{Input: "max(numbers)", Expected: 3.0},
+		{Input: "max(1, 'two')", ExpectedError: "Value 'two' cannot be used as argument 2 of 'max', it is not a number"},
+	}
+
+	runStandardFunctionTests(standardFunctionTests, MathFunctions(), test)
+}
+
+func TestStringFunctions(test *testing.T) {
+
+	standardFunctionTests := []StandardFunctionTest{
+		{Input: "lower('ABC')", Expected: "abc"},
+		{Input: "lower(1)", ExpectedError: "Value '1' cannot be used as argument 1 of 'lower', it is not a string"},
+		{Input: "upper('abc')", Expected: "ABC"},
+		{Input: "trim('  abc ')", Expected: "abc"},
+		{Input: "trim('--abc-', '-')", Expected: "abc"},
+		{Input: "contains('foobar', 'oba')", Expected: true},
+		{Input: "contains('foobar', 'baz')", Expected: false},
+		{Input: "contains(numbers, 1)", ExpectedError: "it is not a string"},
+		{Input: "startsWith('foobar', 'foo')", Expected: true},
+		{Input: "startsWith('foobar', 'bar')", Expected: false},
+		{Input: "endsWith('foobar', 'bar')", Expected: true},
+		{Input: "endsWith('foobar', 'foo')", Expected: false},
+		{Input: "replace('a-b-c', '-', '+')", Expected: "a+b+c"},
+		{Input: "replace('a-b-c', '-')", ExpectedError: "Function 'replace' expects 3 arguments, got 2"},
+		{Input: "substr('héllo', 1)", Expected: "éllo"},
+		{Input: "substr('héllo', 1, 3)", Expected: "éll"},
+		{Input: "substr('abc', 2, 10)", Expected: "c"},
+		{Input: "substr('abc', 5)", Expected: ""},
+		{Input: "substr('abc', -1)", ExpectedError: "it is not a non-negative number"},
+		{Input: "substr('abc', 0, -1)", ExpectedError: "it is not a non-negative number"},
+		{Input: "split('a,b,c', ',')", Expected: []interface{}{"a", "b", "c"}},
+		{Input: "split('abc', ',')", Expected: []interface{}{"abc"}},
+		{Input: "join(['a', 'b'], ', ')", Expected: "a, b"},
+		{Input: "join(numbers, '-')", Expected: "3-1-2"},
+		{Input: "join('abc', '-')", ExpectedError: "it is not an array"},
+		{Input: "format('%s has %v items', 'cart', 3)", Expected: "cart has 3 items"},
+		{Input: "format('plain')", Expected: "plain"},
+		{Input: "format(1)", ExpectedError: "it is not a string"},
+	}
+
+	runStandardFunctionTests(standardFunctionTests, StringFunctions(), test)
+}
+
+func TestTimeFunctions(test *testing.T) {
+
+	march := float64(time.Date(2020, time.March, 4, 10, 30, 0, 0, time.Local).Unix())
+
+	standardFunctionTests := []StandardFunctionTest{
+		{Input: "now() > '2020-01-01'", Expected: true},
+		{Input: "now(1)", ExpectedError: "Function 'now' expects 0 arguments, got 1"},
+		{Input: "date('2020-03-04 10:30')", Expected: march},
+		{Input: "date(timeValue)", Expected: march},
+		{Input: "date(dateString)", Expected: march},
+		{Input: "date('04/03/2020 10:30', '02/01/2006 15:04')", Expected: march},
+		{Input: "date('March 4', '02/01/2006')", ExpectedError: "it does not match the layout"},
+		{Input: "date('soon')", ExpectedError: "Value 'soon' cannot be used as argument 1 of 'date', it is not a time"},
+		{Input: "duration('1h30m')", Expected: 5400.0},
+		{Input: "duration('later')", ExpectedError: "it is not a duration"},
+		{Input: "formatDate('2020-03-04 10:30', '2006/01/02')", Expected: "2020/03/04"},
+		{Input: "formatDate('2020-03-04 10:30')", ExpectedError: "Function 'formatDate' expects 2 arguments, got 1"},
+		{Input: "year('2020-03-04 10:30')", Expected: 2020.0},
+		{Input: "month('2020-03-04 10:30')", Expected: 3.0},
+		{Input: "day('2020-03-04 10:30')", Expected: 4.0},
+		{Input: "hour('2020-03-04 10:30')", Expected: 10.0},
+		{Input: "minute('2020-03-04 10:30')", Expected: 30.0},
+		{Input: "weekday('2020-03-04 10:30')", Expected: 3.0},
+		{Input: "year(true)", ExpectedError: "it is not a time"},
+	}
+
+	runStandardFunctionTests(standardFunctionTests, TimeFunctions(), test)
+}
+
+func TestStandardCollectionFunctions(test *testing.T) {
+
+	standardFunctionTests := []StandardFunctionTest{
+		{Input: "len('héllo')", Expected: 5.0},
+		{Input: "len(numbers)", Expected: 3.0},
+		{Input: "len({'a': 1})", Expected: 1.0},
+		{Input: "len(1)", ExpectedError: "it is not a string, array, or map"},
+		{Input: "first(numbers)", Expected: 3.0},
+		{Input: "first([])", Expected: nil},
+		{Input: "first('abc')", ExpectedError: "it is not an array"},
+		{Input: "last(numbers)", Expected: 2.0},
+		{Input: "last([])", Expected: nil},
+		{Input: "reverse(numbers)", Expected: []interface{}{2.0, 1.0, 3.0}},
+		{Input: "reverse([])", Expected: []interface{}{}},
+		{Input: "keys(counts)", Expected: []interface{}{"a", "b"}},
+		{Input: "keys(numbers)", ExpectedError: "it is not a map"},
+		{Input: "values(counts)", Expected: []interface{}{1.0, 2.0}},
+		{Input: "values({})", Expected: []interface{}{}},
+	}
+
+	runStandardFunctionTests(standardFunctionTests, CollectionFunctions(), test)
+}
+
+func TestMergeFunctions(test *testing.T) {
+
+	custom := map[string]ExpressionFunction{
+		"lower": func(arguments ...interface{}) (interface{}, error) {
+			return "custom", nil
+		},
+	}
+
+	functions := MergeFunctions(StandardFunctions(), custom)
+
+	if len(functions) != len(mathFunctions)+len(stringFunctions)+len(timeFunctions)+len(standardCollectionFunctions) {
+		test.Errorf("Expected every standard function to be present once, got %d functions", len(functions))
+	}
+
+	result, err := functions["lower"]("ABC")
+	if err != nil || result != "custom" {
+		test.Errorf("Expected later function maps to take precedence, got %v (%v)", result, err)
+	}
+}
+
+func runStandardFunctionTests(standardFunctionTests []StandardFunctionTest, functions map[string]ExpressionFunction, test *testing.T) {
+
+	parameters := map[string]interface{}{
+		"small":      int8(-3),
+		"numbers":    []int{3, 1, 2},
+		"counts":     map[string]int{"b": 2, "a": 1},
+		"timeValue":  time.Date(2020, time.March, 4, 10, 30, 0, 0, time.Local),
+		"dateString": "2020-03-04T10:30:00" + time.Date(2020, time.March, 4, 10, 30, 0, 0, time.Local).Format("Z07:00"),
+	}
+
+	for _, testCase := range standardFunctionTests {
+
+		expression, err := NewExpressionWithFunctions(testCase.Input, functions)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.Input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+
+		if testCase.ExpectedError != "" {
+
+			if err == nil || !strings.Contains(err.Error(), testCase.ExpectedError) {
+				test.Errorf("Expected '%s' to fail with '%s', got %v (%v)", testCase.Input, testCase.ExpectedError, result, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", testCase.Input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, testCase.Expected) {
+			test.Errorf("Expected '%s' to evaluate to %#v, got %#v", testCase.Input, testCase.Expected, result)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+)
+
+// how far past the boundary of a comparison (of a number which isn't an integer) a boundary value is; as in 10.000001, for "x > 10".
+// Past numbers so large that this makes no difference, the closest number past the boundary is used instead.
+const boundaryOffset float64 = 0.000001
+
+// TestInput is a set of parameters generated by [Expression.GenerateTestInputs], along with what they exercise.
+type TestInput struct {
+	Parameters MapParameters
+
+	// Covers describes each thing that the parameters exercise, such as "x > 10 is false", or "x is 10, at the boundary of x > 10".
+	Covers []string
+}
+
+// GenerateTestInputs returns sets of parameters for testing the expression, ready to be given to [Expression.Eval].
+//
+// For each condition that the expression's "&&", "||", and "!" operate on (see [Expression.CheckSatisfiability]),
+// there are parameters for which the condition is true, and parameters for which it's false, in the style of MC/DC coverage:
+// in each, the result of the expression would be different if only that condition were the other way.
+// For each comparison of a parameter with a number, there are parameters on either side of its boundary;
+// as with 10 and 10.000001 for "x > 10" (or 10 and 11, if "x" is an Integer).
+// For each "in" of a parameter and an array, there are parameters for each of the array's elements.
+//
+// The values that each parameter can have may be given by [constraints], as with [Expression.CheckSatisfiability], whose analysis this uses.
+// Conditions which can't change the result on their own (like "b" in "a || (a && b)") aren't covered, nor are those
+// for which no parameters were found; so neither is a boundary value which is outside of a parameter's constraint.
+// Parameters which are the same for several things are only given once.
+//
+// Fails if any of the [constraints] are invalid.
+func (expr Expression) GenerateTestInputs(constraints map[string]VariableConstraint) ([]TestInput, error) {
+
+	for name, constraint := range constraints {
+		err := constraint.validate(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	term, err := expr.satisfiabilityTermOf(expr.evaluationStages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	generator := &testInputGenerator{
+		expr:        expr,
+		constraints: constraints,
+		term:        term,
+		indices:     make(map[string]int),
+	}
+
+	// conditions which are the same (or complements, like "x == 1" and "x != 1") are one condition, since they can't be changed separately.
+	var conditions []*boolTerm
+	seen := make(map[string]bool)
+
+	for _, condition := range conditionTermsOf(term) {
+
+		if seen[stageKeyOf(condition)] || seen[complementStageKeyOf(condition)] {
+			continue
+		}
+
+		seen[stageKeyOf(condition)] = true
+		conditions = append(conditions, condition)
+	}
+
+	for _, condition := range conditions {
+		for _, value := range []bool{true, false} {
+
+			isValue := &boolTerm{kind: conditionTerm, condition: condition.condition, negated: !value}
+			deciding := newCompoundTerm(andTerm, []*boolTerm{isValue, generator.decidingTerm(condition)})
+
+			parameters, err := generator.find(deciding, nil)
+			if err != nil {
+				return nil, err
+			}
+			generator.add(parameters, fmt.Sprintf("%s is %v", stageKeyOf(condition), value))
+		}
+	}
+
+	for _, condition := range conditions {
+		for _, boundary := range generator.boundariesOf(condition.condition) {
+
+			pinned := map[string]interface{}{boundary.name: boundary.value}
+
+			// the rest of the parameters are those for which the comparison decides the result, if there are any.
+			// Since the deciding term doesn't depend on the comparison itself, it's joined with a term that does, which is always true.
+			evaluated := newCompoundTerm(orTerm, []*boolTerm{copyTerm(condition), negateTerm(condition)})
+			deciding := newCompoundTerm(andTerm, []*boolTerm{evaluated, generator.decidingTerm(condition)})
+
+			var parameters map[string]interface{}
+			for _, goal := range []*boolTerm{deciding, copyTerm(term), negateTerm(term)} {
+
+				parameters, err = generator.find(goal, pinned)
+				if err != nil {
+					return nil, err
+				}
+				if parameters != nil {
+					break
+				}
+			}
+			generator.add(parameters, boundary.description)
+		}
+	}
+	return generator.inputs, nil
+}
+
+// Generates test inputs for an expression.
+type testInputGenerator struct {
+	expr        Expression
+	constraints map[string]VariableConstraint
+	term        *boolTerm
+
+	inputs []TestInput
+
+	// the index within [inputs] of each set of parameters, keyed by how they're written.
+	indices map[string]int
+}
+
+// A value of a parameter which is on (or next to) the boundary of a comparison.
+type testBoundary struct {
+	name        string
+	value       interface{}
+	description string
+}
+
+// Returns parameters for which [goal] is true, or nil if none were found; trying only the given values for parameters which are [pinned].
+func (generator *testInputGenerator) find(goal *boolTerm, pinned map[string]interface{}) (map[string]interface{}, error) {
+
+	checker, err := generator.expr.newSatisfiabilityChecker(goal, []satisfiabilityGoal{{term: goal, isTrue: true}}, generator.constraints, pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	// pinned parameters which the expression doesn't use can't be given.
+	for name := range pinned {
+		if _, found := checker.variables[name]; !found {
+			return nil, nil
+		}
+	}
+	return checker.search(make(map[string]interface{}), true), nil
+}
+
+// Adds [parameters] (if not nil) as a test input which covers [description], or adds the description to the input which already has them.
+func (generator *testInputGenerator) add(parameters map[string]interface{}, description string) {
+
+	if parameters == nil {
+		return
+	}
+
+	// maps are written in the order of their keys, so parameters which are the same are written the same way.
+	key := fmt.Sprintf("%#v", parameters)
+
+	index, found := generator.indices[key]
+	if found {
+		for _, covered := range generator.inputs[index].Covers {
+			if covered == description {
+				return
+			}
+		}
+		generator.inputs[index].Covers = append(generator.inputs[index].Covers, description)
+		return
+	}
+
+	generator.indices[key] = len(generator.inputs)
+	generator.inputs = append(generator.inputs, TestInput{
+		Parameters: MapParameters(parameters),
+		Covers:     []string{description},
+	})
+}
+
+// Returns the number [offset] past [limit]; or the closest number to it in that direction,
+// where the offset is too small to make a difference (as it is past 2^53, even for integers).
+func pastBoundary(limit float64, offset float64) float64 {
+
+	ret := limit + offset
+	if ret == limit {
+		ret = math.Nextafter(limit, math.Inf(int(math.Copysign(1, offset))))
+	}
+	return ret
+}
+
+// Returns a term which is true wherever [condition] decides the result of the expression;
+// that is, where the expression's result would be different if only the condition were the other way.
+func (generator *testInputGenerator) decidingTerm(condition *boolTerm) *boolTerm {
+
+	key := stageKeyOf(condition)
+
+	// the result is true when the condition is one way, and not when it's the other.
+	var operands []*boolTerm
+	for _, value := range []bool{true, false} {
+
+		operands = append(operands, newCompoundTerm(andTerm, []*boolTerm{
+			forceCondition(generator.term, key, value),
+			negateTerm(forceCondition(generator.term, key, !value)),
+		}))
+	}
+	return newCompoundTerm(orTerm, operands)
+}
+
+// Returns the values of parameters at (and next to) the boundaries of [condition],
+// if it's a comparison of a parameter with a number, or "in" of a parameter and an array.
+func (generator *testInputGenerator) boundariesOf(condition *evaluationStage) []testBoundary {
+
+	for condition.symbol == noopSymbol && condition.rightStage != nil {
+		condition = condition.rightStage
+	}
+
+	left, right := condition.leftStage, condition.rightStage
+	symbol := condition.symbol
+
+	var limits []float64
+	var elements []interface{}
+
+	switch symbol {
+
+	case eq, neq, gt, lt, gte, lte:
+		if left != nil && left.symbol == literal {
+			left, right = right, left
+			symbol = mirroredComparators[symbol]
+		}
+		if right == nil || right.symbol != literal {
+			return nil
+		}
+
+		limit, isNumber := literalValueOf(right).(float64)
+		if !isNumber {
+			return nil
+		}
+		limits = []float64{limit}
+
+	case between, notBetween:
+		if right == nil || right.leftStage == nil || right.rightStage == nil ||
+			right.leftStage.symbol != literal || right.rightStage.symbol != literal {
+			return nil
+		}
+
+		lower, isNumber := literalValueOf(right.leftStage).(float64)
+		upper, isUpperNumber := literalValueOf(right.rightStage).(float64)
+		if !isNumber || !isUpperNumber {
+			return nil
+		}
+		limits = []float64{lower, upper}
+
+	case in, notIn:
+		var isList bool
+		elements, isList = constantElementsOf(right)
+		if !isList {
+			return nil
+		}
+
+	default:
+		return nil
+	}
+
+	if left == nil || left.symbol != value {
+		return nil
+	}
+
+	name := left.name
+	constraint := generator.constraints[name]
+	comparison := generator.format(condition)
+
+	var ret []testBoundary
+
+	for _, element := range elements {
+		ret = append(ret, testBoundary{
+			name:        name,
+			value:       element,
+			description: fmt.Sprintf("%s is %s, from %s", name, generator.format(literalStage(element)), comparison),
+		})
+	}
+
+	offset := boundaryOffset
+	if constraint.Integer {
+		offset = 1
+	}
+
+	for i, limit := range limits {
+
+		if math.IsNaN(limit) || math.IsInf(limit, 0) {
+			continue
+		}
+
+		// the boundary itself, and the number on the other side of it; or for equality (and "between"), those on either side.
+		below, above := true, true
+		switch symbol {
+		case gt, lte:
+			below = false
+		case lt, gte:
+			above = false
+		case between, notBetween:
+			below, above = i == 0, i == 1
+		}
+
+		values := []float64{limit}
+		if below {
+			values = append([]float64{pastBoundary(limit, -offset)}, values...)
+		}
+		if above {
+			values = append(values, pastBoundary(limit, offset))
+		}
+
+		// integers can't be at a boundary which isn't whole, so they're either side of it instead.
+		if constraint.Integer && limit != math.Floor(limit) {
+			values = []float64{math.Floor(limit), math.Ceil(limit)}
+		}
+
+		for _, number := range values {
+
+			if (constraint.Minimum != nil && number < *constraint.Minimum) || (constraint.Maximum != nil && number > *constraint.Maximum) {
+				continue
+			}
+
+			position := "next to"
+			if number == limit {
+				position = "at"
+			}
+
+			ret = append(ret, testBoundary{
+				name:        name,
+				value:       number,
+				description: fmt.Sprintf("%s is %s, %s the boundary of %s", name, generator.format(literalStage(number)), position, comparison),
+			})
+		}
+	}
+	return ret
+}
+
+// Returns [stage] written out, for a description.
+func (generator *testInputGenerator) format(stage *evaluationStage) string {
+
+	text, err := formatStage(stage, generator.expr.options)
+	if err != nil {
+		return fmt.Sprintf("%v", literalValueOf(stage))
+	}
+	return text
+}
+
+// Returns the key of the stage that [condition] evaluates, regardless of whether the condition is negated.
+func stageKeyOf(condition *boolTerm) string {
+
+	if condition.negated {
+		return condition.complementKey
+	}
+	return condition.key
+}
+
+// Returns the key of the stage which gives the opposite result to the stage that [condition] evaluates, as "x != 1" does for "x == 1".
+func complementStageKeyOf(condition *boolTerm) string {
+
+	if condition.negated {
+		return condition.key
+	}
+	return condition.complementKey
+}
+
+// Returns the conditions within [term], in the order they're evaluated.
+func conditionTermsOf(term *boolTerm) []*boolTerm {
+
+	switch term.kind {
+	case conditionTerm:
+		return []*boolTerm{term}
+	case andTerm, orTerm:
+
+		var ret []*boolTerm
+		for _, operand := range term.operands {
+			ret = append(ret, conditionTermsOf(operand)...)
+		}
+		return ret
+	}
+	return nil
+}
+
+// Returns a copy of [term] in which the condition whose stage has the given [key] is always [value] (and so its complements are not).
+func forceCondition(term *boolTerm, key string, value bool) *boolTerm {
+
+	switch term.kind {
+
+	case conditionTerm:
+		if stageKeyOf(term) == key {
+			return constantBoolTerm(value != term.negated)
+		}
+		if complementStageKeyOf(term) == key {
+			return constantBoolTerm(value == term.negated)
+		}
+		return copyTerm(term)
+
+	case andTerm, orTerm:
+
+		operands := make([]*boolTerm, len(term.operands))
+		for i, operand := range term.operands {
+			operands[i] = forceCondition(operand, key, value)
+		}
+		return newCompoundTerm(term.kind, operands)
+	}
+	return constantBoolTerm(term.value)
+}
+
+// Returns a copy of [term], whose conditions are separate from those of the original.
+func copyTerm(term *boolTerm) *boolTerm {
+
+	ret := *term
+
+	if term.kind == andTerm || term.kind == orTerm {
+
+		ret.operands = make([]*boolTerm, len(term.operands))
+		for i, operand := range term.operands {
+			ret.operands[i] = copyTerm(operand)
+		}
+	}
+	return &ret
+}
+
+// Returns a term which is true wherever [term] is false, and false wherever it's true.
+func negateTerm(term *boolTerm) *boolTerm {
+
+	switch term.kind {
+
+	case constantTerm:
+		return constantBoolTerm(!term.value)
+
+	case conditionTerm:
+		ret := *term
+		ret.negated = !term.negated
+		ret.key, ret.complementKey = term.complementKey, term.key
+		return &ret
+	}
+
+	// De Morgan's laws.
+	kind := andTerm
+	if term.kind == andTerm {
+		kind = orTerm
+	}
+
+	operands := make([]*boolTerm, len(term.operands))
+	for i, operand := range term.operands {
+		operands[i] = negateTerm(operand)
+	}
+	return newCompoundTerm(kind, operands)
+}
@@ -0,0 +1,251 @@
+package govaluate
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TestInputTest struct {
+	Name        string
+	Input       string
+	Options     ExpressionOptions
+	Constraints map[string]VariableConstraint
+	Expected    []TestInput
+}
+
+func TestGenerateTestInputs(test *testing.T) {
+
+	zero := 0.0
+
+	testInputTests := []TestInputTest{
+		{
+			Name:  "Boundary",
+			Input: "x > 10",
+			Expected: []TestInput{
+				{MapParameters{"x": 11.0}, []string{"x > 10 is true"}},
+				{MapParameters{"x": 9.0}, []string{"x > 10 is false"}},
+				{MapParameters{"x": 10.0}, []string{"x is 10, at the boundary of x > 10"}},
+				{MapParameters{"x": 10.000001}, []string{"x is 10.000001, next to the boundary of x > 10"}},
+			},
+		},
+		{
+			Name:  "Boundary of a large number",
+			Input: "x > 1e17",
+			Expected: []TestInput{
+				{MapParameters{"x": 1.0000000000000002e+17}, []string{"x > 1e+17 is true", "x is 1.0000000000000002e+17, next to the boundary of x > 1e+17"}},
+				{MapParameters{"x": 9.999999999999998e+16}, []string{"x > 1e+17 is false"}},
+				{MapParameters{"x": 1e+17}, []string{"x is 1e+17, at the boundary of x > 1e+17"}},
+			},
+		},
+		{
+			Name:  "Repeated element",
+			Input: "x in [1, 1]",
+			Expected: []TestInput{
+				{MapParameters{"x": 1.0}, []string{"x in [1, 1] is true", "x is 1, from x in [1, 1]"}},
+				{MapParameters{"x": 0.0}, []string{"x in [1, 1] is false"}},
+			},
+		},
+		{
+			Name:        "Boundary of an integer",
+			Input:       "x >= 2.5",
+			Constraints: map[string]VariableConstraint{"x": {Type: NumberType, Integer: true}},
+			Expected: []TestInput{
+				{MapParameters{"x": 3.0}, []string{"x >= 2.5 is true", "x is 3, next to the boundary of x >= 2.5"}},
+				{MapParameters{"x": 2.0}, []string{"x >= 2.5 is false", "x is 2, next to the boundary of x >= 2.5"}},
+			},
+		},
+		{
+			Name:        "Boundary outside of constraints",
+			Input:       "10 <= x",
+			Constraints: map[string]VariableConstraint{"x": {Type: NumberType, Minimum: &zero}},
+			Expected: []TestInput{
+				{MapParameters{"x": 10.0}, []string{"10 <= x is true", "x is 10, at the boundary of 10 <= x"}},
+				{MapParameters{"x": 0.0}, []string{"10 <= x is false"}},
+				{MapParameters{"x": 9.999999}, []string{"x is 9.999999, next to the boundary of 10 <= x"}},
+			},
+		},
+		{
+			Name:  "Membership",
+			Input: "status in ['open', 'pending'] || vip",
+			Expected: []TestInput{
+				{MapParameters{"status": "open", "vip": false}, []string{"status in ['open', 'pending'] is true", "status is 'open', from status in ['open', 'pending']"}},
+				{MapParameters{"status": "", "vip": false}, []string{"status in ['open', 'pending'] is false", "vip is false"}},
+				{MapParameters{"status": "", "vip": true}, []string{"vip is true"}},
+				{MapParameters{"status": "pending", "vip": false}, []string{"status is 'pending', from status in ['open', 'pending']"}},
+			},
+		},
+		{
+			Name:  "Membership of a list in parentheses",
+			Input: "s in ('a', 'b')",
+			Expected: []TestInput{
+				{MapParameters{"s": "a"}, []string{"s in ('a', 'b') is true", "s is 'a', from s in ('a', 'b')"}},
+				{MapParameters{"s": ""}, []string{"s in ('a', 'b') is false"}},
+				{MapParameters{"s": "b"}, []string{"s is 'b', from s in ('a', 'b')"}},
+			},
+		},
+		{
+			Name:  "Masked condition",
+			Input: "a || (a && b)",
+			Expected: []TestInput{
+				{MapParameters{"a": true, "b": true}, []string{"a is true"}},
+				{MapParameters{"a": false, "b": true}, []string{"a is false"}},
+			},
+		},
+		{
+			Name:  "Complementary conditions",
+			Input: "(x == 1 && a) || (x != 1 && b)",
+			Expected: []TestInput{
+				{MapParameters{"a": true, "b": false, "x": 1.0}, []string{"x == 1 is true", "x is 1, at the boundary of x == 1"}},
+				{MapParameters{"a": true, "b": false, "x": 0.0}, []string{"x == 1 is false", "b is false"}},
+				{MapParameters{"a": true, "b": true, "x": 1.0}, []string{"a is true"}},
+				{MapParameters{"a": false, "b": true, "x": 1.0}, []string{"a is false"}},
+				{MapParameters{"a": true, "b": true, "x": 0.0}, []string{"b is true"}},
+				{MapParameters{"a": true, "b": false, "x": 0.999999}, []string{"x is 0.999999, next to the boundary of x == 1"}},
+				{MapParameters{"a": true, "b": false, "x": 1.000001}, []string{"x is 1.000001, next to the boundary of x == 1"}},
+			},
+		},
+		{
+			Name:     "Constant",
+			Input:    "1 > 2",
+			Expected: nil,
+		},
+	}
+
+	for _, testInputTest := range testInputTests {
+
+		expression, err := NewExpressionWithOptions(testInputTest.Input, testInputTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testInputTest.Name, err)
+			continue
+		}
+
+		inputs, err := expression.GenerateTestInputs(testInputTest.Constraints)
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", testInputTest.Name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(inputs, testInputTest.Expected) {
+			test.Errorf("Test '%s' failed: expected %v, got %v", testInputTest.Name, testInputTest.Expected, inputs)
+		}
+	}
+}
+
+func TestGenerateTestInputsFailure(test *testing.T) {
+
+	one := 1.0
+	zero := 0.0
+
+	expression, err := NewExpression("x > 1")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, err = expression.GenerateTestInputs(map[string]VariableConstraint{"x": {Type: NumberType, Minimum: &one, Maximum: &zero}})
+	if err == nil || !strings.Contains(err.Error(), "Parameter 'x'") {
+		test.Errorf("Expected an invalid constraint to fail, got %v", err)
+	}
+}
+
+// Tests that the inputs generated for randomly generated expressions give each condition the value they say they do,
+// and that the expression's result would change with that condition alone; and that every condition which can is covered.
+func TestTestInputsOfRandomExpressions(test *testing.T) {
+
+	random := rand.New(rand.NewSource(47))
+
+	zero := 0.0
+	three := 3.0
+
+	constraints := map[string]VariableConstraint{
+		"a": {Type: BoolType},
+		"b": {Type: BoolType},
+		"x": {Type: NumberType, Integer: true, Minimum: &zero, Maximum: &three},
+		"s": {Type: StringType},
+	}
+	conditions := []string{"a", "b", "x > 1", "x <= 0", "s > 'p'"}
+
+	var assignments []map[string]interface{}
+	for _, a := range []interface{}{true, false} {
+		for _, b := range []interface{}{true, false} {
+			for _, x := range []interface{}{0.0, 1.0, 2.0, 3.0} {
+				for _, s := range []interface{}{"", "p", "q"} {
+					assignments = append(assignments, map[string]interface{}{"a": a, "b": b, "x": x, "s": s})
+				}
+			}
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+
+		input := randomLogic(random, conditions, 3)
+
+		expression, err := NewExpression(input)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		inputs, err := expression.GenerateTestInputs(constraints)
+		if err != nil {
+			test.Fatalf("Unable to generate test inputs for '%s': %v", input, err)
+		}
+
+		covered := make(map[string]bool)
+		for _, testInput := range inputs {
+			for _, cover := range testInput.Covers {
+				covered[cover] = true
+			}
+		}
+
+		for _, condition := range conditions {
+
+			if !strings.Contains(input, condition) {
+				continue
+			}
+
+			conditionExpression, _ := NewExpression(condition)
+			whenTrue, _ := NewExpression(strings.Replace(input, condition, "true", -1))
+			whenFalse, _ := NewExpression(strings.Replace(input, condition, "false", -1))
+
+			decides := func(parameters map[string]interface{}) bool {
+
+				resultWhenTrue, _ := whenTrue.Evaluate(parameters)
+				resultWhenFalse, _ := whenFalse.Evaluate(parameters)
+				return resultWhenTrue != resultWhenFalse
+			}
+
+			for _, value := range []bool{true, false} {
+
+				description := condition + " is " + map[bool]string{true: "true", false: "false"}[value]
+
+				for _, testInput := range inputs {
+					for _, cover := range testInput.Covers {
+
+						if cover != description {
+							continue
+						}
+
+						actual, _ := conditionExpression.Evaluate(testInput.Parameters)
+						if actual != value || !decides(testInput.Parameters) {
+							test.Errorf("Expected %v to cover '%s' in '%s'", testInput.Parameters, description, input)
+						}
+					}
+				}
+
+				if covered[description] {
+					continue
+				}
+
+				for _, assignment := range assignments {
+
+					actual, _ := conditionExpression.Evaluate(assignment)
+					if actual == value && decides(assignment) {
+						test.Errorf("Expected '%s' to be covered in '%s', as it is by %v", description, input, assignment)
+						break
+					}
+				}
+			}
+		}
+	}
+}
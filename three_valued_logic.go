@@ -0,0 +1,128 @@
+package govaluate
+
+// Returns the result of [stage] under three-valued logic (see ExpressionOptions.ThreeValuedLogic), if either of its operands is null.
+// Returns false if the stage should be evaluated as usual instead; because neither operand is null, because the stage isn't
+// affected by nulls, or because its other operand is of the wrong type (which evaluating as usual reports).
+func evaluateThreeValued(stage *evaluationStage, left interface{}, right interface{}) (interface{}, bool) {
+
+	leftNull := stage.leftStage != nil && left == nil
+	rightNull := stage.rightStage != nil && right == nil
+
+	switch stage.symbol {
+
+	case and:
+		return kleeneOperands(left, right, leftNull, rightNull, false)
+	case or:
+		return kleeneOperands(left, right, leftNull, rightNull, true)
+
+	case between, notBetween:
+		return evaluateNullBetween(stage.symbol, left, right, leftNull)
+
+	case in, notIn:
+		if leftNull || rightNull {
+			return nil, true
+		}
+		return evaluateNullMembership(stage.symbol, left, right)
+
+	case ternaryTrue:
+		// a null condition counts as false, as it does in SQL's CASE.
+		if leftNull {
+			return nil, true
+		}
+		return nil, false
+
+	case eq, neq, gt, lt, gte, lte, req, nreq, like, notLike, ilike, notIlike, glob, notGlob, xor,
+		plus, minus, multiply, divide, modulus, exponent,
+		bitwiseAnd, bitwiseOr, bitwiseXor, bitwiseLshift, bitwiseRshift,
+		negate, invert, bitwiseNot:
+
+		if leftNull || rightNull {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// Applies a Kleene "and" (if not [dominant]) or "or" (if [dominant]) to a pair of operands, at least one of which may be null.
+// If either operand is [dominant], so is the result; otherwise, it's null.
+func kleeneOperands(left interface{}, right interface{}, leftNull bool, rightNull bool, dominant bool) (interface{}, bool) {
+
+	if !leftNull && !rightNull {
+		return nil, false
+	}
+
+	if (!leftNull && !isBool(left)) || (!rightNull && !isBool(right)) {
+		return nil, false
+	}
+
+	if left == dominant || right == dominant {
+		return boolIface(dominant), true
+	}
+	return nil, true
+}
+
+// Evaluates "between" (or "not between") when its value or either of its bounds is null,
+// as "value >= lower && value <= upper", where each comparison with null is null.
+func evaluateNullBetween(symbol OperatorSymbol, left interface{}, right interface{}, leftNull bool) (interface{}, bool) {
+
+	if leftNull {
+		return nil, true
+	}
+
+	bounds, isBounds := right.([]interface{})
+	if !isBounds || len(bounds) != 2 || (bounds[0] != nil && bounds[1] != nil) {
+		return nil, false
+	}
+
+	var comparisons [2]interface{}
+
+	for i, bound := range bounds {
+
+		if bound == nil {
+			continue
+		}
+
+		if !comparatorTypeCheck(left, bound) {
+			return nil, false
+		}
+
+		if i == 0 {
+			comparisons[i], _ = gteStage(left, bound, nil)
+		} else {
+			comparisons[i], _ = lteStage(left, bound, nil)
+		}
+	}
+
+	result, _ := kleeneOperands(comparisons[0], comparisons[1], comparisons[0] == nil, comparisons[1] == nil, false)
+	if symbol == notBetween && result != nil {
+		return boolIface(!result.(bool)), true
+	}
+	return result, true
+}
+
+// Evaluates membership in an array which contains null, which is null rather than false when no other element matches.
+func evaluateNullMembership(symbol OperatorSymbol, left interface{}, right interface{}) (interface{}, bool) {
+
+	elements, isArray := right.([]interface{})
+	if !isArray {
+		return nil, false
+	}
+
+	hasNull := false
+	for _, element := range elements {
+
+		if element == nil {
+			hasNull = true
+			continue
+		}
+
+		if membersEqual(left, element) {
+			return boolIface(symbol == in), true
+		}
+	}
+
+	if hasNull {
+		return nil, true
+	}
+	return nil, false
+}
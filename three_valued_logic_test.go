@@ -0,0 +1,204 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNullLiteral(test *testing.T) {
+
+	evaluationTests := []EvaluationTest{
+		{
+			Name:     "Null literal",
+			Input:    "null",
+			Options:  ExpressionOptions{ThreeValuedLogic: true},
+			Expected: nil,
+		},
+		{
+			Name:    "Equality with null",
+			Input:   "missing == null && present != null && present != NULL",
+			Options: ExpressionOptions{WordOperators: true},
+			Parameters: []EvaluationParameter{
+				{Name: "missing", Value: nil},
+				{Name: "present", Value: 1.0},
+			},
+			Expected: true,
+		},
+		{
+			Name:  "Is null",
+			Input: "missing is null && present IS NOT NULL",
+			Parameters: []EvaluationParameter{
+				{Name: "missing", Value: nil},
+				{Name: "present", Value: "x"},
+			},
+			Expected: true,
+		},
+		{
+			Name:  "Is not null",
+			Input: "missing is not null",
+			Parameters: []EvaluationParameter{
+				{Name: "missing", Value: nil},
+			},
+			Expected: false,
+		},
+		{
+			Name:     "Null coalescence of the null literal",
+			Input:    "null ?? 2",
+			Options:  ExpressionOptions{WordOperators: true},
+			Expected: 2.0,
+		},
+		{
+			Name:     "Null in an array",
+			Input:    "null in [1, null]",
+			Options:  ExpressionOptions{WordOperators: true},
+			Expected: true,
+		},
+		{
+			Name:  "Parameter named 'null'",
+			Input: "null == 1 && [NULL] == 2",
+			Parameters: []EvaluationParameter{
+				{Name: "null", Value: 1.0},
+				{Name: "NULL", Value: 2.0},
+			},
+			Expected: true,
+		},
+		{
+			Name:  "Parameter named 'is'",
+			Input: "is == 1",
+			Parameters: []EvaluationParameter{
+				{Name: "is", Value: 1.0},
+			},
+			Expected: true,
+		},
+	}
+
+	runEvaluationTests(evaluationTests, test)
+}
+
+// Tests that by default, "null" is a parameter name; so comparing with it needs a parameter of that name, and "is null" is the way to check for null.
+func TestNullWithoutKeyword(test *testing.T) {
+
+	parameters := map[string]interface{}{"missing": nil}
+
+	expression, err := NewExpression("missing == null")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, err = expression.Evaluate(parameters)
+	if err == nil || !strings.Contains(err.Error(), "No parameter 'null' found") {
+		test.Errorf("Expected 'missing == null' to need a parameter named 'null', got %v", err)
+	}
+
+	expression, err = NewExpression("missing is null")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	result, err := expression.Evaluate(parameters)
+	if err != nil || result != true {
+		test.Errorf("Expected 'missing is null' to be true, got %v (%v)", result, err)
+	}
+}
+
+func TestThreeValuedLogic(test *testing.T) {
+
+	parameters := map[string]interface{}{
+		"missing": nil,
+		"n":       4.0,
+		"name":    "foo",
+	}
+
+	// expected results, with and without three-valued logic.
+	cases := []struct {
+		input       string
+		threeValued interface{}
+		usual       interface{}
+	}{
+		{"missing == null", nil, "error"},
+		{"missing != 1", nil, "error"},
+		{"n > missing", nil, "error"},
+		{"missing + 1", nil, "error"},
+		{"-missing", nil, "error"},
+		{"!missing", nil, "error"},
+		{"name =~ missing", nil, "error"},
+		{"name like missing", nil, "error"},
+
+		{"missing && false", false, "error"},
+		{"false && missing", false, false},
+		{"missing && true", nil, "error"},
+		{"missing && missing", nil, "error"},
+		{"missing || true", true, "error"},
+		{"true || missing", true, true},
+		{"missing || false", nil, "error"},
+		{"!(n > missing) || n > 1", true, "error"},
+
+		{"n in [1, missing]", nil, false},
+		{"n in [4, missing]", true, true},
+		{"n not in [1, missing]", nil, true},
+		{"missing in [1, 2]", nil, false},
+
+		{"missing is null", true, true},
+		{"n > missing is null", true, "error"},
+		{"missing ?? n", 4.0, 4.0},
+		{"missing ? 1 : 2", 2.0, "error"},
+	}
+
+	for _, threeValued := range []bool{true, false} {
+
+		for _, testCase := range cases {
+
+			expected := testCase.usual
+			if threeValued {
+				expected = testCase.threeValued
+			}
+
+			expression, err := NewExpressionWithOptions(testCase.input, ExpressionOptions{ThreeValuedLogic: threeValued, PatternOperators: true})
+			if err != nil {
+				test.Errorf("Unable to parse '%s': %v", testCase.input, err)
+				continue
+			}
+
+			result, err := expression.Evaluate(parameters)
+			if expected == "error" {
+				if err == nil {
+					test.Errorf("Expected '%s' to fail (with three-valued logic: %v), got %v", testCase.input, threeValued, result)
+				}
+				continue
+			}
+
+			if err != nil || result != expected {
+				test.Errorf("Expected '%s' to be %v (with three-valued logic: %v), got %v (%v)", testCase.input, expected, threeValued, result, err)
+			}
+		}
+	}
+}
+
+// Tests that between follows three-valued logic as if it were written with >= and <=.
+func TestThreeValuedBetween(test *testing.T) {
+
+	cases := map[string]interface{}{
+		"n between null and 3":     false,
+		"n between null and 5":     nil,
+		"n between 1 and null":     nil,
+		"n not between 5 and null": true,
+		"n not between 1 and null": nil,
+		"null between 1 and 5":     nil,
+	}
+
+	options := ExpressionOptions{ThreeValuedLogic: true, WordOperators: true}
+
+	for input, expected := range cases {
+
+		expression, err := NewExpressionWithOptions(input, options)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(map[string]interface{}{"n": 4.0})
+		if err != nil || result != expected {
+			test.Errorf("Expected '%s' to be %v, got %v (%v)", input, expected, result, err)
+		}
+	}
+}
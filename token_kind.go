@@ -25,6 +25,18 @@ const (
 	clauseClose
 
 	ternary
+
+	arrayClause
+	arrayClauseClose
+	mapClause
+	mapClauseClose
+
+	collectionFunction
+	lambda
+
+	nullToken
+
+	boundsSeparator
 )
 
 // GetTokenKindString returns a string that describes the given TokenKind.
@@ -65,6 +77,22 @@ func (kind TokenKind) String() string {
 		return "TERNARY"
 	case accessor:
 		return "ACCESSOR"
+	case arrayClause:
+		return "ARRAY_CLAUSE"
+	case arrayClauseClose:
+		return "ARRAY_CLAUSE_CLOSE"
+	case nullToken:
+		return "NULL"
+	case mapClause:
+		return "MAP_CLAUSE"
+	case mapClauseClose:
+		return "MAP_CLAUSE_CLOSE"
+	case collectionFunction:
+		return "COLLECTION_FUNCTION"
+	case lambda:
+		return "LAMBDA"
+	case boundsSeparator:
+		return "BOUNDS_SEPARATOR"
 	}
 
 	return "UNKNOWN"
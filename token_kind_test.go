@@ -26,6 +26,13 @@ func TestTokenKindStrings(test *testing.T) {
 		clause,
 		clauseClose,
 		ternary,
+		arrayClause,
+		arrayClauseClose,
+		mapClause,
+		mapClauseClose,
+		collectionFunction,
+		lambda,
+		nullToken,
 	}
 
 	for _, kind := range kinds {
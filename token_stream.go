@@ -4,6 +4,18 @@ type tokenStream struct {
 	tokens      []ExpressionToken
 	index       int
 	tokenLength int
+
+	// the options given to the expression, which some stages need to know about when they're planned.
+	options ExpressionOptions
+
+	// the signatures of function calls whose arity can be checked while planning, keyed by the index of the call's token.
+	functionCalls map[int]functionSignature
+
+	// the names of all function calls, keyed by the index of the call's token.
+	functionNames map[int]string
+
+	// how deeply the stage being planned is nested within the stages planned around it.
+	depth int
 }
 
 func newTokenStream(tokens []ExpressionToken) *tokenStream {
@@ -16,6 +28,17 @@ func newTokenStream(tokens []ExpressionToken) *tokenStream {
 	return ret
 }
 
+// Notes that planning is descending into the operand of an operator, or into brackets; and fails if that nests the stages deeper than
+// the expression's MaxDepth. This is checked as the stages are planned, since planning a long chain of operators recurses once for each.
+func (ts *tokenStream) descend() error {
+	ts.depth++
+	return checkLimit("MaxDepth", ts.options.Limits.MaxDepth, ts.depth)
+}
+
+func (ts *tokenStream) ascend() {
+	ts.depth--
+}
+
 func (ts *tokenStream) rewind() {
 	ts.index--
 }
@@ -0,0 +1,346 @@
+package govaluate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TraceNode is a stage of an expression which was evaluated (or skipped) by [Expression.EvalWithTrace],
+// along with the stages within it that it evaluated.
+type TraceNode struct {
+
+	// Expression is the text of the stage, such as "age > 30".
+	Expression string `json:"expression"`
+
+	// Operator is the operator the stage applies, such as ">" or "&&"; the name of the function, for function calls;
+	// or "parameter" and "accessor", for looking up parameters.
+	Operator string `json:"operator"`
+
+	// Parameter is the name of the parameter (or accessor) that the stage looked up, if any.
+	Parameter string `json:"parameter,omitempty"`
+
+	// Inputs are the values of the stage's operands, in the order they were evaluated; those which were skipped aren't included.
+	Inputs []interface{} `json:"inputs,omitempty"`
+
+	// Output is the value that the stage produced.
+	Output interface{} `json:"output"`
+
+	// Error is the reason the stage failed, if it did.
+	Error string `json:"error,omitempty"`
+
+	// ShortCircuited is true if the stage wasn't evaluated, because its result wasn't needed; like "b", in "false && b".
+	ShortCircuited bool `json:"shortCircuited,omitempty"`
+
+	// Children are the stages within this one, in the order they were evaluated, followed by any that were short-circuited.
+	// Literals, and parentheses, aren't included.
+	Children []*TraceNode `json:"children,omitempty"`
+
+	symbol OperatorSymbol
+
+	// the operands which were evaluated.
+	evaluated []*evaluationStage
+}
+
+// EvalWithTrace is like Eval, except that it also returns a trace of every stage that was evaluated;
+// with what they were given, what they produced, and whether they were short-circuited.
+// The trace can be written as indented text with String(), or as JSON with json.Marshal,
+// and the reasons for a boolean result can be found with Explain().
+//
+// The trace is returned even if evaluation fails, in which case the stages which failed have an Error.
+// Evaluating with a trace is slower than without, so it's best kept for finding out why an expression gave the result it did.
+func (expr Expression) EvalWithTrace(parameters Parameters) (interface{}, *TraceNode, error) {
+
+	if expr.evaluationStages == nil {
+		return nil, nil, nil
+	}
+
+	if parameters == nil {
+		parameters = MapParameters(map[string]interface{}{})
+	}
+	parameters = expr.withBoundParameters(parameters)
+
+	tracer := &evaluationTracer{options: expr.options}
+	state := &evaluationState{limits: expr.options.Limits, tracer: tracer, coverage: expr.coverage, profile: expr.profile}
+
+	result, err := expr.evaluateStage(expr.evaluationStages, &sanitizedParameters{orig: parameters, evaluation: state})
+	return result, tracer.root, err
+}
+
+// String returns the trace as indented text, with a line for each stage; such as:
+//
+//	age > 30 && country == 'NZ' -> false
+//	  age > 30 -> false
+//	    age -> 25 (parameter)
+//	  country == 'NZ' (short-circuited)
+func (node *TraceNode) String() string {
+
+	var builder strings.Builder
+	node.write(&builder, 0)
+	return builder.String()
+}
+
+func (node *TraceNode) write(builder *strings.Builder, depth int) {
+
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(node.Expression)
+
+	switch {
+	case node.ShortCircuited:
+		builder.WriteString(" (short-circuited)")
+	case node.Error != "":
+		builder.WriteString(" -> error: ")
+		builder.WriteString(node.Error)
+	default:
+		builder.WriteString(" -> ")
+		builder.WriteString(describeTraceValue(node.Output))
+	}
+
+	if node.Parameter != "" && !node.ShortCircuited {
+		builder.WriteString(" (" + node.Operator + ")")
+	}
+	builder.WriteString("\n")
+
+	for _, child := range node.Children {
+		child.write(builder, depth+1)
+	}
+}
+
+// MarshalJSON writes the trace as JSON.
+// Values which can't be written as JSON, like NaN, are written as strings.
+func (node *TraceNode) MarshalJSON() ([]byte, error) {
+
+	// without the methods of TraceNode, so that this isn't called again.
+	type plainTraceNode TraceNode
+
+	plain := plainTraceNode(*node)
+	plain.Output = jsonTraceValue(node.Output)
+
+	if node.Inputs != nil {
+		plain.Inputs = make([]interface{}, len(node.Inputs))
+		for i, input := range node.Inputs {
+			plain.Inputs[i] = jsonTraceValue(input)
+		}
+	}
+	return json.Marshal(plain)
+}
+
+// Explain returns the conditions which decided the result of a boolean expression, as few as there can be; such as "age > 30 is false".
+// For "&&", one operand which is false decides that the result is false, but every operand decides that it's true; and the opposite for "||".
+// Anything other than "&&", "||", "xor", and "!" is a condition, which is explained by its own result.
+// If evaluation failed, the explanation is the stage which failed.
+func (node *TraceNode) Explain() []string {
+
+	if node == nil || node.ShortCircuited {
+		return nil
+	}
+
+	// failures are explained by the innermost stage which failed.
+	if node.Error != "" {
+
+		for _, child := range node.Children {
+			if child.Error != "" {
+				return child.Explain()
+			}
+		}
+		return []string{fmt.Sprintf("%s failed: %s", node.Expression, node.Error)}
+	}
+
+	var operands []*TraceNode
+	for _, child := range node.Children {
+		if !child.ShortCircuited {
+			operands = append(operands, child)
+		}
+	}
+
+	switch node.symbol {
+
+	case and, or:
+		// the value which decides the result by itself; "false" for "&&", and "true" for "||".
+		deciding := node.symbol == or
+
+		if node.Output == deciding {
+			for _, operand := range operands {
+				if operand.Output == deciding {
+					return operand.Explain()
+				}
+			}
+		}
+
+		// otherwise every operand mattered; except that with three-valued logic, a null result is explained by the nulls.
+		var ret []string
+		for _, operand := range operands {
+			if node.Output == !deciding || operand.Output != !deciding {
+				ret = append(ret, operand.Explain()...)
+			}
+		}
+		return ret
+
+	case xor, invert:
+		var ret []string
+		for _, operand := range operands {
+			ret = append(ret, operand.Explain()...)
+		}
+		return ret
+	}
+
+	return []string{fmt.Sprintf("%s is %s", node.Expression, describeTraceValue(node.Output))}
+}
+
+// Records each stage that's evaluated during EvalWithTrace.
+type evaluationTracer struct {
+	options ExpressionOptions
+	root    *TraceNode
+
+	// the nodes of the stages being evaluated, innermost last; nil for stages which aren't traced.
+	stack []*TraceNode
+}
+
+// Evaluates [stage] with [evaluate], recording it (and each stage evaluated within it).
+func (tracer *evaluationTracer) trace(expr Expression, stage *evaluationStage, evaluate func() (interface{}, error)) (interface{}, error) {
+
+	parent := tracer.current()
+	if parent != nil {
+		parent.evaluated = append(parent.evaluated, stage)
+	}
+
+	// parentheses, the separators between arguments, and the pair of bounds of a "between" aren't traced,
+	// and literals are only traced if they're the whole expression.
+	if stage.symbol == noopSymbol || stage.symbol == separate || stage.symbol == betweenBounds || (stage.symbol == literal && len(tracer.stack) > 0) {
+
+		tracer.stack = append(tracer.stack, nil)
+		defer func() { tracer.stack = tracer.stack[:len(tracer.stack)-1] }()
+
+		return evaluate()
+	}
+
+	node := tracer.nodeOf(stage)
+
+	if parent != nil {
+		parent.Children = append(parent.Children, node)
+	} else if tracer.root == nil {
+		tracer.root = node
+	}
+
+	tracer.stack = append(tracer.stack, node)
+	ret, err := evaluate()
+	tracer.stack = tracer.stack[:len(tracer.stack)-1]
+
+	node.Output = ret
+	if err != nil {
+		node.Error = err.Error()
+		return ret, err
+	}
+
+	// operands which weren't evaluated were short-circuited.
+	for _, operand := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+		if operand == nil || tracer.wasEvaluated(node, operand) {
+			continue
+		}
+
+		skipped := tracer.nodeOf(unwrapNoopStage(operand))
+		skipped.ShortCircuited = true
+		node.Children = append(node.Children, skipped)
+	}
+	return ret, err
+}
+
+// Records [input] as one of the inputs of the stage being evaluated.
+func (tracer *evaluationTracer) recordInput(input interface{}) {
+
+	if len(tracer.stack) == 0 {
+		return
+	}
+
+	node := tracer.stack[len(tracer.stack)-1]
+	if node != nil {
+		node.Inputs = append(node.Inputs, input)
+	}
+}
+
+// Returns the node of the innermost stage being evaluated which is traced, or nil if there isn't one.
+func (tracer *evaluationTracer) current() *TraceNode {
+
+	for i := len(tracer.stack) - 1; i >= 0; i-- {
+		if tracer.stack[i] != nil {
+			return tracer.stack[i]
+		}
+	}
+	return nil
+}
+
+func (tracer *evaluationTracer) wasEvaluated(node *TraceNode, operand *evaluationStage) bool {
+
+	for _, evaluated := range node.evaluated {
+		if evaluated == operand {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a node for [stage], which hasn't yet been evaluated.
+func (tracer *evaluationTracer) nodeOf(stage *evaluationStage) *TraceNode {
+
+	ret := &TraceNode{
+		Operator: stage.symbol.String(),
+		symbol:   stage.symbol,
+	}
+
+	text, err := formatStage(stage, tracer.options)
+	if err != nil {
+		text = ret.Operator
+	}
+	ret.Expression = text
+
+	switch stage.symbol {
+	case value:
+		ret.Operator = "parameter"
+		ret.Parameter = stage.name
+	case access:
+		ret.Operator = "accessor"
+		ret.Parameter = stage.name
+	case functional:
+		ret.Operator = stage.name
+	case collectionFunctional:
+		ret.Operator = stage.collection.name
+	case literal:
+		ret.Operator = "literal"
+	}
+	return ret
+}
+
+// Returns the stage within any parentheses around [stage].
+func unwrapNoopStage(stage *evaluationStage) *evaluationStage {
+
+	for stage.symbol == noopSymbol && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+	return stage
+}
+
+// Returns [value] as it would be written in an expression, if it can be; such as 'NZ' for a string.
+func describeTraceValue(value interface{}) string {
+
+	// null is described as such, whether or not it's a keyword of the expression.
+	if value == nil {
+		return "null"
+	}
+
+	text, err := formatStage(literalStage(value), ExpressionOptions{})
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return text
+}
+
+// Returns [value] if it can be written as JSON, or otherwise, a string of it.
+func jsonTraceValue(value interface{}) interface{} {
+
+	_, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return value
+}
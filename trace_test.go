@@ -0,0 +1,228 @@
+package govaluate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TraceTest struct {
+	Name        string
+	Input       string
+	Options     ExpressionOptions
+	Parameters  map[string]interface{}
+	Expected    interface{}
+	Trace       string
+	Explanation []string
+}
+
+func TestEvalWithTrace(test *testing.T) {
+
+	traceTests := []TraceTest{
+		{
+			Name:       "Short-circuited",
+			Input:      "age > 30 && country == 'NZ'",
+			Parameters: map[string]interface{}{"age": 25, "country": "NZ"},
+			Expected:   false,
+			Trace: `age > 30 && country == 'NZ' -> false
+  age > 30 -> false
+    age -> 25 (parameter)
+  country == 'NZ' (short-circuited)
+`,
+			Explanation: []string{"age > 30 is false"},
+		},
+		{
+			Name:       "Every operand decides",
+			Input:      "(age >= 18 && country == 'NZ') || (vip && age >= 16)",
+			Parameters: map[string]interface{}{"age": 17, "country": "NZ", "vip": false},
+			Expected:   false,
+			Trace: `age >= 18 && country == 'NZ' || vip && age >= 16 -> false
+  age >= 18 && country == 'NZ' -> false
+    age >= 18 -> false
+      age -> 17 (parameter)
+    country == 'NZ' (short-circuited)
+  vip && age >= 16 -> false
+    vip -> false (parameter)
+    age >= 16 (short-circuited)
+`,
+			Explanation: []string{"age >= 18 is false", "vip is false"},
+		},
+		{
+			Name:       "Negation",
+			Input:      "!(banned || suspended)",
+			Parameters: map[string]interface{}{"banned": false, "suspended": true},
+			Expected:   false,
+			Trace: `!(banned || suspended) -> false
+  banned || suspended -> true
+    banned -> false (parameter)
+    suspended -> true (parameter)
+`,
+			Explanation: []string{"suspended is true"},
+		},
+		{
+			Name:       "Functions and ternaries",
+			Input:      "len(items) > 2 ? 'many' : 'few'",
+			Options:    ExpressionOptions{FunctionDescriptors: StandardFunctionDescriptors()},
+			Parameters: map[string]interface{}{"items": []interface{}{1.0, 3.0}},
+			Expected:   "few",
+			Trace: `len(items) > 2 ? 'many' : 'few' -> 'few'
+  len(items) > 2 ? 'many' -> null
+    len(items) > 2 -> false
+      len(items) -> 2
+        items -> [1, 3] (parameter)
+    'many' (short-circuited)
+`,
+			Explanation: []string{"len(items) > 2 ? 'many' : 'few' is 'few'"},
+		},
+		{
+			Name:       "Collection functions",
+			Input:      "any(items, # > 2) && true",
+			Parameters: map[string]interface{}{"items": []interface{}{1.0, 3.0}},
+			Expected:   true,
+			Trace: `any(items, # > 2) && true -> true
+  any(items, # > 2) -> true
+    items -> [1, 3] (parameter)
+    # > 2 -> false
+      # -> 1 (parameter)
+    # > 2 -> true
+      # -> 3 (parameter)
+`,
+			Explanation: []string{"any(items, # > 2) is true"},
+		},
+		{
+			Name:       "Accessors",
+			Input:      "foo.Int > 200",
+			Parameters: map[string]interface{}{"foo": dummyParameterInstance},
+			Expected:   false,
+			Trace: `foo.Int > 200 -> false
+  foo.Int -> 101 (accessor)
+`,
+			Explanation: []string{"foo.Int > 200 is false"},
+		},
+		{
+			Name:       "Three-valued logic",
+			Input:      "a && b && c",
+			Options:    ExpressionOptions{ThreeValuedLogic: true},
+			Parameters: map[string]interface{}{"a": true, "b": nil, "c": true},
+			Expected:   nil,
+			Trace: `a && b && c -> null
+  a && b -> null
+    a -> true (parameter)
+    b -> null (parameter)
+  c -> true (parameter)
+`,
+			Explanation: []string{"b is null"},
+		},
+		{
+			Name:        "Literal",
+			Input:       "true",
+			Expected:    true,
+			Trace:       "true -> true\n",
+			Explanation: []string{"true is true"},
+		},
+	}
+
+	for _, traceTest := range traceTests {
+
+		expression, err := NewExpressionWithOptions(traceTest.Input, traceTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", traceTest.Name, err)
+			continue
+		}
+
+		result, trace, err := expression.EvalWithTrace(MapParameters(traceTest.Parameters))
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", traceTest.Name, err)
+			continue
+		}
+
+		if result != traceTest.Expected {
+			test.Errorf("Test '%s' failed: expected %v, got %v", traceTest.Name, traceTest.Expected, result)
+		}
+
+		if trace.String() != traceTest.Trace {
+			test.Errorf("Test '%s' failed: expected the trace\n%s\ngot\n%s", traceTest.Name, traceTest.Trace, trace.String())
+		}
+
+		if !reflect.DeepEqual(trace.Explain(), traceTest.Explanation) {
+			test.Errorf("Test '%s' failed: expected the explanation %v, got %v", traceTest.Name, traceTest.Explanation, trace.Explain())
+		}
+	}
+}
+
+func TestTraceJSON(test *testing.T) {
+
+	expression, err := NewExpression("x / y > 1 || z")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, trace, err := expression.EvalWithTrace(MapParameters{"x": 0, "y": 0, "z": true})
+	if err != nil {
+		test.Fatalf("Unable to evaluate: %v", err)
+	}
+
+	text, err := json.Marshal(trace)
+	if err != nil {
+		test.Fatalf("Unable to write the trace as JSON: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	err = json.Unmarshal(text, &decoded)
+	if err != nil {
+		test.Fatalf("Unable to read the trace as JSON: %v", err)
+	}
+
+	// NaN can't be written as JSON, so it's written as a string.
+	division := decoded["children"].([]interface{})[0].(map[string]interface{})["children"].([]interface{})[0].(map[string]interface{})
+	expected := map[string]interface{}{
+		"expression": "x / y",
+		"operator":   "/",
+		"inputs":     []interface{}{0.0, 0.0},
+		"output":     "NaN",
+		"children": []interface{}{
+			map[string]interface{}{"expression": "x", "operator": "parameter", "parameter": "x", "output": 0.0},
+			map[string]interface{}{"expression": "y", "operator": "parameter", "parameter": "y", "output": 0.0},
+		},
+	}
+
+	if !reflect.DeepEqual(division, expected) {
+		test.Errorf("Expected the division to be written as %v, got %v", expected, division)
+	}
+}
+
+func TestTraceFailure(test *testing.T) {
+
+	expression, err := NewExpression("a && x > 1")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	_, trace, err := expression.EvalWithTrace(MapParameters{"a": true, "x": "text"})
+	if err == nil {
+		test.Fatalf("Expected evaluation to fail")
+	}
+
+	expected := "a && x > 1 -> error: " + err.Error()
+	if !strings.HasPrefix(trace.String(), expected) {
+		test.Errorf("Expected the trace to start with '%s', got\n%s", expected, trace.String())
+	}
+
+	explanation := trace.Explain()
+	if len(explanation) != 1 || !strings.HasPrefix(explanation[0], "x > 1 failed: ") {
+		test.Errorf("Expected the failure to be explained, got %v", explanation)
+	}
+
+	// parameters which are missing fail the stages which look them up.
+	_, trace, err = expression.EvalWithTrace(nil)
+	if err == nil || !strings.Contains(trace.String(), "  a -> error: ") {
+		test.Errorf("Expected the missing parameter to fail in the trace, got %v\n%s", err, trace)
+	}
+
+	empty := Expression{}
+	result, trace, err := empty.EvalWithTrace(nil)
+	if result != nil || trace != nil || err != nil {
+		test.Errorf("Expected an empty expression to have no trace, got %v, %v, %v", result, trace, err)
+	}
+}
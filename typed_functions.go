@@ -0,0 +1,195 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// typedFunction is an ordinary Go func which has been adapted to be called from an expression.
+type typedFunction struct {
+	signature functionSignature
+	function  reflect.Value
+}
+
+// NewTypedFunction adapts an ordinary Go func, such as `func(s string, n int) (bool, error)`, into an ExpressionFunction.
+// The func must return either a single value, or a value and an error.
+// Its arguments can be of any type that the expression's values can be converted to (see [ExpressionOptions].TypedFunctions),
+// and it may be variadic.
+// The [name] is only used in error messages.
+//
+// Functions given to [ExpressionOptions].TypedFunctions have their arity checked when the expression is parsed;
+// those adapted with this method (and given as an ExpressionFunction) only have it checked when called.
+func NewTypedFunction(name string, function interface{}) (ExpressionFunction, error) {
+
+	typed, err := newTypedFunction(name, function)
+	if err != nil {
+		return nil, err
+	}
+	return typed.call, nil
+}
+
+func newTypedFunction(name string, function interface{}) (typedFunction, error) {
+
+	value := reflect.ValueOf(function)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		errorMsg := fmt.Sprintf("Typed function '%s' must be a func, got %T", name, function)
+		return typedFunction{}, errors.New(errorMsg)
+	}
+
+	functionType := value.Type()
+
+	validResults := functionType.NumOut() == 1 || (functionType.NumOut() == 2 && functionType.Out(1) == errorType)
+	if !validResults {
+		errorMsg := fmt.Sprintf("Typed function '%s' must return either a single value, or a value and an error", name)
+		return typedFunction{}, errors.New(errorMsg)
+	}
+
+	signature := functionSignature{
+		name:         name,
+		minArguments: functionType.NumIn(),
+		maxArguments: functionType.NumIn(),
+		cost:         1,
+		returnType:   valueTypeOf(functionType.Out(0)),
+	}
+
+	if functionType.IsVariadic() {
+		signature.minArguments--
+		signature.maxArguments = -1
+	}
+
+	return typedFunction{signature: signature, function: value}, nil
+}
+
+// call converts the given arguments to the types that the func expects, then calls it.
+func (typed typedFunction) call(arguments ...interface{}) (interface{}, error) {
+
+	name := typed.signature.name
+
+	err := checkArgumentCount(name, len(arguments), typed.signature.minArguments, typed.signature.maxArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	functionType := typed.function.Type()
+	converted := make([]reflect.Value, len(arguments))
+
+	for i, argument := range arguments {
+
+		var argumentType reflect.Type
+		if functionType.IsVariadic() && i >= functionType.NumIn()-1 {
+			argumentType = functionType.In(functionType.NumIn() - 1).Elem()
+		} else {
+			argumentType = functionType.In(i)
+		}
+
+		var ok bool
+		converted[i], ok = convertArgument(argument, argumentType)
+		if !ok {
+			return nil, argumentTypeError(name, i, argument, "convertible to "+argumentType.String())
+		}
+	}
+
+	results := typed.function.Call(converted)
+
+	if len(results) == 2 && !results[1].IsNil() {
+		return nil, results[1].Interface().(error)
+	}
+	return castToFloat64(results[0].Interface()), nil
+}
+
+// Converts a value from an expression to the given type, as an argument to a typed function.
+// Like the conversions of arguments to accessor methods, values of a different kind are converted where Go allows it;
+// except that numbers are only converted to integers if they're whole and in range, numbers are never converted to strings,
+// and arrays are converted element-by-element.
+// Returns false if the value can't be converted.
+func convertArgument(argument interface{}, argumentType reflect.Type) (reflect.Value, bool) {
+
+	if argument == nil {
+
+		switch argumentType.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(argumentType), true
+		}
+		return reflect.Value{}, false
+	}
+
+	value := reflect.ValueOf(argument)
+	if value.Type().AssignableTo(argumentType) {
+		return value, true
+	}
+
+	switch argumentType.Kind() {
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+		number, ok := wholeNumber(value)
+		if !ok || number < math.MinInt64 || number >= -math.MinInt64 || reflect.Zero(argumentType).OverflowInt(int64(number)) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(int64(number)).Convert(argumentType), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+
+		number, ok := wholeNumber(value)
+		if !ok || number < 0 || number >= (1<<64) || reflect.Zero(argumentType).OverflowUint(uint64(number)) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(uint64(number)).Convert(argumentType), true
+
+	case reflect.Float32, reflect.Float64:
+
+		if !isNumericKind(value.Kind()) {
+			return reflect.Value{}, false
+		}
+		return value.Convert(argumentType), true
+
+	case reflect.Slice:
+
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			return reflect.Value{}, false
+		}
+
+		ret := reflect.MakeSlice(argumentType, value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+
+			element, ok := convertArgument(value.Index(i).Interface(), argumentType.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			ret.Index(i).Set(element)
+		}
+		return ret, true
+	}
+
+	// anything else (such as a named string type) is only converted between values of the same kind.
+	if value.Kind() == argumentType.Kind() && value.Type().ConvertibleTo(argumentType) {
+		return value.Convert(argumentType), true
+	}
+	return reflect.Value{}, false
+}
+
+// Returns the value as a float64, if it is a number with no fractional part.
+func wholeNumber(value reflect.Value) (float64, bool) {
+
+	if !isNumericKind(value.Kind()) {
+		return 0, false
+	}
+
+	number := value.Convert(reflect.TypeOf(float64(0))).Float()
+	return number, number == math.Trunc(number)
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedFunctionLevel string
+
+var typedFunctions = map[string]interface{}{
+	"repeat": func(value string, count int) (string, error) {
+		if count > 3 {
+			return "", errors.New("Too many repetitions")
+		}
+		return strings.Repeat(value, count), nil
+	},
+	"total": func(values ...int) int {
+		var ret int
+		for _, value := range values {
+			ret += value
+		}
+		return ret
+	},
+	"joinWith": func(separator string, parts []string) string {
+		return strings.Join(parts, separator)
+	},
+	"isHigh": func(level typedFunctionLevel) bool {
+		return level == "high"
+	},
+	"byte": func(value uint8) uint8 {
+		return value
+	},
+	"half": func(value float32) float32 {
+		return value / 2
+	},
+	"isNil": func(value interface{}) bool {
+		return value == nil
+	},
+	"ready": func() bool {
+		return true
+	},
+}
+
+func TestTypedFunctions(test *testing.T) {
+
+	standardFunctionTests := []StandardFunctionTest{
+		{Input: "repeat('ab', 2)", Expected: "abab"},
+		{Input: "repeat('ab', count)", Expected: "ababab"},
+		{Input: "repeat('ab', 5)", ExpectedError: "Too many repetitions"},
+		{Input: "repeat('ab', 1.5)", ExpectedError: "Value '1.5' cannot be used as argument 2 of 'repeat', it is not convertible to int"},
+		{Input: "repeat(1, 1)", ExpectedError: "Value '1' cannot be used as argument 1 of 'repeat', it is not convertible to string"},
+		{Input: "total()", Expected: 0.0},
+		{Input: "total(1, 2, count)", Expected: 6.0},
+		{Input: "total(1, 'two')", ExpectedError: "Value 'two' cannot be used as argument 2 of 'total', it is not convertible to int"},
+		{Input: "joinWith('-', ['a', 'b'])", Expected: "a-b"},
+		{Input: "joinWith('-', names)", Expected: "x-y"},
+		{Input: "joinWith('-', ['a', 1])", ExpectedError: "it is not convertible to []string"},
+		{Input: "isHigh('high')", Expected: true},
+		{Input: "byte(255)", Expected: 255.0},
+		{Input: "byte(256)", ExpectedError: "it is not convertible to uint8"},
+		{Input: "byte(-1)", ExpectedError: "it is not convertible to uint8"},
+		{Input: "half(3)", Expected: 1.5},
+		{Input: "isNil(missing)", Expected: true},
+		{Input: "isNil(1)", Expected: false},
+		{Input: "ready()", Expected: true},
+	}
+
+	parameters := map[string]interface{}{
+		"count":   3,
+		"names":   []interface{}{"x", "y"},
+		"missing": nil,
+	}
+
+	for _, testCase := range standardFunctionTests {
+
+		expression, err := NewExpressionWithOptions(testCase.Input, ExpressionOptions{TypedFunctions: typedFunctions})
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", testCase.Input, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(parameters)
+
+		if testCase.ExpectedError != "" {
+
+			if err == nil || !strings.Contains(err.Error(), testCase.ExpectedError) {
+				test.Errorf("Expected '%s' to fail with '%s', got %v (%v)", testCase.Input, testCase.ExpectedError, result, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			test.Errorf("Unable to evaluate '%s': %v", testCase.Input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, testCase.Expected) {
+			test.Errorf("Expected '%s' to evaluate to %#v, got %#v", testCase.Input, testCase.Expected, result)
+		}
+	}
+}
+
+// Tests that calls to typed functions with the wrong number of arguments fail to parse.
+func TestTypedFunctionArity(test *testing.T) {
+
+	parsingTests := []ParsingFailureTest{
+		{
+			Name:     "Too few arguments",
+			Input:    "repeat('ab')",
+			Expected: "Function 'repeat' expects 2 arguments, got 1",
+		},
+		{
+			Name:     "Too many arguments",
+			Input:    "repeat('ab', 1, 2)",
+			Expected: "Function 'repeat' expects 2 arguments, got 3",
+		},
+		{
+			Name:     "Arguments to a function without parameters",
+			Input:    "ready(1)",
+			Expected: "Function 'ready' expects 0 arguments, got 1",
+		},
+		{
+			Name:     "Nested call with too many arguments",
+			Input:    "total(repeat('a', 1, 2), 1)",
+			Expected: "Function 'repeat' expects 2 arguments, got 3",
+		},
+	}
+
+	for _, testCase := range parsingTests {
+
+		_, err := NewExpressionWithOptions(testCase.Input, ExpressionOptions{TypedFunctions: typedFunctions})
+
+		if err == nil || !strings.Contains(err.Error(), testCase.Expected) {
+			test.Errorf("Test '%s' failed: expected error '%s', got %v", testCase.Name, testCase.Expected, err)
+		}
+	}
+}
+
+func TestInvalidTypedFunctions(test *testing.T) {
+
+	cases := []struct {
+		name     string
+		options  ExpressionOptions
+		expected string
+	}{
+		{
+			name:     "Not a func",
+			options:  ExpressionOptions{TypedFunctions: map[string]interface{}{"foo": 1}},
+			expected: "Typed function 'foo' must be a func, got int",
+		},
+		{
+			name:     "No results",
+			options:  ExpressionOptions{TypedFunctions: map[string]interface{}{"foo": func() {}}},
+			expected: "must return either a single value, or a value and an error",
+		},
+		{
+			name:     "Second result is not an error",
+			options:  ExpressionOptions{TypedFunctions: map[string]interface{}{"foo": func() (int, int) { return 0, 0 }}},
+			expected: "must return either a single value, or a value and an error",
+		},
+		{
+			name: "Defined twice",
+			options: ExpressionOptions{
+				Functions:      map[string]ExpressionFunction{"foo": func(arguments ...interface{}) (interface{}, error) { return nil, nil }},
+				TypedFunctions: map[string]interface{}{"foo": func() bool { return true }},
+			},
+			expected: "Function 'foo' is defined more than once",
+		},
+	}
+
+	for _, testCase := range cases {
+
+		_, err := NewExpressionWithOptions("1", testCase.options)
+
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			test.Errorf("Test '%s' failed: expected error '%s', got %v", testCase.name, testCase.expected, err)
+		}
+	}
+}
+
+func TestNewTypedFunction(test *testing.T) {
+
+	function, err := NewTypedFunction("repeat", typedFunctions["repeat"])
+	if err != nil {
+		test.Fatalf("Unable to adapt function: %v", err)
+	}
+
+	result, err := function("a", 2.0)
+	if err != nil || result != "aa" {
+		test.Errorf("Expected 'aa', got %v (%v)", result, err)
+	}
+
+	_, err = function("a")
+	if err == nil || !strings.Contains(err.Error(), "Function 'repeat' expects 2 arguments, got 1") {
+		test.Errorf("Expected an arity error when called, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+// Represents a test of an expression parsed with word operators.
+// If [Expected] is set, the expression should fail (to parse or evaluate) with an error containing it;
+// otherwise it should evaluate to [Result], and translate to the [Query], if any.
+type WordOperatorTest struct {
+	Name     string
+	Input    string
+	Result   interface{}
+	Query    string
+	Expected string
+}
+
+func TestWordOperators(test *testing.T) {
+
+	parameters := map[string]interface{}{
+		"n":     4.0,
+		"name":  "Alice",
+		"flag":  true,
+		"and":   "reserved",
+		"lower": 1.0,
+		"upper": 5.0,
+	}
+
+	wordOperatorTests := []WordOperatorTest{
+		{
+			Name:   "Logical words",
+			Input:  "flag and n > 3 or false",
+			Result: true,
+			Query:  "[flag] AND [n] > 3 OR 0",
+		},
+		{
+			Name:   "Logical words in any case",
+			Input:  "flag AND n > 5 Or NOT flag",
+			Result: false,
+		},
+		{
+			Name:   "Not",
+			Input:  "not flag",
+			Result: false,
+			Query:  "NOT [flag]",
+		},
+		{
+			Name:   "Not binds tighter than and",
+			Input:  "not false and false",
+			Result: false,
+		},
+		{
+			Name:   "Not binds more loosely than comparators",
+			Input:  "not n > 5",
+			Result: true,
+			Query:  "NOT [n] > 5",
+		},
+		{
+			Name:   "Not of an equality, within and",
+			Input:  "not n == 5 and flag",
+			Result: true,
+			Query:  "NOT [n] = 5 AND [flag]",
+		},
+		{
+			Name:   "Not within and",
+			Input:  "n == 4 and not name == 'Bob'",
+			Result: true,
+		},
+		{
+			Name:   "Not after a comparator",
+			Input:  "flag == not n > 5",
+			Result: true,
+		},
+		{
+			Name:   "Xor",
+			Input:  "true xor flag",
+			Result: false,
+			Query:  "1 XOR [flag]",
+		},
+		{
+			Name:   "Xor binds more loosely than and, and more tightly than or",
+			Input:  "true xor true and false or false",
+			Result: true,
+		},
+		{
+			Name:   "Upper case booleans",
+			Input:  "TRUE == flag && False == !flag",
+			Result: true,
+		},
+		{
+			Name:   "Between",
+			Input:  "n between 1 and 5",
+			Result: true,
+			Query:  "[n] BETWEEN 1 AND 5",
+		},
+		{
+			Name:   "Between is inclusive",
+			Input:  "n BETWEEN 4 AND 4",
+			Result: true,
+		},
+		{
+			Name:   "Not between",
+			Input:  "n not between lower and upper",
+			Result: false,
+			Query:  "[n] NOT BETWEEN [lower] AND [upper]",
+		},
+		{
+			Name:   "Between followed by and",
+			Input:  "n between 1 and 3 and flag or n between 4 and 6 and flag",
+			Result: true,
+			Query:  "[n] BETWEEN 1 AND 3 AND [flag] OR [n] BETWEEN 4 AND 6 AND [flag]",
+		},
+		{
+			Name:   "Between with arithmetic bounds",
+			Input:  "n + 1 between lower * 2 and upper",
+			Result: true,
+		},
+		{
+			Name:   "Between compared with another comparator",
+			Input:  "n between 5 and 6 == false",
+			Result: true,
+		},
+		{
+			Name:   "Between strings",
+			Input:  "name between 'A' and 'B'",
+			Result: true,
+		},
+		{
+			Name:   "Between within a clause",
+			Input:  "(n between (lower) and upper) and (lower between 0 and 1)",
+			Result: true,
+		},
+		{
+			Name:   "Negated textual comparators in any case",
+			Input:  "name Not Like 'B%' and n Not In [1, 2]",
+			Result: true,
+		},
+		{
+			Name:   "Reserved word in brackets",
+			Input:  "[and] == 'reserved'",
+			Result: true,
+		},
+		{
+			Name:     "Reserved word as a parameter",
+			Input:    "and == 'reserved'",
+			Expected: "Cannot transition token types",
+		},
+		{
+			Name:     "Between without and",
+			Input:    "n between 1 or 5",
+			Expected: "Expected 'and' between the bounds of 'between', found '||'",
+		},
+		{
+			Name:     "Between mismatched types",
+			Input:    "n between 'a' and 'b'",
+			Expected: "cannot be used with the comparator 'between'",
+		},
+		{
+			Name:     "Xor of numbers",
+			Input:    "1 xor 2",
+			Expected: "cannot be used with the logical operator 'xor'",
+		},
+	}
+
+	for _, wordTest := range wordOperatorTests {
+
+		expression, err := NewExpressionWithOptions(wordTest.Input, ExpressionOptions{WordOperators: true, PatternOperators: true})
+
+		var result interface{}
+		if err == nil {
+			result, err = expression.Evaluate(parameters)
+		}
+
+		if wordTest.Expected != "" {
+			if err == nil || !strings.Contains(err.Error(), wordTest.Expected) {
+				test.Errorf("Test '%s' failed: expected an error containing '%s', got %v", wordTest.Name, wordTest.Expected, err)
+			}
+			continue
+		}
+
+		if err != nil || result != wordTest.Result {
+			test.Errorf("Test '%s' failed: expected %v, got %v (%v)", wordTest.Name, wordTest.Result, result, err)
+			continue
+		}
+
+		if wordTest.Query == "" {
+			continue
+		}
+
+		query, err := expression.ToSQLQuery()
+		if err != nil || query != wordTest.Query {
+			test.Errorf("Test '%s' failed: expected the query '%s', got '%s' (%v)", wordTest.Name, wordTest.Query, query, err)
+		}
+	}
+}
+
+// Tests that word operators are only keywords when enabled.
+func TestWordOperatorsDisabled(test *testing.T) {
+
+	parameters := map[string]interface{}{"and": 1.0, "between": 2.0, "TRUE": 3.0}
+
+	expression, err := NewExpression("and + between + TRUE")
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	result, err := expression.Evaluate(parameters)
+	if err != nil || result != 6.0 {
+		test.Errorf("Expected words to be parameters without word operators, got %v (%v)", result, err)
+	}
+
+	// pattern matching comparators are enabled separately.
+	expression, err = NewExpressionWithOptions("Like + [between]", ExpressionOptions{WordOperators: true})
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	result, err = expression.Evaluate(map[string]interface{}{"Like": 1.0, "between": 2.0})
+	if err != nil || result != 3.0 {
+		test.Errorf("Expected 'Like' to be a parameter without pattern operators, got %v (%v)", result, err)
+	}
+}
+
+// Tests that the "and" between the bounds of a "between" is a token of its own, and that its bounds aren't taken for the arguments of a function.
+func TestBetweenBounds(test *testing.T) {
+
+	options := ExpressionOptions{
+		WordOperators: true,
+		Functions: map[string]ExpressionFunction{
+			"count": func(arguments ...interface{}) (interface{}, error) {
+				return float64(len(arguments)), nil
+			},
+		},
+	}
+
+	expression, err := NewExpressionWithOptions("count(n between 1 and 5 and flag, n)", options)
+	if err != nil {
+		test.Fatalf("Unable to parse: %v", err)
+	}
+
+	expected := []ExpressionToken{
+		{Kind: function},
+		{Kind: clause, Value: '('},
+		{Kind: variable, Value: "n"},
+		{Kind: comparator, Value: "between"},
+		{Kind: numeric, Value: 1.0},
+		{Kind: boundsSeparator, Value: "and"},
+		{Kind: numeric, Value: 5.0},
+		{Kind: logicalop, Value: "&&"},
+		{Kind: variable, Value: "flag"},
+		{Kind: separator, Value: ","},
+		{Kind: variable, Value: "n"},
+		{Kind: clauseClose, Value: ')'},
+	}
+
+	tokens := expression.Tokens()
+	if len(tokens) != len(expected) {
+		test.Fatalf("Expected %d tokens, got %v", len(expected), tokens)
+	}
+
+	for i, token := range tokens[1:] {
+		if token != expected[i+1] {
+			test.Errorf("Expected token %d to be %v, got %v", i+1, expected[i+1], token)
+		}
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"n": 3.0, "flag": true})
+	if err != nil || result != 2.0 {
+		test.Errorf("Expected the function to be given two arguments, got %v (%v)", result, err)
+	}
+
+	formatted, err := formatStage(expression.evaluationStages, options)
+	if err != nil || formatted != "count(n between 1 and 5 && flag, n)" {
+		test.Errorf("Expected the expression to be written out as it was, got '%s' (%v)", formatted, err)
+	}
+}